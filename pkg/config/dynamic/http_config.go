@@ -297,6 +297,8 @@ type Server struct {
 	Priority     *int   `json:"priority,omitempty" toml:"priority,omitempty" yaml:"priority,omitempty" export:"true"`
 	PreservePath bool   `json:"preservePath,omitempty" toml:"preservePath,omitempty" yaml:"preservePath,omitempty" export:"true"`
 	Fenced       bool   `json:"fenced,omitempty" toml:"-" yaml:"-" label:"-" file:"-" kv:"-"`
+	// Headers are the request headers set on the requests dispatched to the server, by the balancers supporting them.
+	Headers map[string]string `json:"headers,omitempty" toml:"-" yaml:"-" label:"-" file:"-" kv:"-"`
 	// Scheme can only be defined with label Providers.
 	Scheme string `json:"-" toml:"-" yaml:"-" file:"-" kv:"-"`
 	Port   string `json:"-" toml:"-" yaml:"-" file:"-" kv:"-"`
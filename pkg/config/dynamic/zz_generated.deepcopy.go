@@ -1412,6 +1412,13 @@ func (in *Server) DeepCopyInto(out *Server) {
 		*out = new(int)
 		**out = **in
 	}
+	if in.Headers != nil {
+		in, out := &in.Headers, &out.Headers
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
 	return
 }
 
@@ -0,0 +1,73 @@
+package lblb
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/traefik/traefik/v3/pkg/middlewares/accesslog"
+)
+
+// Access log fields set by the balancer when enabled with SetAccessLogFields.
+const (
+	// AccessLogServer is the access log field holding the name of the selected server, empty for a rejected request.
+	AccessLogServer = "LeakyBucketServer"
+	// AccessLogAdmission is the access log field holding how the request was admitted, see the Admission values.
+	AccessLogAdmission = "LeakyBucketAdmission"
+	// AccessLogSelectionDuration is the access log field holding the time spent selecting the server.
+	AccessLogSelectionDuration = "LeakyBucketSelectionDuration"
+)
+
+// Values of the AccessLogAdmission access log field.
+const (
+	// AdmissionSelected is a request admitted by the bucket of the selected server.
+	AdmissionSelected = "selected"
+	// AdmissionSticky is a request admitted by the bucket of the server it sticks to.
+	AdmissionSticky = "sticky"
+	// AdmissionBypassed is a request which bypassed the rate limiting with the bypass token.
+	AdmissionBypassed = "bypassed"
+	// AdmissionRejected is a request which could not be dispatched to any server.
+	AdmissionRejected = "rejected"
+	// AdmissionCapped is a request rejected by a cap of the balancer as a whole, e.g. its concurrency limit.
+	AdmissionCapped = "capped"
+	// AdmissionDefaultBackend is a request sent to the default backend because all the up servers are draining.
+	AdmissionDefaultBackend = "default-backend"
+	// AdmissionOverflowFallback is a request sent to the overflow fallback because it reached the maximum overflow hops.
+	AdmissionOverflowFallback = "overflow-fallback"
+	// AdmissionCached is a request replied from the response cache, see SetResponseCache.
+	AdmissionCached = "cached"
+)
+
+// SetAccessLogFields enables the access log fields describing the selection of each request,
+// so that each logged request shows which server of the balancer served it.
+// The fields are only set when the access log is enabled, which is then the case of the request context.
+func (b *LBBalancer) SetAccessLogFields(enabled bool) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.accessLogFields = enabled
+}
+
+// logSelection sets the access log fields of the selection of req.
+func (b *LBBalancer) logSelection(req *http.Request, server *namedHandler, admission string, duration time.Duration) {
+	b.mutex.RLock()
+	enabled := b.accessLogFields
+	b.mutex.RUnlock()
+
+	if !enabled {
+		return
+	}
+
+	data := accesslog.GetLogData(req)
+	if data == nil {
+		return
+	}
+
+	var name string
+	if server != nil {
+		name = server.name
+	}
+
+	data.Core[AccessLogServer] = name
+	data.Core[AccessLogAdmission] = admission
+	data.Core[AccessLogSelectionDuration] = duration
+}
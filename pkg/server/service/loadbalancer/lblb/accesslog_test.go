@@ -0,0 +1,73 @@
+package lblb
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/traefik/traefik/v3/pkg/config/dynamic"
+	"github.com/traefik/traefik/v3/pkg/middlewares/accesslog"
+)
+
+func TestLBBalancerAccessLogFields(t *testing.T) {
+	balancer := New(&dynamic.Sticky{Cookie: &dynamic.Cookie{Name: "test"}}, false)
+	balancer.SetAccessLogFields(true)
+	balancer.SetBypassToken("X-Bypass", "secret")
+
+	// Tokens are not refilled during the test.
+	for i, name := range []string{"first", "second"} {
+		balancer.Add(name, http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			rw.WriteHeader(http.StatusOK)
+		}), Int(i+1), Int(1), Int(3600000), Int(i+1))
+	}
+
+	// serve plays the access log middleware, and returns the fields it would log.
+	serve := func(req *http.Request) accesslog.CoreLogData {
+		data := &accesslog.LogData{Core: accesslog.CoreLogData{}}
+		req = req.WithContext(context.WithValue(req.Context(), accesslog.DataTableKey, data))
+
+		recorder := httptest.NewRecorder()
+		balancer.ServeHTTP(recorder, req)
+
+		return data.Core
+	}
+
+	fields := serve(httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, "first", fields[AccessLogServer])
+	assert.Equal(t, AdmissionSelected, fields[AccessLogAdmission])
+	assert.IsType(t, time.Duration(0), fields[AccessLogSelectionDuration])
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-Bypass", "secret")
+	fields = serve(req)
+	assert.Equal(t, "first", fields[AccessLogServer])
+	assert.Equal(t, AdmissionBypassed, fields[AccessLogAdmission])
+
+	// first has no more tokens, and the client now sticks to second.
+	recorder := httptest.NewRecorder()
+	balancer.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+	cookies := recorder.Result().Cookies()
+	require.Len(t, cookies, 1)
+
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(cookies[0])
+	fields = serve(req)
+	assert.Equal(t, "second", fields[AccessLogServer])
+	assert.Equal(t, AdmissionSticky, fields[AccessLogAdmission])
+
+	fields = serve(httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Empty(t, fields[AccessLogServer])
+	assert.Equal(t, AdmissionRejected, fields[AccessLogAdmission])
+
+	// Once disabled, no field is set.
+	balancer.SetAccessLogFields(false)
+	assert.Empty(t, serve(httptest.NewRequest(http.MethodGet, "/", nil)))
+
+	// Without access log, nothing happens.
+	balancer.SetAccessLogFields(true)
+	balancer.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+}
@@ -0,0 +1,37 @@
+package lblb
+
+import "math"
+
+// CanAdmit returns how many of n more requests could be admitted right now,
+// given the tokens available in the buckets of the servers a selection would consider,
+// i.e. skipping the down, disabled, ejected and draining ones, and the concurrency limit if set.
+// It does not consume any token.
+func (b *LBBalancer) CanAdmit(n int) int {
+	if n <= 0 {
+		return 0
+	}
+
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	now := b.clock.Now()
+
+	admitted := 0
+	for _, h := range b.handlers {
+		if b.skipReason(h, selection{dryRun: true}, now) != "" {
+			continue
+		}
+
+		admitted += int(math.Floor(h.bucket.TokensAt(now)))
+		if admitted >= n {
+			admitted = n
+			break
+		}
+	}
+
+	if b.concurrencyLimit > 0 {
+		admitted = min(admitted, int(max(b.concurrencyLimit-b.inflight.Load(), 0)))
+	}
+
+	return admitted
+}
@@ -0,0 +1,54 @@
+package lblb
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLBBalancerCanAdmit(t *testing.T) {
+	clock := newFakeClock()
+
+	balancer := New(nil, false)
+	balancer.clock = clock
+
+	handler := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusOK)
+	})
+
+	// One token per second for each server.
+	balancer.Add("first", handler, Int(3), Int(1), Int(1000), Int(1))
+	balancer.Add("second", handler, Int(2), Int(1), Int(1000), Int(2))
+	balancer.Add("third", handler, Int(4), Int(1), Int(1000), Int(3))
+
+	balancer.SetStatus(context.Background(), "third", false)
+
+	assert.Equal(t, 5, balancer.CanAdmit(10))
+	assert.Equal(t, 4, balancer.CanAdmit(4))
+	assert.Equal(t, 0, balancer.CanAdmit(0))
+
+	// Computing the headroom does not consume any token.
+	assert.Equal(t, 5, balancer.CanAdmit(10))
+
+	for range 4 {
+		balancer.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	}
+	assert.Equal(t, 1, balancer.CanAdmit(10))
+
+	clock.Advance(time.Second)
+	assert.Equal(t, 3, balancer.CanAdmit(10))
+
+	// A draining server does not take new requests, and its tokens are not counted.
+	balancer.SetDraining("first", true)
+	assert.Equal(t, 2, balancer.CanAdmit(10))
+	balancer.SetDraining("first", false)
+	assert.Equal(t, 3, balancer.CanAdmit(10))
+
+	// The concurrency limit bounds the headroom as well.
+	balancer.SetConcurrencyLimit(2, nil)
+	assert.Equal(t, 2, balancer.CanAdmit(10))
+}
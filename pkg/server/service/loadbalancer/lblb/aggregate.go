@@ -0,0 +1,79 @@
+package lblb
+
+import (
+	"errors"
+
+	"golang.org/x/time/rate"
+)
+
+var errAggregateBurst = errors.New("aggregate burst exceeded")
+
+// SetMaxAggregateBurst caps the spike of requests the balancer forwards at once across all its servers,
+// which is otherwise the sum of the bursts of the servers, e.g. to protect a downstream dependency of the servers:
+// when the bursts of the up servers sum up to more than burst, the requests also go through an aggregate bucket
+// of the given burst, refilled at the sum of the configured rates of the up servers,
+// so that the sustained rate is unchanged while a coordinated spike is bounded, even if the servers have tokens.
+// The sums are updated when a server is added, updated or removed, or changes status.
+// A request denied by it is rejected as by a cap of the balancer, see SetCapRejection,
+// and counted with the RejectAggregateBurst reason, while a request which could not be dispatched to any server
+// gets its aggregate token back. The bypassing requests are not limited.
+// A non-positive burst disables the cap, which is the default.
+func (b *LBBalancer) SetMaxAggregateBurst(burst int) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if burst <= 0 {
+		b.aggregateBucket = nil
+		return
+	}
+
+	b.aggregateBucket = rate.NewLimiter(0, burst)
+	b.updateAggregateBurst()
+}
+
+// updateAggregateBurst updates the sum of the bursts of the up servers, and the rate of the aggregate bucket,
+// after a change of the servers.
+// It must be called with the mutex held.
+func (b *LBBalancer) updateAggregateBurst() {
+	if b.aggregateBucket == nil {
+		return
+	}
+
+	var burst int64
+	var limit rate.Limit
+	for _, h := range b.handlers {
+		if _, up := b.status[h.name]; up {
+			burst += h.burst
+			limit += h.limit()
+		}
+	}
+
+	b.aggregateBurst = burst
+	b.aggregateBucket.SetLimitAt(b.clock.Now(), limit)
+}
+
+// allowAggregateBurst reports whether the aggregate bucket admits a request,
+// and whether it took a token of the bucket to do so, which is to be given back with refundAggregateBurst.
+// The bucket only applies when the bursts of the up servers sum up to more than its burst.
+func (b *LBBalancer) allowAggregateBurst() (allowed, taken bool) {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	bucket := b.aggregateBucket
+	if bucket == nil || b.aggregateBurst <= int64(bucket.Burst()) {
+		return true, false
+	}
+
+	allowed = bucket.AllowN(b.clock.Now(), 1)
+	return allowed, allowed
+}
+
+// refundAggregateBurst gives back the aggregate token of a request which could not be dispatched to any server.
+func (b *LBBalancer) refundAggregateBurst() {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	if b.aggregateBucket != nil {
+		b.aggregateBucket.ReserveN(b.clock.Now(), -1)
+	}
+}
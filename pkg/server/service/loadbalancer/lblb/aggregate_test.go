@@ -0,0 +1,62 @@
+package lblb
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLBBalancerMaxAggregateBurst(t *testing.T) {
+	clock := newFakeClock()
+	balancer := New(nil, false)
+	balancer.clock = clock
+	balancer.SetMaxAggregateBurst(5)
+
+	for i, name := range []string{"first", "second", "third"} {
+		balancer.Add(name, http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			rw.WriteHeader(http.StatusOK)
+		}), Int(4), Int(2), Int(1000), Int(i+1))
+	}
+
+	admitted := func(requests int) int {
+		var count int
+		for range requests {
+			recorder := httptest.NewRecorder()
+			balancer.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+			if recorder.Code == http.StatusOK {
+				count++
+			}
+		}
+		return count
+	}
+
+	// The buckets of the servers hold 12 tokens, but only 5 requests are forwarded at once.
+	assert.Equal(t, 5, admitted(12))
+	assert.Equal(t, uint64(7), balancer.Stats().RejectionReasons[RejectAggregateBurst])
+
+	stats := balancer.Stats().Servers
+	assert.Equal(t, uint64(4), stats["first"].Served)
+	assert.Equal(t, uint64(1), stats["second"].Served)
+
+	// Once the bursts of the up servers do not exceed the cap, the aggregate bucket does not apply,
+	// and the requests which could not be dispatched do not give back a token they did not take.
+	balancer.SetStatus(context.Background(), "second", false)
+	balancer.SetStatus(context.Background(), "third", false)
+	assert.Equal(t, 0, admitted(5))
+	balancer.SetStatus(context.Background(), "second", true)
+	balancer.SetStatus(context.Background(), "third", true)
+	assert.Equal(t, 0, admitted(5))
+
+	// The aggregate bucket refills at the sum of the rates of the servers, i.e. 6 per second.
+	clock.Advance(500 * time.Millisecond)
+	assert.Equal(t, 3, admitted(12))
+
+	// Below the cap, the aggregate bucket does not apply.
+	balancer.SetMaxAggregateBurst(12)
+	clock.Advance(10 * time.Second)
+	assert.Equal(t, 12, admitted(13))
+}
@@ -0,0 +1,103 @@
+package lblb
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+)
+
+// AllDrainingPolicy is how the non-sticky requests are handled when all the up servers are draining.
+type AllDrainingPolicy string
+
+const (
+	// AllDrainingReject rejects the requests as unavailable, with a 503. It is the default policy.
+	AllDrainingReject AllDrainingPolicy = "reject"
+	// AllDrainingLeastDrained sends the requests to the draining server with the most tokens available in its bucket,
+	// the priorities breaking ties, the requests being rejected if no bucket admits them.
+	AllDrainingLeastDrained AllDrainingPolicy = "least-drained"
+	// AllDrainingDefaultBackend sends the requests to a default backend.
+	AllDrainingDefaultBackend AllDrainingPolicy = "default-backend"
+)
+
+// allDraining is the handling of the requests when all the up servers are draining.
+type allDraining struct {
+	policy  AllDrainingPolicy
+	backend http.Handler
+}
+
+// SetAllDrainingPolicy sets how the non-sticky requests are handled when all the up servers are draining,
+// e.g. in the middle of a deployment, instead of being rejected as when all the servers are down.
+// The backend is the default backend of the AllDrainingDefaultBackend policy, and is ignored by the other policies.
+// An empty policy restores AllDrainingReject, which is the default.
+// It returns an error for an unknown policy, or for the AllDrainingDefaultBackend policy without a backend.
+func (b *LBBalancer) SetAllDrainingPolicy(policy AllDrainingPolicy, backend http.Handler) error {
+	switch policy {
+	case "", AllDrainingReject, AllDrainingLeastDrained:
+		backend = nil
+	case AllDrainingDefaultBackend:
+		if backend == nil {
+			return errors.New("no default backend for the all draining policy")
+		}
+	default:
+		return errors.New("unknown all draining policy " + string(policy))
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if policy == "" || policy == AllDrainingReject {
+		b.allDraining = nil
+		return nil
+	}
+
+	b.allDraining = &allDraining{policy: policy, backend: backend}
+
+	return nil
+}
+
+// allDrainingFallback returns where a request which could not be dispatched goes according to the all draining policy:
+// either a draining server, or the default backend.
+// Both are nil if the policy rejects it, or if not all the up servers are draining.
+// The draining server is selected for sel, i.e. is not excluded, and is allowed by its bucket unless bypassed.
+func (b *LBBalancer) allDrainingFallback(sel selection) (*namedHandler, http.Handler) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.allDraining == nil || len(b.status) == 0 {
+		return nil, nil
+	}
+
+	now := b.clock.Now()
+
+	var best *namedHandler
+	var bestTokens float64
+	for _, h := range b.handlers {
+		if _, up := b.status[h.name]; !up || !h.dispatchable() {
+			continue
+		}
+
+		if _, draining := b.draining[h.name]; !draining {
+			return nil, nil
+		}
+
+		if slices.Contains(sel.excluded, h.name) {
+			continue
+		}
+
+		// The candidates are ranked without changing their availability, e.g. ending their cooldown.
+		tokens := h.bucket.TokensAt(now)
+		if b.availableFor(h, selection{dryRun: true}, now) && (best == nil || tokens > bestTokens || (tokens == bestTokens && higherPriority(h, best))) {
+			best, bestTokens = h, tokens
+		}
+	}
+
+	if b.allDraining.policy == AllDrainingDefaultBackend {
+		return nil, b.allDraining.backend
+	}
+
+	if best == nil || !b.available(best, now) || (!sel.bypass && !best.allow(now)) {
+		return nil, nil
+	}
+
+	return best, nil
+}
@@ -0,0 +1,113 @@
+package lblb
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLBBalancerAllDrainingPolicy(t *testing.T) {
+	newBalancer := func() *LBBalancer {
+		balancer := New(nil, false)
+		balancer.clock = newFakeClock()
+
+		// Tokens are not refilled during the test.
+		for i, name := range []string{"first", "second"} {
+			balancer.Add(name, http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+				rw.Header().Set("server", name)
+				rw.WriteHeader(http.StatusOK)
+			}), Int(2*(i+1)), Int(1), Int(3600000), Int(i+1))
+			balancer.SetDraining(name, true)
+		}
+
+		return balancer
+	}
+
+	serve := func(balancer *LBBalancer) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		balancer.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+		return recorder
+	}
+
+	backend := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Header().Set("server", "default")
+		rw.WriteHeader(http.StatusOK)
+	})
+
+	t.Run("reject", func(t *testing.T) {
+		t.Parallel()
+
+		balancer := newBalancer()
+		assert.Equal(t, http.StatusServiceUnavailable, serve(balancer).Code)
+
+		require.NoError(t, balancer.SetAllDrainingPolicy(AllDrainingLeastDrained, nil))
+		require.NoError(t, balancer.SetAllDrainingPolicy(AllDrainingReject, backend))
+		assert.Equal(t, http.StatusServiceUnavailable, serve(balancer).Code)
+	})
+
+	t.Run("least drained", func(t *testing.T) {
+		t.Parallel()
+
+		balancer := newBalancer()
+		require.NoError(t, balancer.SetAllDrainingPolicy(AllDrainingLeastDrained, nil))
+
+		// second has the most tokens, and then the priority breaks the ties.
+		for _, expected := range []string{"second", "second", "first", "second", "first", "second"} {
+			assert.Equal(t, expected, serve(balancer).Header().Get("server"))
+		}
+
+		assert.Equal(t, http.StatusServiceUnavailable, serve(balancer).Code)
+	})
+
+	t.Run("ranking without ending the cooldowns", func(t *testing.T) {
+		t.Parallel()
+
+		balancer := newBalancer()
+		require.NoError(t, balancer.SetAllDrainingPolicy(AllDrainingLeastDrained, nil))
+
+		// The cooldown of first is over, but only ends once it is chosen.
+		until := balancer.clock.Now().Add(-time.Second)
+		balancer.serverAvailability["first"] = until
+
+		server, _ := balancer.allDrainingFallback(selection{})
+		require.NotNil(t, server)
+		assert.Equal(t, "second", server.name)
+		assert.Equal(t, until, balancer.serverAvailability["first"])
+	})
+
+	t.Run("default backend", func(t *testing.T) {
+		t.Parallel()
+
+		balancer := newBalancer()
+		require.NoError(t, balancer.SetAllDrainingPolicy(AllDrainingDefaultBackend, backend))
+		assert.Equal(t, "default", serve(balancer).Header().Get("server"))
+		assert.Equal(t, uint64(0), balancer.Stats().Rejected)
+	})
+
+	t.Run("not all draining", func(t *testing.T) {
+		t.Parallel()
+
+		balancer := newBalancer()
+		require.NoError(t, balancer.SetAllDrainingPolicy(AllDrainingDefaultBackend, backend))
+		balancer.SetDraining("second", false)
+
+		for range 4 {
+			assert.Equal(t, "second", serve(balancer).Header().Get("server"))
+		}
+
+		// second is exhausted, which is not handled as if all servers were draining.
+		assert.Equal(t, http.StatusServiceUnavailable, serve(balancer).Code)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		t.Parallel()
+
+		balancer := newBalancer()
+		assert.Error(t, balancer.SetAllDrainingPolicy(AllDrainingDefaultBackend, nil))
+		assert.Error(t, balancer.SetAllDrainingPolicy("unknown", nil))
+	})
+}
@@ -0,0 +1,66 @@
+package lblb
+
+import "time"
+
+// Operations reported in an AuditEvent.
+const (
+	AuditSetStatus     = "set-status"
+	AuditSetDraining   = "set-draining"
+	AuditRemoveServer  = "remove-server"
+	AuditUpdateServer  = "update-server"
+	AuditSetServers    = "set-servers"
+	AuditBoostPriority = "boost-priority"
+)
+
+// AuditEvent is the record of an administrative operation changing the behavior of the balancer.
+type AuditEvent struct {
+	Time      time.Time      `json:"time"`
+	Operation string         `json:"operation"`
+	Args      map[string]any `json:"args,omitempty"`
+
+	// Up is whether the balancer has at least one up server after the operation.
+	Up bool `json:"up"`
+	// Servers is the number of servers after the operation.
+	Servers int `json:"servers"`
+	// UpServers is the number of up servers after the operation.
+	UpServers int `json:"upServers"`
+	// DrainingServers is the number of draining servers after the operation.
+	DrainingServers int `json:"drainingServers"`
+}
+
+// SetAuditHook sets the hook called with a record of every administrative operation,
+// i.e. SetStatus, SetDraining, RemoveServer, UpdateServer, SetServers, and BoostPriority,
+// so that the changes of the traffic behavior are traceable.
+// It is called in its own goroutine, so that it does not block the operations.
+// A nil fn disables the auditing, which is the default.
+func (b *LBBalancer) SetAuditHook(fn func(AuditEvent)) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.auditHook = fn
+}
+
+// audit reports an administrative operation to the audit hook.
+// It must be called with the mutex held, after the operation.
+func (b *LBBalancer) audit(operation string, args map[string]any) {
+	fn := b.auditHook
+	if fn == nil {
+		return
+	}
+
+	event := AuditEvent{
+		Time:            b.clock.Now(),
+		Operation:       operation,
+		Args:            args,
+		Up:              len(b.status) > 0,
+		Servers:         len(b.handlers),
+		DrainingServers: len(b.draining),
+	}
+	for _, h := range b.handlers {
+		if _, up := b.status[h.name]; up {
+			event.UpServers++
+		}
+	}
+
+	go fn(event)
+}
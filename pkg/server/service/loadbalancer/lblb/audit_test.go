@@ -0,0 +1,100 @@
+package lblb
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/traefik/traefik/v3/pkg/config/dynamic"
+)
+
+func TestLBBalancerAuditHook(t *testing.T) {
+	clock := newFakeClock()
+	balancer := New(nil, false)
+	balancer.clock = clock
+	defer balancer.Close()
+
+	handler := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {})
+	for i, name := range []string{"first", "second"} {
+		balancer.Add(name, handler, Int(1), Int(1), Int(1000), Int(i+1))
+	}
+
+	events := make(chan AuditEvent, 1)
+	balancer.SetAuditHook(func(event AuditEvent) {
+		events <- event
+	})
+
+	next := func() AuditEvent {
+		t.Helper()
+
+		select {
+		case event := <-events:
+			return event
+		case <-time.After(5 * time.Second):
+			t.Fatal("no audit event")
+			return AuditEvent{}
+		}
+	}
+
+	balancer.SetStatus(context.Background(), "first", false)
+	assert.Equal(t, AuditEvent{
+		Time:      clock.Now(),
+		Operation: AuditSetStatus,
+		Args:      map[string]any{"name": "first", "up": false},
+		Up:        true,
+		Servers:   2,
+		UpServers: 1,
+	}, next())
+
+	assert.True(t, balancer.SetDraining("second", true))
+	assert.Equal(t, AuditEvent{
+		Time:            clock.Now(),
+		Operation:       AuditSetDraining,
+		Args:            map[string]any{"name": "second", "draining": true},
+		Up:              true,
+		Servers:         2,
+		UpServers:       1,
+		DrainingServers: 1,
+	}, next())
+
+	require.NoError(t, balancer.BoostPriority("second", 1, time.Minute))
+	event := next()
+	assert.Equal(t, AuditBoostPriority, event.Operation)
+	assert.Equal(t, map[string]any{"name": "second", "priority": int64(1), "duration": time.Minute}, event.Args)
+
+	require.NoError(t, balancer.UpdateServer("first", dynamic.Server{Burst: Int(5), Average: Int(2), Period: Int(1000), Priority: Int(3)}))
+	event = next()
+	assert.Equal(t, AuditUpdateServer, event.Operation)
+	assert.Equal(t, map[string]any{"name": "first", "burst": 5, "average": 2, "period": time.Second, "priority": 3}, event.Args)
+
+	assert.True(t, balancer.RemoveServer(context.Background(), "first"))
+	event = next()
+	assert.Equal(t, AuditRemoveServer, event.Operation)
+	assert.Equal(t, map[string]any{"name": "first"}, event.Args)
+	assert.Equal(t, 1, event.Servers)
+
+	require.NoError(t, balancer.SetServers(context.Background(), []Server{
+		{Name: "third", Handler: handler, Config: dynamic.Server{Burst: Int(1), Average: Int(1), Period: Int(1000), Priority: Int(1)}},
+	}))
+	event = next()
+	assert.Equal(t, AuditSetServers, event.Operation)
+	assert.Equal(t, map[string]any{"servers": []string{"third"}}, event.Args)
+	assert.True(t, event.Up)
+	assert.Equal(t, 1, event.UpServers)
+
+	// Failed operations are not audited.
+	assert.False(t, balancer.RemoveServer(context.Background(), "unknown"))
+	assert.Error(t, balancer.BoostPriority("unknown", 1, time.Minute))
+
+	balancer.SetAuditHook(nil)
+	balancer.SetStatus(context.Background(), "third", false)
+
+	select {
+	case event := <-events:
+		t.Fatalf("unexpected audit event %+v", event)
+	case <-time.After(10 * time.Millisecond):
+	}
+}
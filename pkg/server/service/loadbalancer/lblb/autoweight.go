@@ -0,0 +1,168 @@
+package lblb
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/traefik/traefik/v3/pkg/config/dynamic"
+)
+
+// AutoWeighting configures the periodic adjustment of the rates of the servers to their observed capacity.
+type AutoWeighting struct {
+	// Interval is the period of the adjustments.
+	Interval time.Duration `json:"interval"`
+	// LearningRate is the fraction, between 0 and 1, of the gap between the current and the computed rate of a server
+	// closed by each adjustment.
+	LearningRate float64 `json:"learningRate"`
+	// MinAverage and MaxAverage bound the adjusted averages of the servers, per their period.
+	// A MinAverage below 1 is set to 1, and a MaxAverage below MinAverage to MinAverage,
+	// while a zero MaxAverage means no upper bound.
+	MinAverage int `json:"minAverage"`
+	MaxAverage int `json:"maxAverage"`
+}
+
+// SetDefaults sets the default values.
+func (a *AutoWeighting) SetDefaults() {
+	a.Interval = 10 * time.Second
+	a.LearningRate = 0.2
+	a.MinAverage = 1
+}
+
+// autoWeighting is the state of the periodic adjustment of the rates.
+type autoWeighting struct {
+	ctx    context.Context
+	config AutoWeighting
+	stop   func() bool
+
+	// successes are the numbers of successful responses of the servers at the previous adjustment, by name.
+	successes map[string]uint64
+}
+
+// SetAutoWeighting enables the periodic adjustment of the rates of the servers to their observed capacity,
+// i.e. their successful throughput divided by their moving average latency.
+// At every adjustment, the total rate of the servers observed since the previous one is shared among them
+// in proportion to their capacity, so that the servers consistently handling more get higher rates,
+// without the total rate running away, each adjusted average being clamped between MinAverage and MaxAverage.
+// The rates are changed with UpdateServer, and the configured priorities are kept,
+// the priority boosts and scheduled priorities in progress still applying until they end:
+// the servers of a lower priority still only receive the traffic the servers of a higher priority cannot admit.
+// A nil config disables the adjustment, which is the default, and keeps the current rates.
+func (b *LBBalancer) SetAutoWeighting(ctx context.Context, config *AutoWeighting) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.autoWeighting != nil {
+		b.autoWeighting.stop()
+		b.autoWeighting = nil
+	}
+
+	if config == nil || config.Interval <= 0 {
+		return
+	}
+
+	a := &autoWeighting{ctx: ctx, config: *config}
+	a.config.LearningRate = min(max(a.config.LearningRate, 0), 1)
+	a.config.MinAverage = max(a.config.MinAverage, 1)
+	if a.config.MaxAverage > 0 {
+		a.config.MaxAverage = max(a.config.MaxAverage, a.config.MinAverage)
+	}
+	a.successes = b.successes()
+	a.stop = b.clock.AfterFunc(a.config.Interval, func() { b.adjustWeights(a) })
+	b.autoWeighting = a
+}
+
+// adjustWeights adjusts the rates of the servers to their capacity observed since the previous adjustment,
+// and schedules the next adjustment.
+func (b *LBBalancer) adjustWeights(a *autoWeighting) {
+	b.mutex.Lock()
+
+	if b.autoWeighting != a {
+		b.mutex.Unlock()
+		return
+	}
+
+	successes := b.successes()
+	updates := b.weightUpdates(a, successes)
+	a.successes = successes
+	a.stop = b.clock.AfterFunc(a.config.Interval, func() { b.adjustWeights(a) })
+
+	b.mutex.Unlock()
+
+	for name, server := range updates {
+		if err := b.UpdateServer(name, server); err != nil {
+			log.Ctx(a.ctx).Debug().Err(err).Msgf("Skipping the automatic weighting of server %s", name)
+			continue
+		}
+
+		log.Ctx(a.ctx).Debug().Msgf("Automatic weighting set the average of server %s to %d", name, *server.Average)
+	}
+}
+
+// weightUpdates returns the new parameters of the servers whose rate is to be adjusted, by name.
+// A server is adjusted only if it has a measured latency and successful responses since the previous adjustment,
+// and only when at least two servers are.
+// It must be called with the mutex held.
+func (b *LBBalancer) weightUpdates(a *autoWeighting, successes map[string]uint64) map[string]dynamic.Server {
+	type observed struct {
+		h        *namedHandler
+		capacity float64
+	}
+
+	var servers []observed
+	var totalRate, totalCapacity float64
+	for _, h := range b.handlers {
+		if _, up := b.status[h.name]; !up || h.latency <= 0 {
+			continue
+		}
+
+		handled := successes[h.name] - min(a.successes[h.name], successes[h.name])
+		if handled == 0 {
+			continue
+		}
+
+		capacity := float64(handled) / h.latency.Seconds()
+		servers = append(servers, observed{h: h, capacity: capacity})
+		// The configured rates are adjusted, regardless of a rate scaling in progress.
+		totalRate += float64(h.limit())
+		totalCapacity += capacity
+	}
+
+	if len(servers) < 2 {
+		return nil
+	}
+
+	updates := make(map[string]dynamic.Server, len(servers))
+	for _, s := range servers {
+		current := float64(s.h.limit())
+		target := totalRate * s.capacity / totalCapacity
+		next := current + a.config.LearningRate*(target-current)
+
+		average := max(int(math.Round(next*s.h.period.Seconds())), a.config.MinAverage)
+		if a.config.MaxAverage > 0 {
+			average = min(average, a.config.MaxAverage)
+		}
+		if int64(average) == s.h.average {
+			continue
+		}
+
+		burst := int(s.h.burst)
+		period := int(s.h.period.Milliseconds())
+		prio := int(b.configuredPriority(s.h))
+		updates[s.h.name] = dynamic.Server{Burst: &burst, Average: &average, Period: &period, Priority: &prio}
+	}
+
+	return updates
+}
+
+// successes returns the number of successful, i.e. 2xx and 3xx, responses of the servers so far, by name.
+// It must be called with the mutex held.
+func (b *LBBalancer) successes() map[string]uint64 {
+	successes := make(map[string]uint64, len(b.handlers))
+	for _, h := range b.handlers {
+		successes[h.name] = h.responses[1].Load() + h.responses[2].Load()
+	}
+
+	return successes
+}
@@ -0,0 +1,163 @@
+package lblb
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLBBalancerAutoWeighting(t *testing.T) {
+	clock := newFakeClock()
+	balancer := New(nil, false)
+	balancer.clock = clock
+	defer balancer.Close()
+
+	latencies := map[string]time.Duration{"fast": time.Millisecond, "slow": 10 * time.Millisecond}
+	for _, name := range []string{"fast", "slow"} {
+		latency := latencies[name]
+		balancer.Add(name, http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			clock.Advance(latency)
+			rw.WriteHeader(http.StatusOK)
+		}), Int(10), Int(10), Int(1000), Int(1))
+	}
+
+	balancer.SetAutoWeighting(context.Background(), &AutoWeighting{Interval: time.Minute, LearningRate: 0.5, MinAverage: 1, MaxAverage: 18})
+
+	average := func(name string) int64 {
+		balancer.mutex.RLock()
+		defer balancer.mutex.RUnlock()
+
+		return balancer.servers[name].average
+	}
+
+	fast := []int64{average("fast")}
+	slow := []int64{average("slow")}
+	for range 3 {
+		start := clock.Now()
+		for range 20 {
+			balancer.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+			// Both buckets are refilled, so that both servers keep handling requests.
+			clock.Advance(time.Second)
+		}
+		clock.Advance(time.Minute - clock.Now().Sub(start))
+
+		fast = append(fast, average("fast"))
+		slow = append(slow, average("slow"))
+	}
+
+	for i := 1; i < len(fast); i++ {
+		assert.Greater(t, fast[i], fast[i-1], "fast averages %v", fast)
+		assert.Less(t, slow[i], slow[i-1], "slow averages %v", slow)
+	}
+
+	// The total rate is kept, and the averages are bounded.
+	for i := range fast {
+		assert.InDelta(t, 20, fast[i]+slow[i], 1)
+		assert.LessOrEqual(t, fast[i], int64(18))
+	}
+
+	// Once disabled, the rates are not adjusted anymore.
+	balancer.SetAutoWeighting(context.Background(), nil)
+	for range 20 {
+		balancer.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	}
+	clock.Advance(time.Minute)
+	assert.Equal(t, fast[len(fast)-1], average("fast"))
+}
+
+func TestLBBalancerAutoWeightingKeepsPriority(t *testing.T) {
+	clock := newFakeClock()
+	balancer := New(nil, false)
+	balancer.clock = clock
+	defer balancer.Close()
+
+	for i, name := range []string{"first", "second"} {
+		balancer.Add(name, http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			clock.Advance(time.Duration(i+1) * time.Millisecond)
+		}), Int(1), Int(10), Int(1000), Int(i+1))
+	}
+
+	balancer.SetAutoWeighting(context.Background(), &AutoWeighting{Interval: time.Minute, LearningRate: 1})
+	require.NoError(t, balancer.BoostPriority("second", 1, time.Hour))
+
+	for range 20 {
+		balancer.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+		clock.Advance(100 * time.Millisecond)
+	}
+	clock.Advance(time.Minute)
+
+	balancer.mutex.RLock()
+	defer balancer.mutex.RUnlock()
+
+	assert.Equal(t, int64(1), balancer.servers["first"].priority)
+	assert.Equal(t, int64(1), balancer.servers["second"].priority)
+	assert.Equal(t, int64(2), balancer.boosts["second"].original)
+}
+
+func TestLBBalancerAutoWeightingBounds(t *testing.T) {
+	testCases := []struct {
+		desc         string
+		config       AutoWeighting
+		expectedFast int64
+		expectedSlow int64
+	}{
+		{
+			desc:         "unbounded",
+			config:       AutoWeighting{Interval: time.Minute, LearningRate: 1},
+			expectedFast: 20,
+			expectedSlow: 1,
+		},
+		{
+			desc:         "clamped",
+			config:       AutoWeighting{Interval: time.Minute, LearningRate: 1, MinAverage: 5, MaxAverage: 12},
+			expectedFast: 12,
+			expectedSlow: 5,
+		},
+		{
+			desc:         "maximum below the minimum",
+			config:       AutoWeighting{Interval: time.Minute, LearningRate: 1, MinAverage: 8, MaxAverage: 2},
+			expectedFast: 8,
+			expectedSlow: 8,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			clock := newFakeClock()
+			balancer := New(nil, false)
+			balancer.clock = clock
+			defer balancer.Close()
+
+			latencies := map[string]time.Duration{"fast": time.Millisecond, "slow": time.Second}
+			for _, name := range []string{"fast", "slow"} {
+				latency := latencies[name]
+				balancer.Add(name, http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+					clock.Advance(latency)
+					rw.WriteHeader(http.StatusOK)
+				}), Int(10), Int(10), Int(1000), Int(1))
+			}
+
+			balancer.SetAutoWeighting(context.Background(), &test.config)
+
+			start := clock.Now()
+			for range 20 {
+				balancer.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+				clock.Advance(time.Second)
+			}
+			clock.Advance(time.Minute - clock.Now().Sub(start))
+
+			balancer.mutex.RLock()
+			defer balancer.mutex.RUnlock()
+
+			assert.Equal(t, test.expectedFast, balancer.servers["fast"].average)
+			assert.Equal(t, test.expectedSlow, balancer.servers["slow"].average)
+		})
+	}
+}
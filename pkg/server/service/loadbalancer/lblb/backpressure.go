@@ -0,0 +1,114 @@
+package lblb
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Backpressure configures the reduction of the rates of the servers reporting a high queue depth.
+type Backpressure struct {
+	// Header is the response header the servers report their queue depth in.
+	Header string `json:"header"`
+	// MaxDepth is the queue depth at which the rate of a server is reduced to MinFactor of its configured rate,
+	// the rate being reduced linearly below it.
+	MaxDepth float64 `json:"maxDepth"`
+	// MinFactor is the fraction, between 0 and 1 excluded, of its configured rate a server is at least admitted,
+	// so that it keeps receiving the responses reporting its recovery.
+	MinFactor float64 `json:"minFactor"`
+	// HalfLife is the time after which the estimated queue depth of a server not reporting it anymore is halved.
+	HalfLife time.Duration `json:"halfLife"`
+}
+
+// SetDefaults sets the default values.
+func (b *Backpressure) SetDefaults() {
+	b.Header = "X-Queue-Depth"
+	b.MaxDepth = 100
+	b.MinFactor = 0.1
+	b.HalfLife = 10 * time.Second
+}
+
+// factor returns the fraction of its configured rate a server with the given queue depth is admitted.
+func (b *Backpressure) factor(depth float64) float64 {
+	return 1 - (1-b.MinFactor)*min(depth/b.MaxDepth, 1)
+}
+
+// backpressureEstimate is the estimated queue depth of a server.
+type backpressureEstimate struct {
+	// depth is the queue depth estimated at.
+	depth float64
+	at    time.Time
+	// factor is the fraction of its configured rate the server is admitted.
+	factor float64
+}
+
+// rateFactor returns the fraction of its configured rate the server is admitted, 1 without any estimate.
+func (e *backpressureEstimate) rateFactor() float64 {
+	if e == nil {
+		return 1
+	}
+
+	return e.factor
+}
+
+// SetBackpressure enables the reduction of the rates of the servers reporting a high queue depth
+// in a response header, which is a feedback loop on top of their configured rates.
+// The queue depth of a server is estimated from its latest report, and decays over time
+// when it does not report it anymore, the rate of the server being updated with every response.
+// The rate scaling of ScaleAllRates applies on top of it.
+// A nil config disables the reduction, which is the default, and restores the configured rates.
+func (b *LBBalancer) SetBackpressure(config *Backpressure) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.backpressure = nil
+	for _, h := range b.handlers {
+		h.backpressure = nil
+	}
+
+	if config != nil && config.Header != "" && config.MaxDepth > 0 {
+		c := *config
+		c.MinFactor = min(max(c.MinFactor, 0.01), 1)
+		b.backpressure = &c
+	}
+
+	b.applyRates()
+}
+
+// recordBackpressure updates the estimated queue depth of h with the headers of one of its responses,
+// and its rate accordingly.
+func (b *LBBalancer) recordBackpressure(h *namedHandler, header http.Header) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	config := b.backpressure
+	if config == nil {
+		return
+	}
+
+	now := b.clock.Now()
+
+	var depth float64
+	if e := h.backpressure; e != nil && config.HalfLife > 0 {
+		depth = e.depth * math.Pow(0.5, float64(now.Sub(e.at))/float64(config.HalfLife))
+	}
+
+	if value := header.Get(config.Header); value != "" {
+		reported, err := strconv.ParseFloat(value, 64)
+		if err != nil || reported < 0 || math.IsNaN(reported) || math.IsInf(reported, 0) {
+			log.Debug().Msgf("Ignoring the invalid queue depth %q reported by server %s", value, h.name)
+		} else {
+			depth = reported
+		}
+	}
+
+	if h.backpressure == nil && depth == 0 {
+		return
+	}
+
+	h.backpressure = &backpressureEstimate{depth: depth, at: now, factor: config.factor(depth)}
+	b.applyRate(h, now)
+}
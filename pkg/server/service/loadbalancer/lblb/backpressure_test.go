@@ -0,0 +1,85 @@
+package lblb
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/time/rate"
+)
+
+func TestLBBalancerBackpressure(t *testing.T) {
+	clock := newFakeClock()
+
+	balancer := New(nil, false)
+	balancer.clock = clock
+	balancer.SetBackpressure(&Backpressure{
+		Header:    "X-Queue-Depth",
+		MaxDepth:  100,
+		MinFactor: 0.1,
+		HalfLife:  time.Minute,
+	})
+
+	var depth atomic.Int64
+	balancer.Add("first", http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Header().Set("server", "first")
+		rw.Header().Set("X-Queue-Depth", strconv.FormatInt(depth.Load(), 10))
+		rw.WriteHeader(http.StatusOK)
+	}), Int(1), Int(20), Int(1000), Int(1))
+
+	balancer.Add("second", http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Header().Set("server", "second")
+		rw.WriteHeader(http.StatusOK)
+	}), Int(100), Int(1000), Int(1000), Int(2))
+
+	// The rising queue depth reported by first lowers its share of the traffic.
+	var shares []int
+	for _, reported := range []int64{0, 50, 100} {
+		depth.Store(reported)
+
+		recorder := &responseRecorder{ResponseRecorder: httptest.NewRecorder(), save: map[string]int{}}
+		for range 100 {
+			clock.Advance(10 * time.Millisecond)
+			balancer.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+		}
+
+		shares = append(shares, recorder.save["first"])
+	}
+
+	assert.InDelta(t, 20, shares[0], 1)
+	assert.InDelta(t, 11, shares[1], 2)
+	assert.InDelta(t, 2, shares[2], 2)
+
+	// The estimate decays once first does not report its queue depth anymore.
+	first := balancer.servers["first"]
+	clock.Advance(5 * time.Minute)
+	balancer.recordBackpressure(first, http.Header{})
+	assert.InDelta(t, 20*(1-0.9*100.0/32/100), float64(first.bucket.Limit()), 0.01)
+
+	balancer.SetBackpressure(nil)
+	assert.Equal(t, rate.Limit(20), first.bucket.Limit())
+}
+
+func TestLBBalancerBackpressureNonFinite(t *testing.T) {
+	clock := newFakeClock()
+
+	balancer := New(nil, false)
+	balancer.clock = clock
+	balancer.SetBackpressure(&Backpressure{Header: "X-Queue-Depth", MaxDepth: 100, MinFactor: 0.1, HalfLife: time.Minute})
+
+	balancer.Add("first", http.NotFoundHandler(), Int(1), Int(20), Int(1000), Int(1))
+	first := balancer.servers["first"]
+
+	balancer.recordBackpressure(first, http.Header{"X-Queue-Depth": {"50"}})
+	limit := first.bucket.Limit()
+
+	// The non-finite queue depths are ignored, instead of making the rate unlimited.
+	for _, value := range []string{"NaN", "Inf", "+Inf", "-Inf"} {
+		balancer.recordBackpressure(first, http.Header{"X-Queue-Depth": {value}})
+		assert.Equal(t, limit, first.bucket.Limit(), value)
+	}
+}
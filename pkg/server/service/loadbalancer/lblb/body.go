@@ -0,0 +1,50 @@
+package lblb
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/rs/zerolog/log"
+)
+
+// SetMaxRequestBodySize sets the maximum size, in bytes, of the request bodies dispatched to the named server,
+// protecting it from oversized uploads, so that servers with different limits, e.g. an upload service and an API,
+// can be balanced together.
+// A request announcing a larger body is rejected with a 413 without reaching the server,
+// counted with the RejectBodyTooLarge reason, and the tokens it was admitted with are given back.
+// The body of a request without a known length is cut at the limit, the server getting an error reading past it.
+// A non-positive size disables the limit, which is the default.
+// It returns an error if no such server exists.
+func (b *LBBalancer) SetMaxRequestBodySize(name string, size int64) error {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	h, ok := b.servers[name]
+	if !ok {
+		return fmt.Errorf("unknown server %s", name)
+	}
+
+	h.maxRequestBodySize.Store(max(size, 0))
+
+	return nil
+}
+
+// limitRequestBody enforces the maximum request body size of server on req,
+// and reports whether req can be dispatched to it, i.e. does not announce a larger body.
+func (b *LBBalancer) limitRequestBody(rw http.ResponseWriter, req *http.Request, server *namedHandler) bool {
+	limit := server.maxRequestBodySize.Load()
+	if limit <= 0 {
+		return true
+	}
+
+	if req.ContentLength > limit {
+		log.Ctx(req.Context()).Debug().Msgf("Rejecting a request body of %d bytes, above the maximum of %d bytes of server %s", req.ContentLength, limit, server.name)
+		return false
+	}
+
+	if req.Body != nil && req.Body != http.NoBody {
+		req.Body = http.MaxBytesReader(rw, req.Body, limit)
+	}
+
+	return true
+}
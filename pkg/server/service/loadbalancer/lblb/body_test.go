@@ -0,0 +1,82 @@
+package lblb
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLBBalancerMaxRequestBodySize(t *testing.T) {
+	balancer := New(nil, false)
+
+	var bodies []string
+	for i, name := range []string{"upload", "api"} {
+		balancer.Add(name, http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			body, err := io.ReadAll(req.Body)
+			if err != nil {
+				rw.WriteHeader(http.StatusBadRequest)
+				return
+			}
+
+			bodies = append(bodies, name+":"+string(body))
+			rw.WriteHeader(http.StatusOK)
+		}), Int(1), Int(1), Int(3600000), Int(i+1))
+	}
+
+	// The global bucket only has the tokens of the admitted requests.
+	balancer.SetGlobalRateLimit(1, time.Hour, 2)
+
+	require.NoError(t, balancer.SetMaxRequestBodySize("upload", 5))
+	assert.Error(t, balancer.SetMaxRequestBodySize("unknown", 5))
+
+	serve := func(body io.Reader) int {
+		recorder := httptest.NewRecorder()
+		balancer.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/", body))
+		return recorder.Code
+	}
+
+	// The tokens of upload and of the balancer are given back when its request is rejected.
+	assert.Equal(t, http.StatusRequestEntityTooLarge, serve(strings.NewReader("too large")))
+	assert.Empty(t, bodies)
+
+	stats := balancer.Stats()
+	assert.Equal(t, uint64(1), stats.RejectionReasons[RejectBodyTooLarge])
+	assert.Zero(t, stats.Servers["upload"].Served)
+
+	assert.Equal(t, http.StatusOK, serve(strings.NewReader("small")))
+	assert.Equal(t, []string{"upload:small"}, bodies)
+
+	// The other server has no limit.
+	assert.Equal(t, http.StatusOK, serve(strings.NewReader("too large")))
+	assert.Equal(t, []string{"upload:small", "api:too large"}, bodies)
+}
+
+func TestLBBalancerMaxRequestBodySizeUnknownLength(t *testing.T) {
+	balancer := New(nil, false)
+
+	var read int
+	balancer.Add("upload", http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		body, err := io.ReadAll(req.Body)
+		read = len(body)
+		if err != nil {
+			rw.WriteHeader(http.StatusRequestEntityTooLarge)
+			return
+		}
+		rw.WriteHeader(http.StatusOK)
+	}), Int(1), Int(1), Int(1), Int(1))
+	require.NoError(t, balancer.SetMaxRequestBodySize("upload", 5))
+
+	req := httptest.NewRequest(http.MethodPost, "/", io.NopCloser(strings.NewReader("too large")))
+	req.ContentLength = -1
+	recorder := httptest.NewRecorder()
+	balancer.ServeHTTP(recorder, req)
+
+	assert.Equal(t, http.StatusRequestEntityTooLarge, recorder.Code)
+	assert.Equal(t, 5, read)
+}
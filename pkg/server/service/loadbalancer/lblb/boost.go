@@ -0,0 +1,72 @@
+package lblb
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// boost is a temporary priority boost of a server.
+type boost struct {
+	// original is the priority restored when the boost ends.
+	original int64
+	stop     func() bool
+}
+
+// BoostPriority sets the priority of the named server to priority for the given duration,
+// after which its original priority is restored.
+// Boosting an already boosted server replaces the boost, and the original priority is still the one restored.
+func (b *LBBalancer) BoostPriority(name string, priority int, duration time.Duration) error {
+	if duration <= 0 {
+		return fmt.Errorf("invalid boost duration %s", duration)
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	h, ok := b.servers[name]
+	if !ok {
+		return fmt.Errorf("unknown server %s", name)
+	}
+
+	original := h.priority
+	if previous, ok := b.boosts[name]; ok {
+		previous.stop()
+		original = previous.original
+	}
+
+	h.priority = int64(max(priority, 1))
+	b.fix(h)
+
+	bst := &boost{original: original}
+	bst.stop = b.clock.AfterFunc(duration, func() { b.endBoost(name, bst) })
+	b.boosts[name] = bst
+
+	log.Debug().Msgf("Boosting priority of %s to %d for %s", name, h.priority, duration)
+
+	b.audit(AuditBoostPriority, map[string]any{"name": name, "priority": h.priority, "duration": duration})
+
+	return nil
+}
+
+// endBoost restores the original priority of the named server, if bst is still its current boost.
+func (b *LBBalancer) endBoost(name string, bst *boost) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.boosts[name] != bst {
+		return
+	}
+	delete(b.boosts, name)
+
+	h, ok := b.servers[name]
+	if !ok {
+		return
+	}
+
+	h.priority = bst.original
+	b.fix(h)
+
+	log.Debug().Msgf("Priority boost of %s ended, restoring priority %d", name, h.priority)
+}
@@ -0,0 +1,98 @@
+package lblb
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/traefik/traefik/v3/pkg/config/dynamic"
+)
+
+func newBoostTestBalancer(clock *fakeClock) *LBBalancer {
+	balancer := New(nil, false)
+	balancer.clock = clock
+
+	for i, name := range []string{"first", "second"} {
+		balancer.Add(name, http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			rw.Header().Set("server", name)
+			rw.WriteHeader(http.StatusOK)
+		}), Int(100), Int(100), Int(1), Int(i+2))
+	}
+
+	return balancer
+}
+
+func serveOne(balancer *LBBalancer) string {
+	recorder := &responseRecorder{ResponseRecorder: httptest.NewRecorder(), save: map[string]int{}}
+	balancer.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	return recorder.Header().Get("server")
+}
+
+func TestLBBalancerBoostPriority(t *testing.T) {
+	clock := newFakeClock()
+	balancer := newBoostTestBalancer(clock)
+
+	assert.Equal(t, "first", serveOne(balancer))
+
+	require.NoError(t, balancer.BoostPriority("second", 1, 10*time.Second))
+	assert.Equal(t, "second", serveOne(balancer))
+
+	clock.Advance(10*time.Second - time.Millisecond)
+	assert.Equal(t, "second", serveOne(balancer))
+
+	clock.Advance(time.Millisecond)
+	assert.Equal(t, "first", serveOne(balancer))
+	assert.Equal(t, int64(3), balancer.servers["second"].priority)
+	assert.Empty(t, balancer.boosts)
+
+	assert.Error(t, balancer.BoostPriority("unknown", 1, time.Second))
+	assert.Error(t, balancer.BoostPriority("second", 1, 0))
+}
+
+func TestLBBalancerBoostPriorityOverlap(t *testing.T) {
+	clock := newFakeClock()
+	balancer := newBoostTestBalancer(clock)
+
+	require.NoError(t, balancer.BoostPriority("second", 1, 10*time.Second))
+
+	// The second boost replaces the first one, and extends it.
+	clock.Advance(5 * time.Second)
+	require.NoError(t, balancer.BoostPriority("second", 1, 10*time.Second))
+
+	clock.Advance(5 * time.Second)
+	assert.Equal(t, "second", serveOne(balancer))
+
+	// The original priority is restored, not the one of the first boost.
+	clock.Advance(5 * time.Second)
+	assert.Equal(t, "first", serveOne(balancer))
+	assert.Equal(t, int64(3), balancer.servers["second"].priority)
+}
+
+func TestLBBalancerBoostPriorityUpdate(t *testing.T) {
+	clock := newFakeClock()
+	balancer := newBoostTestBalancer(clock)
+
+	require.NoError(t, balancer.BoostPriority("second", 1, 10*time.Second))
+
+	// An update during the boost sets the priority restored at the end of it.
+	require.NoError(t, balancer.UpdateServer("second", dynamic.Server{Burst: Int(100), Average: Int(100), Period: Int(1), Priority: Int(4)}))
+	assert.Equal(t, "second", serveOne(balancer))
+
+	clock.Advance(10 * time.Second)
+	assert.Equal(t, int64(4), balancer.servers["second"].priority)
+}
+
+func TestLBBalancerBoostPriorityClose(t *testing.T) {
+	clock := newFakeClock()
+	balancer := newBoostTestBalancer(clock)
+
+	require.NoError(t, balancer.BoostPriority("second", 1, 10*time.Second))
+	balancer.Close()
+
+	clock.Advance(time.Minute)
+	assert.Equal(t, "second", serveOne(balancer))
+}
@@ -0,0 +1,77 @@
+package lblb
+
+import (
+	"math/rand/v2"
+	"time"
+)
+
+// BucketEvent is the state of the bucket of a server around one of its admission decisions.
+type BucketEvent struct {
+	Time   time.Time `json:"time"`
+	Server string    `json:"server"`
+	// Before and After are the tokens available in the bucket right before and after the decision.
+	Before  float64 `json:"before"`
+	After   float64 `json:"after"`
+	Allowed bool    `json:"allowed"`
+}
+
+// bucketTrace is a ring of sampled bucket events, shared by the servers and guarded by the balancer mutex.
+type bucketTrace struct {
+	rate   float64
+	events []BucketEvent
+	// next is the index of the next event to record, and full whether the ring wrapped around.
+	next int
+	full bool
+}
+
+// SetBucketTrace enables the recording of the state of the buckets of the servers around their admission decisions,
+// for a given fraction, between 0 and 1, of the decisions, keeping the latest size events,
+// to be retrieved with BucketTrace, e.g. to understand why a request was throttled at a given time.
+// As it is heavier than the other stats, the decisions are sampled, and the events are computed at selection time.
+// Changing the trace resets it, and a non-positive rate or size disables it, which is the default.
+func (b *LBBalancer) SetBucketTrace(rate float64, size int) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.bucketTrace = nil
+	if rate > 0 && size > 0 {
+		b.bucketTrace = &bucketTrace{rate: min(rate, 1), events: make([]BucketEvent, size)}
+	}
+
+	for _, h := range b.handlers {
+		h.trace = b.bucketTrace
+	}
+}
+
+// BucketTrace returns the latest sampled bucket events recorded since SetBucketTrace, oldest first.
+func (b *LBBalancer) BucketTrace() []BucketEvent {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	t := b.bucketTrace
+	if t == nil {
+		return nil
+	}
+
+	if !t.full {
+		return append([]BucketEvent(nil), t.events[:t.next]...)
+	}
+
+	return append(append([]BucketEvent(nil), t.events[t.next:]...), t.events[:t.next]...)
+}
+
+// sampled reports whether the next admission decision is to be recorded.
+func (t *bucketTrace) sampled() bool {
+	return t != nil && (t.rate >= 1 || rand.Float64() < t.rate)
+}
+
+// record adds an event to the trace.
+// It must be called with the balancer mutex held.
+func (t *bucketTrace) record(event BucketEvent) {
+	t.events[t.next] = event
+	t.next++
+	if t.next == len(t.events) {
+		t.next = 0
+		t.full = true
+	}
+}
@@ -0,0 +1,72 @@
+package lblb
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLBBalancerBucketTrace(t *testing.T) {
+	clock := newFakeClock()
+
+	balancer := New(nil, false)
+	balancer.clock = clock
+	assert.Nil(t, balancer.BucketTrace())
+
+	balancer.SetBucketTrace(1, 4)
+
+	for i, name := range []string{"first", "second"} {
+		balancer.Add(name, http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			rw.WriteHeader(http.StatusOK)
+		}), Int(2), Int(1), Int(1000), Int(i+1))
+	}
+
+	for range 3 {
+		balancer.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	}
+
+	// first admits its burst of 2, then denies, and second admits the last request.
+	events := balancer.BucketTrace()
+	require.Len(t, events, 4)
+	for i, expected := range []struct {
+		server  string
+		before  float64
+		allowed bool
+	}{
+		{server: "first", before: 2, allowed: true},
+		{server: "first", before: 1, allowed: true},
+		{server: "first", before: 0, allowed: false},
+		{server: "second", before: 2, allowed: true},
+	} {
+		event := events[i]
+		assert.Equal(t, expected.server, event.Server)
+		assert.Equal(t, clock.Now(), event.Time)
+		assert.InDelta(t, expected.before, event.Before, 0.001)
+		assert.Equal(t, expected.allowed, event.Allowed)
+		if event.Allowed {
+			assert.InDelta(t, event.Before-1, event.After, 0.001)
+		} else {
+			assert.InDelta(t, event.Before, event.After, 0.001)
+		}
+	}
+
+	// The ring keeps the latest events.
+	clock.Advance(time.Second)
+	balancer.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	events = balancer.BucketTrace()
+	require.Len(t, events, 4)
+	assert.Equal(t, "first", events[0].Server)
+	assert.False(t, events[1].Allowed)
+	assert.Equal(t, "first", events[3].Server)
+	assert.InDelta(t, 1, events[3].Before, 0.001)
+
+	// A zero rate records nothing.
+	balancer.SetBucketTrace(0, 4)
+	assert.Nil(t, balancer.BucketTrace())
+	assert.Nil(t, balancer.servers["first"].trace)
+}
@@ -0,0 +1,57 @@
+package lblb
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	"github.com/rs/zerolog/log"
+)
+
+// bypassToken is the request header, and its secret value, allowing a request to bypass the rate limiting.
+type bypassToken struct {
+	header string
+	secret []byte
+}
+
+// SetBypassToken lets the requests whose header holds secret bypass the rate limiting, e.g. for admin operations:
+// they are dispatched to the highest priority up server regardless of its bucket, and ignore the concurrency limit.
+// The header is removed before the request is forwarded, and every bypass is logged and counted in the stats.
+// An empty header or secret disables the bypass, which is the default.
+func (b *LBBalancer) SetBypassToken(header, secret string) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if header == "" || secret == "" {
+		b.bypass = nil
+		return
+	}
+
+	b.bypass = &bypassToken{header: header, secret: []byte(secret)}
+}
+
+// bypasses reports whether req carries the bypass token, and removes it from req if so.
+func (b *LBBalancer) bypasses(req *http.Request) bool {
+	b.mutex.RLock()
+	token := b.bypass
+	b.mutex.RUnlock()
+
+	if token == nil {
+		return false
+	}
+
+	value := req.Header.Get(token.header)
+	if value == "" {
+		return false
+	}
+	req.Header.Del(token.header)
+
+	if subtle.ConstantTimeCompare([]byte(value), token.secret) != 1 {
+		log.Ctx(req.Context()).Warn().Msgf("Invalid rate limiting bypass token from %s", req.RemoteAddr)
+		return false
+	}
+
+	b.bypassed.Add(1)
+	log.Ctx(req.Context()).Info().Msgf("Request from %s bypasses the rate limiting", req.RemoteAddr)
+
+	return true
+}
@@ -0,0 +1,78 @@
+package lblb
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLBBalancerBypassToken(t *testing.T) {
+	balancer := New(nil, false)
+	balancer.SetBypassToken("X-Bypass", "secret")
+	balancer.SetConcurrencyLimit(1, nil)
+
+	var tokens []string
+	for i, name := range []string{"first", "second"} {
+		balancer.Add(name, http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			tokens = append(tokens, req.Header.Get("X-Bypass"))
+			rw.Header().Set("server", name)
+			rw.WriteHeader(http.StatusOK)
+		}), Int(1), Int(1), Int(1000), Int(i+1))
+	}
+	balancer.SetStatus(context.Background(), "first", false)
+
+	serve := func(token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if token != "" {
+			req.Header.Set("X-Bypass", token)
+		}
+		recorder := httptest.NewRecorder()
+		balancer.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	// The only token of second is consumed, so a request without the token is throttled.
+	assert.Equal(t, http.StatusOK, serve("").Code)
+	assert.Equal(t, http.StatusServiceUnavailable, serve("").Code)
+	assert.Equal(t, http.StatusServiceUnavailable, serve("wrong").Code)
+
+	// With the token, the highest priority up server is selected, even with an empty bucket.
+	for range 3 {
+		recorder := serve("secret")
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, "second", recorder.Header().Get("server"))
+	}
+
+	// The token is not forwarded to the servers.
+	assert.Equal(t, []string{"", "", "", ""}, tokens)
+
+	stats := balancer.Stats()
+	assert.Equal(t, uint64(3), stats.Bypassed)
+	assert.Equal(t, uint64(2), stats.Rejected)
+}
+
+func TestLBBalancerBypassTokenConcurrencyLimit(t *testing.T) {
+	balancer := New(nil, false)
+	balancer.SetBypassToken("X-Bypass", "secret")
+	balancer.SetConcurrencyLimit(1, nil)
+
+	balancer.Add("first", http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusOK)
+	}), Int(10), Int(10), Int(1000), Int(1))
+
+	// The only concurrency slot is taken.
+	balancer.inflight.Add(1)
+
+	recorder := httptest.NewRecorder()
+	balancer.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusTooManyRequests, recorder.Code)
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-Bypass", "secret")
+	recorder = httptest.NewRecorder()
+	balancer.ServeHTTP(recorder, req)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+}
@@ -0,0 +1,315 @@
+package lblb
+
+import (
+	"bytes"
+	"container/list"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ResponseCache configures the in-memory cache of the responses of the servers, see SetResponseCache.
+type ResponseCache struct {
+	// TTL is how long a response is served from the cache.
+	TTL time.Duration `json:"ttl"`
+	// Key returns the key of the cached responses of a request, DefaultCacheKey if nil.
+	// Two requests with the same key get the same cached response.
+	Key func(*http.Request) string `json:"-"`
+	// Statuses are the status codes of the cached responses, all the 2xx ones if empty.
+	Statuses []int `json:"statuses,omitempty"`
+	// MaxEntries is the maximum number of cached responses, unbounded if not positive.
+	MaxEntries int `json:"maxEntries"`
+	// MaxBytes is the maximum total size of the bodies of the cached responses, unbounded if not positive.
+	// A response with a larger body is not cached.
+	MaxBytes int64 `json:"maxBytes"`
+}
+
+// SetDefaults sets the default values.
+func (c *ResponseCache) SetDefaults() {
+	c.TTL = 10 * time.Second
+	c.MaxEntries = 1000
+	c.MaxBytes = 10 << 20
+}
+
+// cacheable reports whether a response with the given status code is cached.
+func (c *ResponseCache) cacheable(status int) bool {
+	if len(c.Statuses) == 0 {
+		return status >= http.StatusOK && status < http.StatusMultipleChoices
+	}
+
+	return slices.Contains(c.Statuses, status)
+}
+
+// DefaultCacheKey is the default key of the cached responses: the host and the URI of the request.
+func DefaultCacheKey(req *http.Request) string {
+	return req.Host + req.URL.RequestURI()
+}
+
+// SetResponseCache enables the caching of the responses of the servers to the GET requests,
+// for idempotent read-heavy servers: a request having the key of a cached response is replied with it
+// until it expires, without being dispatched to any server, and thus without consuming any token.
+// The cache is shared by all the servers, and evicts the least recently used responses when full.
+// The responses with a "Cache-Control: no-store" or "private" header are never cached,
+// and neither are the streamed ones, i.e. flushed before their end, the ones with trailers,
+// nor the ones with a Vary header, as the key of a request does not include the headers it varies on.
+// The requests with credentials, i.e. with an Authorization or a Cookie header,
+// only get, and only have cached, the responses with a "Cache-Control: public" header,
+// so that a response specific to a client is never replayed to another one.
+// Setting the cache empties it, and a nil config or a non-positive TTL disables it, which is the default.
+func (b *LBBalancer) SetResponseCache(config *ResponseCache) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if config == nil || config.TTL <= 0 {
+		b.responseCache = nil
+		return
+	}
+
+	c := *config
+	if c.Key == nil {
+		c.Key = DefaultCacheKey
+	}
+	c.Statuses = slices.Clone(c.Statuses)
+
+	b.responseCache = &responseCache{config: c, entries: make(map[string]*list.Element), lru: list.New()}
+}
+
+// responseCache is a LRU cache of responses, guarded by its own mutex rather than the balancer one,
+// so that the cache hits do not contend with the selections.
+type responseCache struct {
+	config ResponseCache
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	// lru holds the cached responses, the most recently used first.
+	lru   *list.List
+	bytes int64
+	hits  uint64
+}
+
+// cachedResponse is a response of the cache.
+type cachedResponse struct {
+	key     string
+	status  int
+	header  http.Header
+	body    []byte
+	expires time.Time
+	// public is whether the response is explicitly public, and can then be replied to the requests with credentials.
+	public bool
+}
+
+// cacheOf returns the response cache of the balancer, and the key of req in it,
+// nil if req is not a GET request, or if the cache is disabled.
+func (b *LBBalancer) cacheOf(req *http.Request) (*responseCache, string) {
+	if req.Method != http.MethodGet {
+		return nil, ""
+	}
+
+	b.mutex.RLock()
+	c := b.responseCache
+	b.mutex.RUnlock()
+
+	if c == nil {
+		return nil, ""
+	}
+
+	return c, c.config.Key(req)
+}
+
+// get returns the response cached with the key at now, nil if none,
+// or if the request has credentials, and the response is not public.
+func (c *responseCache) get(key string, credentials bool, now time.Time) *cachedResponse {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil
+	}
+
+	response := elem.Value.(*cachedResponse)
+	if !now.Before(response.expires) {
+		c.remove(elem)
+		return nil
+	}
+	if credentials && !response.public {
+		return nil
+	}
+
+	c.lru.MoveToFront(elem)
+	c.hits++
+
+	return response
+}
+
+// put caches a response with the key at now, to a request with or without credentials,
+// evicting the least recently used responses as needed.
+func (c *responseCache) put(key string, credentials bool, status int, header http.Header, body []byte, now time.Time) {
+	if !c.config.cacheable(status) || !storable(header) || hasTrailers(header) || len(header.Values("Vary")) > 0 {
+		return
+	}
+
+	public := hasDirective(header, "public")
+	if credentials && !public {
+		return
+	}
+	if c.config.MaxBytes > 0 && int64(len(body)) > c.config.MaxBytes {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.entries[key]; ok {
+		c.remove(elem)
+	}
+
+	// The headers specific to the client, or to the admission, are not replayed to the other clients.
+	header = header.Clone()
+	for _, name := range []string{"Set-Cookie", "Connection", headerRateLimitLimit, headerRateLimitRemaining, headerRateLimitReset} {
+		header.Del(name)
+	}
+
+	response := &cachedResponse{key: key, status: status, header: header, body: body, expires: now.Add(c.config.TTL), public: public}
+	c.entries[key] = c.lru.PushFront(response)
+	c.bytes += int64(len(body))
+
+	for (c.config.MaxEntries > 0 && c.lru.Len() > c.config.MaxEntries) || (c.config.MaxBytes > 0 && c.bytes > c.config.MaxBytes) {
+		c.remove(c.lru.Back())
+	}
+}
+
+// remove removes a cached response.
+// It must be called with the cache mutex held.
+func (c *responseCache) remove(elem *list.Element) {
+	response := c.lru.Remove(elem).(*cachedResponse)
+	delete(c.entries, response.key)
+	c.bytes -= int64(len(response.body))
+}
+
+// stats returns the number of cached responses, and of the requests replied from the cache.
+func (c *responseCache) stats() (int, uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.lru.Len(), c.hits
+}
+
+// storable reports whether the Cache-Control header of a response allows it to be cached.
+func storable(header http.Header) bool {
+	return !hasDirective(header, "no-store") && !hasDirective(header, "private")
+}
+
+// hasDirective reports whether the Cache-Control header has the given directive, without argument.
+func hasDirective(header http.Header, directive string) bool {
+	for _, value := range header.Values("Cache-Control") {
+		for d := range strings.SplitSeq(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(d), directive) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// hasCredentials reports whether req has credentials, which may make the response specific to its client.
+func hasCredentials(req *http.Request) bool {
+	return req.Header.Get("Authorization") != "" || req.Header.Get("Cookie") != ""
+}
+
+// hasTrailers reports whether a response has trailers, either announced or set after its body,
+// which cannot be replayed from the headers of the response.
+func hasTrailers(header http.Header) bool {
+	if len(header.Values("Trailer")) > 0 {
+		return true
+	}
+
+	for name := range header {
+		if strings.HasPrefix(name, http.TrailerPrefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// write replies with the cached response.
+func (r *cachedResponse) write(rw http.ResponseWriter) {
+	for name, values := range r.header {
+		rw.Header()[name] = slices.Clone(values)
+	}
+
+	rw.WriteHeader(r.status)
+	_, _ = rw.Write(r.body)
+}
+
+// cacheRecorder is a http.ResponseWriter recording the response to be cached, up to a maximum body size.
+type cacheRecorder struct {
+	http.ResponseWriter
+
+	status int
+	body   bytes.Buffer
+	limit  int64
+	// exceeded is whether the body exceeded the limit, or was flushed, the response then not being cached.
+	exceeded bool
+}
+
+func (r *cacheRecorder) WriteHeader(statusCode int) {
+	// Informational responses are not final.
+	if r.status == 0 && statusCode >= http.StatusOK {
+		r.status = statusCode
+	}
+
+	r.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (r *cacheRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+
+	n, err := r.ResponseWriter.Write(b)
+	if !r.exceeded {
+		if r.limit > 0 && int64(r.body.Len()+n) > r.limit {
+			r.exceeded = true
+			r.body = bytes.Buffer{}
+		} else {
+			r.body.Write(b[:n])
+		}
+	}
+
+	return n, err
+}
+
+// Flush implements http.Flusher.
+// A flushed response is streamed, and is not cached.
+func (r *cacheRecorder) Flush() {
+	r.exceeded = true
+	r.body = bytes.Buffer{}
+
+	if flusher, ok := r.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap returns the underlying http.ResponseWriter, for http.ResponseController.
+func (r *cacheRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// cached returns the recorded response to be cached, false if it cannot be.
+func (r *cacheRecorder) cached() (int, []byte, bool) {
+	if r.exceeded {
+		return 0, nil, false
+	}
+
+	status := r.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+
+	return status, bytes.Clone(r.body.Bytes()), true
+}
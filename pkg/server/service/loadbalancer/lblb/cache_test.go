@@ -0,0 +1,188 @@
+package lblb
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLBBalancerResponseCache(t *testing.T) {
+	clock := newFakeClock()
+	balancer := New(nil, false)
+	balancer.clock = clock
+	balancer.SetResponseCache(&ResponseCache{TTL: time.Second})
+
+	var hits int
+	balancer.Add("first", http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		hits++
+		rw.Header().Set("Content-Type", "text/plain")
+		if req.URL.Path == "/private" {
+			rw.Header().Set("Cache-Control", "private")
+		}
+		if req.URL.Path == "/missing" {
+			rw.WriteHeader(http.StatusNotFound)
+		}
+		_, _ = rw.Write([]byte(req.URL.Path))
+	}), Int(10), Int(1), Int(1000), Int(1))
+
+	serve := func(method, path string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		balancer.ServeHTTP(recorder, httptest.NewRequest(method, path, nil))
+		return recorder
+	}
+	tokens := func() float64 {
+		balancer.mutex.RLock()
+		defer balancer.mutex.RUnlock()
+
+		return balancer.servers["first"].bucket.TokensAt(clock.Now())
+	}
+
+	first := serve(http.MethodGet, "/")
+	assert.Equal(t, http.StatusOK, first.Code)
+	assert.Equal(t, 1, hits)
+	assert.InDelta(t, 9, tokens(), 0.01)
+
+	// A repeated request within the TTL is replied from the cache, without any token consumed.
+	clock.Advance(500 * time.Millisecond)
+	cached := serve(http.MethodGet, "/")
+	assert.Equal(t, http.StatusOK, cached.Code)
+	assert.Equal(t, "/", cached.Body.String())
+	assert.Equal(t, "text/plain", cached.Header().Get("Content-Type"))
+	assert.Empty(t, cached.Header().Get(headerRateLimitRemaining))
+	assert.Equal(t, 1, hits)
+	assert.InDelta(t, 9.5, tokens(), 0.01)
+
+	// After expiry, the response is fetched again.
+	clock.Advance(500 * time.Millisecond)
+	assert.Equal(t, "/", serve(http.MethodGet, "/").Body.String())
+	assert.Equal(t, 2, hits)
+
+	// The uncacheable responses, and the non-GET requests, always reach the server.
+	for _, path := range []string{"/missing", "/private"} {
+		serve(http.MethodGet, path)
+		serve(http.MethodGet, path)
+	}
+	serve(http.MethodPost, "/")
+	assert.Equal(t, 7, hits)
+
+	stats := balancer.Stats().Cache
+	require.NotNil(t, stats)
+	assert.Equal(t, CacheStats{Entries: 1, Hits: 1}, *stats)
+}
+
+func TestLBBalancerResponseCacheEviction(t *testing.T) {
+	clock := newFakeClock()
+	balancer := New(nil, false)
+	balancer.clock = clock
+	balancer.SetResponseCache(&ResponseCache{TTL: time.Minute, MaxEntries: 2, MaxBytes: 4})
+
+	served := make(map[string]int)
+	balancer.Add("first", http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		served[req.URL.Path]++
+		_, _ = rw.Write([]byte(req.URL.Path))
+	}), Int(100), Int(1), Int(1000), Int(1))
+
+	serve := func(path string) {
+		balancer.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	serve("/a")
+	serve("/b")
+	// /a is used more recently than /b, which is evicted by /c.
+	serve("/a")
+	serve("/c")
+	serve("/a")
+	serve("/b")
+	// A body larger than the maximum size is never cached.
+	serve("/large")
+	serve("/large")
+
+	assert.Equal(t, map[string]int{"/a": 1, "/b": 2, "/c": 1, "/large": 2}, served)
+}
+
+func TestLBBalancerResponseCacheTrailers(t *testing.T) {
+	balancer := New(nil, false)
+	balancer.clock = newFakeClock()
+	balancer.SetResponseCache(&ResponseCache{TTL: time.Minute})
+
+	var hits int
+	balancer.Add("first", http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		hits++
+		if req.URL.Path == "/announced" {
+			rw.Header().Set("Trailer", "X-Checksum")
+		}
+		_, _ = rw.Write([]byte("body"))
+		if req.URL.Path == "/announced" {
+			rw.Header().Set("X-Checksum", "sum")
+		} else {
+			rw.Header().Set(http.TrailerPrefix+"X-Checksum", "sum")
+		}
+	}), Int(10), Int(1), Int(1000), Int(1))
+
+	// The trailers could not be replayed from the cache.
+	for _, path := range []string{"/announced", "/late"} {
+		for range 2 {
+			balancer.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+		}
+	}
+
+	assert.Equal(t, 4, hits)
+	assert.Zero(t, balancer.Stats().Cache.Entries)
+}
+
+func TestLBBalancerResponseCacheCredentials(t *testing.T) {
+	clock := newFakeClock()
+	balancer := New(nil, false)
+	balancer.clock = clock
+	balancer.SetResponseCache(&ResponseCache{TTL: time.Second})
+
+	hits := map[string]int{}
+	balancer.Add("first", http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		hits[req.URL.Path]++
+		switch req.URL.Path {
+		case "/public":
+			rw.Header().Set("Cache-Control", "max-age=60, public")
+		case "/vary":
+			rw.Header().Set("Vary", "Accept-Language")
+		}
+		_, _ = rw.Write([]byte(req.Header.Get("Authorization")))
+	}), Int(100), Int(1), Int(1000), Int(1))
+
+	serve := func(path, name, value string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if name != "" {
+			req.Header.Set(name, value)
+		}
+		recorder := httptest.NewRecorder()
+		balancer.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	// The requests with credentials each reach the server.
+	assert.Equal(t, "alice", serve("/", "Authorization", "alice").Body.String())
+	assert.Equal(t, "bob", serve("/", "Authorization", "bob").Body.String())
+	serve("/", "Cookie", "session=alice")
+	serve("/", "Cookie", "session=alice")
+	assert.Equal(t, 4, hits["/"])
+
+	// A response cached for the requests without credentials is not replied to the ones with credentials.
+	serve("/", "", "")
+	serve("/", "", "")
+	assert.Equal(t, 5, hits["/"])
+	assert.Equal(t, "bob", serve("/", "Authorization", "bob").Body.String())
+	assert.Equal(t, 6, hits["/"])
+
+	// The public responses are cached, and replied, whatever the credentials.
+	assert.Equal(t, "alice", serve("/public", "Authorization", "alice").Body.String())
+	assert.Equal(t, "alice", serve("/public", "Authorization", "bob").Body.String())
+	assert.Equal(t, 1, hits["/public"])
+
+	// The responses with a Vary header are not cached.
+	serve("/vary", "", "")
+	serve("/vary", "", "")
+	assert.Equal(t, 2, hits["/vary"])
+}
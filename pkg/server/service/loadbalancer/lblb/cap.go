@@ -0,0 +1,60 @@
+package lblb
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// capRejection is the response to the requests rejected by a cap of the balancer as a whole.
+type capRejection struct {
+	status     int
+	retryAfter time.Duration
+}
+
+// defaultCapRejection is a 429 asking the client to retry after a second.
+var defaultCapRejection = capRejection{status: http.StatusTooManyRequests, retryAfter: time.Second}
+
+// SetCapRejection sets the status, and the delay sent in the Retry-After header, of the responses
+// to the requests rejected by a cap of the balancer as a whole, e.g. its concurrency limit.
+// They are told apart from the requests rejected because every server is exhausted, which get a 503,
+// so that the clients and dashboards distinguish the aggregate throttling from the backend throttling.
+// A non-positive status restores the default, a 429 with a Retry-After of one second,
+// and a non-positive retryAfter omits the Retry-After header.
+func (b *LBBalancer) SetCapRejection(status int, retryAfter time.Duration) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if status <= 0 {
+		b.capRejection = defaultCapRejection
+		return
+	}
+
+	b.capRejection = capRejection{status: status, retryAfter: max(retryAfter, 0)}
+}
+
+// writeCapped writes the response for a request rejected by a cap of the balancer because of err.
+func (b *LBBalancer) writeCapped(rw http.ResponseWriter, req *http.Request, err error) {
+	b.rejected.Add(1)
+	b.capped.Add(1)
+
+	log.Ctx(req.Context()).Debug().Err(err).Msg("Request rejected by a cap of the balancer")
+
+	b.mutex.RLock()
+	resp := b.capRejection
+	b.mutex.RUnlock()
+
+	// As for the unavailable responses, the body of the request is never read.
+	if expectsContinue(req) {
+		rw.Header().Set("Connection", "close")
+	}
+
+	if resp.retryAfter > 0 {
+		rw.Header().Set("Retry-After", strconv.FormatInt(int64(math.Ceil(resp.retryAfter.Seconds())), 10))
+	}
+
+	http.Error(rw, err.Error(), resp.status)
+}
@@ -0,0 +1,71 @@
+package lblb
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/traefik/traefik/v3/pkg/middlewares/accesslog"
+)
+
+func TestLBBalancerCapRejection(t *testing.T) {
+	balancer := New(nil, false)
+	balancer.SetAccessLogFields(true)
+	balancer.SetConcurrencyLimit(1, nil)
+
+	// Tokens are not refilled during the test.
+	balancer.Add("first", http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusOK)
+	}), Int(1), Int(1), Int(3600000), Int(1))
+
+	serve := func() (*httptest.ResponseRecorder, accesslog.CoreLogData) {
+		data := &accesslog.LogData{Core: accesslog.CoreLogData{}}
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req = req.WithContext(context.WithValue(req.Context(), accesslog.DataTableKey, data))
+
+		recorder := httptest.NewRecorder()
+		balancer.ServeHTTP(recorder, req)
+
+		return recorder, data.Core
+	}
+
+	// The only concurrency slot is taken.
+	balancer.inflight.Add(1)
+
+	recorder, fields := serve()
+	assert.Equal(t, http.StatusTooManyRequests, recorder.Code)
+	assert.Equal(t, "1", recorder.Header().Get("Retry-After"))
+	assert.Equal(t, AdmissionCapped, fields[AccessLogAdmission])
+
+	balancer.SetCapRejection(http.StatusServiceUnavailable, 1500*time.Millisecond)
+	recorder, _ = serve()
+	assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+	assert.Equal(t, "2", recorder.Header().Get("Retry-After"))
+
+	balancer.SetCapRejection(http.StatusTooManyRequests, 0)
+	recorder, _ = serve()
+	assert.Equal(t, http.StatusTooManyRequests, recorder.Code)
+	assert.Empty(t, recorder.Header().Get("Retry-After"))
+
+	balancer.SetCapRejection(0, 0)
+	recorder, _ = serve()
+	assert.Equal(t, http.StatusTooManyRequests, recorder.Code)
+	assert.Equal(t, "1", recorder.Header().Get("Retry-After"))
+
+	// The exhaustion of the servers is still a 503, without Retry-After.
+	balancer.inflight.Add(-1)
+	recorder, _ = serve()
+	assert.Equal(t, http.StatusOK, recorder.Code)
+
+	recorder, fields = serve()
+	assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+	assert.Empty(t, recorder.Header().Get("Retry-After"))
+	assert.Equal(t, AdmissionRejected, fields[AccessLogAdmission])
+
+	stats := balancer.Stats()
+	assert.Equal(t, uint64(5), stats.Rejected)
+	assert.Equal(t, uint64(4), stats.Capped)
+}
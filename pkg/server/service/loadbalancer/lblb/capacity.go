@@ -0,0 +1,84 @@
+package lblb
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// capacityCheck is the state of the periodic comparison of the demand with the configured capacity.
+type capacityCheck struct {
+	ctx      context.Context
+	interval time.Duration
+	stop     func() bool
+
+	served   uint64
+	rejected uint64
+}
+
+// SetCapacityCheck enables a periodic check, every interval, of whether the demand exceeds the configured capacity,
+// i.e. the sum of the rates of the up servers.
+// When it does, a warning with the shortfall is logged with the logger of ctx, as a capacity planning signal.
+// A non-positive interval disables the check, which is the default.
+func (b *LBBalancer) SetCapacityCheck(ctx context.Context, interval time.Duration) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.capacityCheck != nil {
+		b.capacityCheck.stop()
+		b.capacityCheck = nil
+	}
+
+	if interval <= 0 {
+		return
+	}
+
+	check := &capacityCheck{ctx: ctx, interval: interval}
+	check.served, check.rejected = b.demand()
+	check.stop = b.clock.AfterFunc(interval, func() { b.checkCapacity(check) })
+	b.capacityCheck = check
+}
+
+// checkCapacity compares the demand since the previous check with the capacity, and schedules the next check.
+func (b *LBBalancer) checkCapacity(check *capacityCheck) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.capacityCheck != check {
+		return
+	}
+
+	served, rejected := b.demand()
+	// The served count goes down when a server is removed.
+	servedDelta := served - min(check.served, served)
+	rejectedDelta := rejected - check.rejected
+	check.served, check.rejected = served, rejected
+
+	seconds := check.interval.Seconds()
+	demand := float64(servedDelta+rejectedDelta) / seconds
+	rejectedRate := float64(rejectedDelta) / seconds
+
+	var capacity float64
+	for _, h := range b.handlers {
+		if _, up := b.status[h.name]; up {
+			capacity += float64(h.bucket.Limit())
+		}
+	}
+
+	if demand > capacity {
+		log.Ctx(check.ctx).Warn().Msgf("Leaky bucket demand %.0f/s exceeds capacity %.0f/s, %.0f/s rejected", demand, capacity, rejectedRate)
+	}
+
+	check.stop = b.clock.AfterFunc(check.interval, func() { b.checkCapacity(check) })
+}
+
+// demand returns the number of requests served by the current servers, and rejected, so far.
+// It must be called with the mutex held.
+func (b *LBBalancer) demand() (served, rejected uint64) {
+	for _, h := range b.handlers {
+		served += h.served.Load()
+	}
+
+	return served, b.rejected.Load()
+}
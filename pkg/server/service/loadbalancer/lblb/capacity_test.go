@@ -0,0 +1,56 @@
+package lblb
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLBBalancerCapacityCheck(t *testing.T) {
+	clock := newFakeClock()
+	balancer := New(nil, false)
+	balancer.clock = clock
+
+	// 10 requests per second each, for a capacity of 20 requests per second.
+	for i, name := range []string{"first", "second"} {
+		balancer.Add(name, http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			rw.WriteHeader(http.StatusOK)
+		}), Int(10), Int(10), Int(1000), Int(i+1))
+	}
+
+	buf := &bytes.Buffer{}
+	ctx := zerolog.New(buf).WithContext(context.Background())
+	balancer.SetCapacityCheck(ctx, time.Second)
+	defer balancer.Close()
+
+	// 30 requests within the second: both buckets are emptied, and 10 requests are rejected.
+	for range 30 {
+		balancer.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	}
+
+	clock.Advance(time.Second)
+	assert.Contains(t, buf.String(), `"level":"warn"`)
+	assert.Contains(t, buf.String(), "Leaky bucket demand 30/s exceeds capacity 20/s, 10/s rejected")
+
+	// Within the capacity, nothing is logged.
+	buf.Reset()
+	for range 15 {
+		balancer.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	}
+	clock.Advance(time.Second)
+	assert.Empty(t, buf.String())
+
+	// Once disabled, no check is run anymore.
+	for range 30 {
+		balancer.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	}
+	balancer.SetCapacityCheck(ctx, 0)
+	clock.Advance(time.Second)
+	assert.NotContains(t, buf.String(), "exceeds capacity")
+}
@@ -0,0 +1,20 @@
+package lblb
+
+import "time"
+
+// clock is the source of time of the balancer.
+type clock interface {
+	Now() time.Time
+	// AfterFunc calls f in its own goroutine after d, and returns a function cancelling the call.
+	AfterFunc(d time.Duration, f func()) (stop func() bool)
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) AfterFunc(d time.Duration, f func()) func() bool {
+	return time.AfterFunc(d, f).Stop
+}
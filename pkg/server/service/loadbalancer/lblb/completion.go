@@ -0,0 +1,80 @@
+package lblb
+
+import (
+	"cmp"
+	"slices"
+	"time"
+)
+
+// predictedCompletion returns how long a new request dispatched to h would take to complete,
+// assuming that the requests in flight are served one after the other at its moving average latency.
+// A server without measured latency yet is predicted to complete right away, so that it gets measured.
+// It must be called with the mutex held.
+func predictedCompletion(h *namedHandler) time.Duration {
+	return time.Duration(h.inflight.Load()+1) * h.latency
+}
+
+// pickPredictedCompletion selects the server predicted to complete a new request the soonest, see predictedCompletion,
+// overflowing to the next soonest ones when its bucket does not admit the request.
+// The priorities only break ties.
+// The considered candidates are recorded in decision if it is not nil.
+// It must be called with the mutex held.
+func (b *LBBalancer) pickPredictedCompletion(decision *Decision, sel selection) (*namedHandler, error) {
+	if len(b.handlers) == 0 || len(b.status) == 0 {
+		return nil, errNoAvailableServer
+	}
+
+	now := b.clock.Now()
+
+	type candidate struct {
+		h         *namedHandler
+		predicted time.Duration
+	}
+
+	candidates := make([]candidate, 0, len(b.handlers))
+	for _, h := range b.handlers {
+		if reason := b.skipReason(h, sel, now); reason != "" {
+			decision.add(h, reason)
+			continue
+		}
+
+		candidates = append(candidates, candidate{h: h, predicted: predictedCompletion(h)})
+	}
+
+	slices.SortStableFunc(candidates, func(a, c candidate) int {
+		if predicted := cmp.Compare(a.predicted, c.predicted); predicted != 0 {
+			return predicted
+		}
+
+		switch {
+		case higherPriority(a.h, c.h):
+			return -1
+		case higherPriority(c.h, a.h):
+			return 1
+		default:
+			return 0
+		}
+	})
+
+	var throttled int
+	for _, c := range candidates {
+		if sel.bypass {
+			decision.add(c.h, "")
+			return c.h, nil
+		}
+
+		if b.admitCandidate(c.h, sel, now) {
+			decision.add(c.h, "")
+			return c.h, nil
+		}
+
+		decision.add(c.h, skipRateLimited)
+
+		throttled++
+		if b.overflowLimitReached(throttled) {
+			return nil, errOverflowLimit
+		}
+	}
+
+	return nil, errNoAvailableServer
+}
@@ -0,0 +1,77 @@
+package lblb
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLBBalancerPredictedCompletion(t *testing.T) {
+	balancer := New(nil, false)
+
+	for i, name := range []string{"slow", "fast", "busy"} {
+		balancer.Add(name, http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			rw.Header().Set("server", name)
+			rw.WriteHeader(http.StatusOK)
+		}), Int(100), Int(100), Int(1000), Int(i+1))
+	}
+
+	balancer.mutex.Lock()
+	balancer.servers["slow"].latency = 100 * time.Millisecond
+	balancer.servers["fast"].latency = 10 * time.Millisecond
+	balancer.servers["busy"].latency = time.Millisecond
+	balancer.mutex.Unlock()
+
+	// busy is the fastest, but has a long queue: 20 * 1ms = 20ms.
+	balancer.servers["busy"].inflight.Store(19)
+
+	balancer.SetStrategySelector(func(req *http.Request) Strategy {
+		return StrategyPredictedCompletion
+	})
+
+	serve := func() string {
+		recorder := httptest.NewRecorder()
+		balancer.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+		return recorder.Header().Get("server")
+	}
+
+	// fast completes in 10ms, ahead of busy and slow.
+	assert.Equal(t, "fast", serve())
+
+	// With a queue, fast would complete in 30ms, after busy.
+	balancer.servers["fast"].inflight.Store(2)
+	assert.Equal(t, "busy", serve())
+
+	// The requests in flight are counted while they are served.
+	assert.Equal(t, int64(19), balancer.servers["busy"].inflight.Load())
+}
+
+func TestLBBalancerPredictedCompletionOverflow(t *testing.T) {
+	balancer := New(nil, false)
+
+	// Tokens are not refilled during the test.
+	for i, name := range []string{"slow", "fast"} {
+		balancer.Add(name, http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			rw.Header().Set("server", name)
+			rw.WriteHeader(http.StatusOK)
+		}), Int(1), Int(1), Int(3600000), Int(i+1))
+	}
+
+	balancer.mutex.Lock()
+	balancer.servers["slow"].latency = 100 * time.Millisecond
+	balancer.servers["fast"].latency = 10 * time.Millisecond
+	balancer.mutex.Unlock()
+
+	for _, expected := range []string{"fast", "slow"} {
+		h, err := balancer.selectServer(selection{strategy: StrategyPredictedCompletion})
+		if assert.NoError(t, err) {
+			assert.Equal(t, expected, h.name)
+		}
+	}
+
+	_, err := balancer.selectServer(selection{strategy: StrategyPredictedCompletion})
+	assert.ErrorIs(t, err, errNoAvailableServer)
+}
@@ -0,0 +1,97 @@
+package lblb
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+)
+
+var errOverloaded = errors.New("too many concurrent requests")
+
+// LoadShedding configures the shedding of the low priority requests first,
+// when the concurrency limit of the balancer is reached.
+type LoadShedding struct {
+	// Header is the request header holding the priority of a request, as an integer.
+	// As for servers, a lower value means a higher priority.
+	// A priority set on the request context with WithRequestPriority takes precedence over the header.
+	Header string
+	// CriticalPriority is the priority up to which (included) a request is critical.
+	// Requests without any priority are not critical.
+	CriticalPriority int
+	// Reserved is the number of concurrent requests reserved for the critical requests.
+	Reserved int
+}
+
+type requestPriorityKey struct{}
+
+// WithRequestPriority returns a copy of ctx holding the given request priority, used for load shedding.
+func WithRequestPriority(ctx context.Context, priority int) context.Context {
+	return context.WithValue(ctx, requestPriorityKey{}, priority)
+}
+
+// SetConcurrencyLimit sets the maximum number of requests dispatched concurrently by the balancer,
+// above which requests are rejected. A non-positive limit disables it.
+// When shedding is not nil, only the critical requests can use the last shedding.Reserved slots.
+func (b *LBBalancer) SetConcurrencyLimit(limit int, shedding *LoadShedding) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.concurrencyLimit = int64(max(limit, 0))
+	b.shedding = shedding
+}
+
+// acquire reserves a concurrency slot for req, and reports whether it succeeded,
+// and if not, whether it is because only the slots reserved for the critical requests are left.
+// A bypassing request always gets a slot, regardless of the limit.
+// A successful acquire must be followed by a release.
+func (b *LBBalancer) acquire(req *http.Request, bypass bool) (ok, shed bool) {
+	b.mutex.RLock()
+	limit := b.concurrencyLimit
+	shedding := b.shedding
+	b.mutex.RUnlock()
+
+	if limit == 0 || bypass {
+		b.inflight.Add(1)
+		return true, false
+	}
+
+	full := limit
+	if shedding != nil && !shedding.critical(req) {
+		limit -= int64(shedding.Reserved)
+	}
+
+	for {
+		inflight := b.inflight.Load()
+		if inflight >= limit {
+			return false, inflight < full
+		}
+
+		if b.inflight.CompareAndSwap(inflight, inflight+1) {
+			return true, false
+		}
+	}
+}
+
+// release releases a concurrency slot reserved by acquire.
+func (b *LBBalancer) release() {
+	b.inflight.Add(-1)
+}
+
+// critical reports whether req is a critical request.
+func (s *LoadShedding) critical(req *http.Request) bool {
+	if priority, ok := req.Context().Value(requestPriorityKey{}).(int); ok {
+		return priority <= s.CriticalPriority
+	}
+
+	if s.Header == "" {
+		return false
+	}
+
+	priority, err := strconv.Atoi(req.Header.Get(s.Header))
+	if err != nil {
+		return false
+	}
+
+	return priority <= s.CriticalPriority
+}
@@ -0,0 +1,105 @@
+package lblb
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLBBalancerLoadShedding(t *testing.T) {
+	balancer := New(nil, false)
+	balancer.SetConcurrencyLimit(2, &LoadShedding{Header: "X-Priority", CriticalPriority: 1, Reserved: 1})
+
+	started := make(chan struct{})
+	unblock := make(chan struct{})
+	balancer.Add("first", http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/block" {
+			started <- struct{}{}
+			<-unblock
+		}
+		rw.WriteHeader(http.StatusOK)
+	}), Int(100), Int(100), Int(1), Int(1))
+
+	var wg sync.WaitGroup
+	serve := func(path, priority string) {
+		defer wg.Done()
+
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if priority != "" {
+			req.Header.Set("X-Priority", priority)
+		}
+		balancer.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	// A low priority request takes the only non reserved slot.
+	wg.Add(1)
+	go serve("/block", "5")
+	<-started
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-Priority", "5")
+	recorder := httptest.NewRecorder()
+	balancer.ServeHTTP(recorder, req)
+	assert.Equal(t, http.StatusTooManyRequests, recorder.Code)
+
+	// Requests without priority are shed as well.
+	recorder = httptest.NewRecorder()
+	balancer.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusTooManyRequests, recorder.Code)
+
+	// A critical request uses the reserved slot.
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-Priority", "1")
+	recorder = httptest.NewRecorder()
+	balancer.ServeHTTP(recorder, req)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+
+	// Once the reserved slot is taken as well, even critical requests are rejected.
+	wg.Add(1)
+	go serve("/block", "0")
+	<-started
+
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req = req.WithContext(WithRequestPriority(req.Context(), 0))
+	recorder = httptest.NewRecorder()
+	balancer.ServeHTTP(recorder, req)
+	assert.Equal(t, http.StatusTooManyRequests, recorder.Code)
+
+	close(unblock)
+	wg.Wait()
+
+	assert.Equal(t, int64(0), balancer.inflight.Load())
+
+	recorder = httptest.NewRecorder()
+	balancer.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+}
+
+func TestLBBalancerConcurrencyLimitContextPriority(t *testing.T) {
+	balancer := New(nil, false)
+	balancer.SetConcurrencyLimit(1, &LoadShedding{Header: "X-Priority", CriticalPriority: 1, Reserved: 1})
+
+	balancer.Add("first", http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusOK)
+	}), Int(100), Int(100), Int(1), Int(1))
+
+	// The context priority takes precedence over the header.
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-Priority", "5")
+	req = req.WithContext(WithRequestPriority(req.Context(), 1))
+
+	recorder := httptest.NewRecorder()
+	balancer.ServeHTTP(recorder, req)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-Priority", "1")
+	req = req.WithContext(WithRequestPriority(req.Context(), 5))
+
+	recorder = httptest.NewRecorder()
+	balancer.ServeHTTP(recorder, req)
+	assert.Equal(t, http.StatusTooManyRequests, recorder.Code)
+}
@@ -0,0 +1,56 @@
+package lblb
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// SetRequestTimeout sets the time budget of the requests dispatched to the servers without a timeout of their own.
+// The request passed to the server carries the deadline of the budget, so that a server aware of its context
+// stops working on a request whose client gave up, and an earlier deadline of the incoming request is kept.
+// A non-positive timeout disables it, which is the default.
+func (b *LBBalancer) SetRequestTimeout(timeout time.Duration) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.requestTimeout = max(timeout, 0)
+}
+
+// SetServerRequestTimeout sets the time budget of the requests dispatched to the named server,
+// overriding the one set with SetRequestTimeout.
+// A non-positive timeout restores the timeout of the balancer.
+// It returns an error if no such server exists.
+func (b *LBBalancer) SetServerRequestTimeout(name string, timeout time.Duration) error {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	h, ok := b.servers[name]
+	if !ok {
+		return fmt.Errorf("unknown server %s", name)
+	}
+
+	h.requestTimeout.Store(int64(max(timeout, 0)))
+
+	return nil
+}
+
+// withDeadline returns req with the deadline of the time budget of server, if any,
+// along with the function releasing its resources.
+func (b *LBBalancer) withDeadline(req *http.Request, server *namedHandler) (*http.Request, context.CancelFunc) {
+	timeout := time.Duration(server.requestTimeout.Load())
+	if timeout <= 0 {
+		b.mutex.RLock()
+		timeout = b.requestTimeout
+		b.mutex.RUnlock()
+	}
+
+	if timeout <= 0 {
+		return req, func() {}
+	}
+
+	// The deadline of the parent context is kept if it is earlier.
+	ctx, cancel := context.WithTimeout(req.Context(), timeout)
+	return req.WithContext(ctx), cancel
+}
@@ -0,0 +1,62 @@
+package lblb
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLBBalancerRequestTimeout(t *testing.T) {
+	balancer := New(nil, false)
+
+	deadlines := make(chan time.Time, 1)
+	balancer.Add("first", http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		// The deadline is zero when there is none.
+		deadline, _ := req.Context().Deadline()
+		deadlines <- deadline
+	}), Int(100), Int(100), Int(1000), Int(1))
+
+	serve := func(ctx context.Context) time.Time {
+		t.Helper()
+
+		balancer.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx))
+		return <-deadlines
+	}
+
+	// No deadline without any timeout.
+	assert.Zero(t, serve(context.Background()))
+
+	// The incoming deadline is kept as is.
+	incoming := time.Now().Add(time.Hour)
+	ctx, cancel := context.WithDeadline(context.Background(), incoming)
+	defer cancel()
+	assert.Equal(t, incoming, serve(ctx))
+
+	balancer.SetRequestTimeout(time.Minute)
+
+	start := time.Now()
+	assert.WithinRange(t, serve(context.Background()), start.Add(time.Minute), time.Now().Add(time.Minute))
+
+	// The budget is earlier than the incoming deadline.
+	start = time.Now()
+	assert.WithinRange(t, serve(ctx), start.Add(time.Minute), time.Now().Add(time.Minute))
+
+	// The timeout of the server overrides the one of the balancer.
+	require.NoError(t, balancer.SetServerRequestTimeout("first", 2*time.Hour))
+	start = time.Now()
+	assert.WithinRange(t, serve(context.Background()), start.Add(2*time.Hour), time.Now().Add(2*time.Hour))
+
+	// The incoming deadline is earlier than the budget.
+	assert.Equal(t, incoming, serve(ctx))
+
+	require.NoError(t, balancer.SetServerRequestTimeout("first", 0))
+	start = time.Now()
+	assert.WithinRange(t, serve(context.Background()), start.Add(time.Minute), time.Now().Add(time.Minute))
+
+	assert.Error(t, balancer.SetServerRequestTimeout("unknown", time.Second))
+}
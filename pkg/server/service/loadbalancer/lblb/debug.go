@@ -0,0 +1,101 @@
+package lblb
+
+import "time"
+
+// HeapOrder returns the names of the servers in the order of the internal heap array,
+// i.e. the actual heap layout rather than a sorted list: the first server is the top of the heap.
+// It is meant for debugging priority issues.
+func (b *LBBalancer) HeapOrder() []string {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	names := make([]string, 0, len(b.handlers))
+	for _, h := range b.handlers {
+		names = append(names, h.name)
+	}
+
+	return names
+}
+
+// DebugState is a snapshot of the whole state of the balancer, e.g. to diagnose a stuck balancer.
+type DebugState struct {
+	Time time.Time `json:"time"`
+	Up   bool      `json:"up"`
+	// HeapOrder are the names of the servers in the order of the internal heap array, see HeapOrder.
+	HeapOrder []string `json:"heapOrder"`
+	// Servers are the states of the servers, in the order of HeapOrder.
+	Servers []DebugServer `json:"servers"`
+
+	Rejected         uint64 `json:"rejected"`
+	Bypassed         uint64 `json:"bypassed"`
+	Inflight         int64  `json:"inflight"`
+	ConcurrencyLimit int64  `json:"concurrencyLimit,omitempty"`
+}
+
+// DebugServer is a snapshot of the state of a server.
+type DebugServer struct {
+	Name     string        `json:"name"`
+	Priority int64         `json:"priority"`
+	Burst    int64         `json:"burst"`
+	Average  int64         `json:"average"`
+	Period   time.Duration `json:"period"`
+	// Tokens is the number of tokens available in the bucket.
+	Tokens   float64 `json:"tokens"`
+	Draining bool    `json:"draining,omitempty"`
+	// Boosted is whether the priority is temporarily boosted, see BoostPriority.
+	Boosted bool `json:"boosted,omitempty"`
+	// AvailableAt is the end of the cooldown of the server, zero if it is not cooling down.
+	AvailableAt     time.Time     `json:"availableAt,omitempty"`
+	Latency         time.Duration `json:"latency,omitempty"`
+	MaxResponseSize int64         `json:"maxResponseSize,omitempty"`
+
+	ServerStats
+}
+
+// DebugSnapshot returns a snapshot of the whole state of the balancer, captured while holding the mutex,
+// so that the servers, their buckets, status, and the heap order are consistent with each other.
+// The counters updated on the request path without the mutex may be slightly ahead.
+func (b *LBBalancer) DebugSnapshot() DebugState {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	now := b.clock.Now()
+
+	state := DebugState{
+		Time:             now,
+		Up:               len(b.status) > 0,
+		HeapOrder:        make([]string, 0, len(b.handlers)),
+		Servers:          make([]DebugServer, 0, len(b.handlers)),
+		Rejected:         b.rejected.Load(),
+		Bypassed:         b.bypassed.Load(),
+		Inflight:         b.inflight.Load(),
+		ConcurrencyLimit: b.concurrencyLimit,
+	}
+
+	for _, h := range b.handlers {
+		_, draining := b.draining[h.name]
+		_, boosted := b.boosts[h.name]
+
+		server := DebugServer{
+			Name:            h.name,
+			Priority:        h.priority,
+			Burst:           h.burst,
+			Average:         h.average,
+			Period:          h.period,
+			Tokens:          h.bucket.TokensAt(now),
+			Draining:        draining,
+			Boosted:         boosted,
+			Latency:         h.latency,
+			MaxResponseSize: h.maxResponseSize.Load(),
+			ServerStats:     b.handlerStats(h),
+		}
+		if b.coolingDown(h.name, now) {
+			server.AvailableAt = b.serverAvailability[h.name]
+		}
+
+		state.HeapOrder = append(state.HeapOrder, h.name)
+		state.Servers = append(state.Servers, server)
+	}
+
+	return state
+}
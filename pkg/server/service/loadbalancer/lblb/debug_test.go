@@ -0,0 +1,91 @@
+package lblb
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/traefik/traefik/v3/pkg/config/dynamic"
+)
+
+func TestLBBalancerHeapOrder(t *testing.T) {
+	balancer := New(nil, false)
+
+	handler := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {})
+
+	balancer.Add("a", handler, Int(1), Int(1), Int(1), Int(4))
+	balancer.Add("b", handler, Int(1), Int(1), Int(1), Int(3))
+	balancer.Add("c", handler, Int(1), Int(1), Int(1), Int(2))
+	balancer.Add("d", handler, Int(1), Int(1), Int(1), Int(1))
+
+	// Each push sifts the new server up: [a] -> [b a] -> [c a b] -> [d c b a].
+	assert.Equal(t, []string{"d", "c", "b", "a"}, balancer.HeapOrder())
+
+	// Lowering the priority of the top server sifts it down, swapping it with its smallest child at each level:
+	// [d c b a] -> [c d b a] -> [c a b d].
+	require.NoError(t, balancer.UpdateServer("d", dynamic.Server{Burst: Int(1), Average: Int(1), Period: Int(1), Priority: Int(5)}))
+	assert.Equal(t, []string{"c", "a", "b", "d"}, balancer.HeapOrder())
+
+	// Raising the priority of a server sifts it up: [c a b d] -> [a c b d].
+	require.NoError(t, balancer.UpdateServer("a", dynamic.Server{Burst: Int(1), Average: Int(1), Period: Int(1), Priority: Int(1)}))
+	assert.Equal(t, []string{"a", "c", "b", "d"}, balancer.HeapOrder())
+}
+
+func TestLBBalancerDebugSnapshot(t *testing.T) {
+	clock := newFakeClock()
+	balancer := New(nil, false)
+	balancer.clock = clock
+	balancer.SetConcurrencyLimit(10, nil)
+
+	for i, name := range []string{"first", "second", "third"} {
+		balancer.Add(name, http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			rw.WriteHeader(http.StatusOK)
+		}), Int(2), Int(1), Int(1000), Int(i+1))
+	}
+
+	balancer.SetStatus(context.Background(), "third", false)
+	balancer.SetDraining("second", true)
+	require.NoError(t, balancer.BoostPriority("third", 1, time.Minute))
+	defer balancer.Close()
+
+	for range 3 {
+		balancer.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	}
+
+	state := balancer.DebugSnapshot()
+	stats := balancer.Stats()
+
+	assert.Equal(t, clock.Now(), state.Time)
+	assert.True(t, state.Up)
+	assert.Equal(t, balancer.HeapOrder(), state.HeapOrder)
+	assert.Equal(t, uint64(1), state.Rejected)
+	assert.Equal(t, stats.Rejected, state.Rejected)
+	assert.Equal(t, int64(10), state.ConcurrencyLimit)
+	assert.Zero(t, state.Inflight)
+
+	require.Len(t, state.Servers, 3)
+	servers := make(map[string]DebugServer)
+	for i, server := range state.Servers {
+		assert.Equal(t, state.HeapOrder[i], server.Name)
+		assert.Equal(t, stats.Servers[server.Name], server.ServerStats)
+		servers[server.Name] = server
+	}
+
+	assert.Equal(t, DebugServer{
+		Name:        "first",
+		Priority:    1,
+		Burst:       2,
+		Average:     1,
+		Period:      time.Second,
+		ServerStats: ServerStats{Up: true, Served: 2, Throttled: 1, Responses: map[string]uint64{"2xx": 2}},
+	}, servers["first"])
+	assert.True(t, servers["second"].Draining)
+	assert.InDelta(t, 2, servers["second"].Tokens, 1e-9)
+	assert.True(t, servers["third"].Boosted)
+	assert.False(t, servers["third"].Up)
+	assert.Equal(t, int64(1), servers["third"].Priority)
+}
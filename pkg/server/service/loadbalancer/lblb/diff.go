@@ -0,0 +1,98 @@
+package lblb
+
+import (
+	"context"
+	"time"
+)
+
+// ServerParams are the rate and priority parameters of a server, as resolved from its configuration.
+type ServerParams struct {
+	Burst    int64         `json:"burst"`
+	Average  int64         `json:"average"`
+	Period   time.Duration `json:"period"`
+	Priority int64         `json:"priority"`
+}
+
+// ServerUpdate is the change of the parameters of a server.
+type ServerUpdate struct {
+	Old ServerParams `json:"old"`
+	New ServerParams `json:"new"`
+}
+
+// ConfigDiff is the change of the servers of the balancer made by a reload, by server name.
+// The servers kept with the same parameters are not listed.
+type ConfigDiff struct {
+	Added   map[string]ServerParams `json:"added,omitempty"`
+	Removed map[string]ServerParams `json:"removed,omitempty"`
+	Updated map[string]ServerUpdate `json:"updated,omitempty"`
+}
+
+// Empty reports whether the reload did not change any server.
+func (d ConfigDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Updated) == 0
+}
+
+// SetServersDiff is SetServers, also returning the change of the servers it made, e.g. for a change log.
+// The diff is empty when an error is returned, as the balancer is left untouched.
+func (b *LBBalancer) SetServersDiff(ctx context.Context, servers []Server) (ConfigDiff, error) {
+	var diff ConfigDiff
+	err := b.setServers(ctx, servers, &diff)
+
+	return diff, err
+}
+
+// params returns the parameters of the handler.
+func (h *namedHandler) params() ServerParams {
+	return ServerParams{Burst: h.burst, Average: h.average, Period: h.period, Priority: h.priority}
+}
+
+// paramsOf returns the parameters of the current server h,
+// with its configured priority rather than the one of its boost or schedule, see configuredPriority.
+// It must be called with the mutex held.
+func (b *LBBalancer) paramsOf(h *namedHandler) ServerParams {
+	params := h.params()
+	params.Priority = b.configuredPriority(h)
+
+	return params
+}
+
+// configDiff returns the change from the current servers to the given handlers.
+// It must be called with the mutex held, before the handlers are swapped in.
+func (b *LBBalancer) configDiff(handlers []*namedHandler) ConfigDiff {
+	var diff ConfigDiff
+
+	kept := make(map[string]struct{}, len(handlers))
+	for _, h := range handlers {
+		kept[h.name] = struct{}{}
+		params := h.params()
+
+		previous, ok := b.servers[h.name]
+		if !ok {
+			if diff.Added == nil {
+				diff.Added = make(map[string]ServerParams)
+			}
+			diff.Added[h.name] = params
+			continue
+		}
+
+		if old := b.paramsOf(previous); old != params {
+			if diff.Updated == nil {
+				diff.Updated = make(map[string]ServerUpdate)
+			}
+			diff.Updated[h.name] = ServerUpdate{Old: old, New: params}
+		}
+	}
+
+	for _, h := range b.handlers {
+		if _, ok := kept[h.name]; ok {
+			continue
+		}
+
+		if diff.Removed == nil {
+			diff.Removed = make(map[string]ServerParams)
+		}
+		diff.Removed[h.name] = b.paramsOf(h)
+	}
+
+	return diff
+}
@@ -0,0 +1,38 @@
+package lblb
+
+// SetZeroAverageDisabled sets whether a server added with an explicit zero average, by Add or SetServers,
+// is registered as disabled, instead of being ignored as with any non-positive average, which is the default:
+// a disabled server is part of the pool but never admits any request, e.g. to pre-register a backend
+// before enabling it with UpdateServer. It counts neither as up nor as down, its status being ignored,
+// so that it does not change the status of the balancer.
+// An unset average still defaults to 1, and a negative one is still ignored.
+// It only applies to the servers added afterward.
+func (b *LBBalancer) SetZeroAverageDisabled(enabled bool) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.zeroAverageDisabled = enabled
+}
+
+// resolveServerParams resolves the parameters of a server as newServerParams does,
+// but for the disabled servers, resolved with a zero average and burst when registered as such.
+func (b *LBBalancer) resolveServerParams(burst *int, average *int, period *int, priority *int) (serverParams, bool) {
+	params, ok := newServerParams(burst, average, period, priority)
+	if ok || average == nil || *average != 0 {
+		return params, ok
+	}
+
+	b.mutex.RLock()
+	disabled := b.zeroAverageDisabled
+	b.mutex.RUnlock()
+
+	if !disabled {
+		return params, false
+	}
+
+	one := 1
+	params, _ = newServerParams(&one, &one, period, priority)
+	params.burst, params.average, params.disabled = 0, 0, true
+
+	return params, true
+}
@@ -0,0 +1,152 @@
+package lblb
+
+import (
+	"net/http"
+
+	"github.com/rs/zerolog/log"
+)
+
+// SetDraining sets whether the named server is draining.
+// A draining server does not receive new clients anymore, but keeps serving the clients sticking to it.
+// It returns false if no such server exists.
+func (b *LBBalancer) SetDraining(name string, draining bool) bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if _, ok := b.servers[name]; !ok {
+		return false
+	}
+
+	if draining {
+		b.draining[name] = struct{}{}
+	} else {
+		delete(b.draining, name)
+	}
+
+	log.Debug().Msgf("Setting draining of %s to %t", name, draining)
+
+	b.audit(AuditSetDraining, map[string]any{"name": name, "draining": draining})
+
+	return true
+}
+
+// isDraining reports whether the server is draining.
+func (b *LBBalancer) isDraining(server *namedHandler) bool {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	_, draining := b.draining[server.name]
+	return draining
+}
+
+// SetDrainingCookieMaxAge sets the MaxAge, in seconds, of the sticky cookies written for a draining server.
+// A shorter MaxAge than the configured one lets idle clients migrate to another server sooner.
+// A non-positive maxAge keeps the configured MaxAge, which is the default.
+func (b *LBBalancer) SetDrainingCookieMaxAge(maxAge int) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.drainingCookieMaxAge = maxAge
+}
+
+// stickyTarget is the outcome of the lookup of the server a request sticks to.
+type stickyTarget struct {
+	// server is the server the request sticks to, if it is usable and admitted by its bucket.
+	server *namedHandler
+	// rewrite is whether the sticky cookie has to be written again.
+	rewrite bool
+	// excluded, when the server is only denied because the sticky traffic used up its share,
+	// holds its name, which must be excluded from the selection of another server.
+	excluded []string
+	// previous is the name of the server the request was sticking to, when it could not be used.
+	previous string
+	// removed is whether the previous server was removed from the balancer, rather than unusable.
+	removed bool
+	// reason is why the previous server could not be used, as a skip reason, empty if removed.
+	reason string
+}
+
+// stickyServer looks up the server the request sticks to.
+// A dry run does not consume any token, nor change any state, see Explain.
+func (b *LBBalancer) stickyServer(req *http.Request, dryRun bool) stickyTarget {
+	if b.sticky == nil {
+		return stickyTarget{}
+	}
+
+	h, rewrite, err := b.sticky.StickyHandler(req)
+	if err != nil {
+		log.Error().Err(err).Msg("Error while getting sticky handler")
+		return stickyTarget{}
+	}
+	if h == nil {
+		return stickyTarget{}
+	}
+
+	unusable := stickyTarget{previous: h.Name}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	server, ok := b.servers[h.Name]
+	if !ok {
+		unusable.removed = true
+		return unusable
+	}
+
+	if excludes(req, server.name) {
+		unusable.reason = skipExcluded
+		return unusable
+	}
+
+	if _, up := b.status[server.name]; !up || !server.dispatchable() {
+		unusable.reason = skipDown
+		return unusable
+	}
+
+	now := b.clock.Now()
+	sel := selection{dryRun: dryRun}
+	if !b.availableFor(server, sel, now) {
+		unusable.reason = skipEjected
+		return unusable
+	}
+
+	if server.stickyBucket != nil && !admits(server.stickyBucket, sel, now) {
+		unusable.excluded = []string{server.name}
+		unusable.reason = skipRateLimited
+		return unusable
+	}
+
+	if !b.admit(server, sel, now) {
+		// The sticky token is given back, as the request is not admitted.
+		if server.stickyBucket != nil && !dryRun {
+			server.stickyBucket.ReserveN(now, -1)
+		}
+		unusable.reason = skipRateLimited
+		return unusable
+	}
+
+	// The cookie of a draining server is rewritten with a shorter MaxAge.
+	if _, draining := b.draining[server.name]; draining && b.drainingCookieMaxAge > 0 {
+		rewrite = true
+	}
+
+	return stickyTarget{server: server, rewrite: rewrite}
+}
+
+// writeStickyCookie writes the sticky cookie pinning the client to the given server.
+func (b *LBBalancer) writeStickyCookie(rw http.ResponseWriter, server *namedHandler) {
+	b.mutex.RLock()
+	maxAge := b.drainingCookieMaxAge
+	b.mutex.RUnlock()
+
+	var err error
+	if maxAge > 0 && b.isDraining(server) {
+		err = b.sticky.WriteStickyCookieMaxAge(rw, server.name, maxAge)
+	} else {
+		err = b.sticky.WriteStickyCookie(rw, server.name)
+	}
+
+	if err != nil {
+		log.Error().Err(err).Msg("Error while writing sticky cookie")
+	}
+}
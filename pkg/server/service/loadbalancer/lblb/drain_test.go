@@ -0,0 +1,139 @@
+package lblb
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/traefik/traefik/v3/pkg/config/dynamic"
+)
+
+func TestLBBalancerDraining(t *testing.T) {
+	balancer := New(&dynamic.Sticky{Cookie: &dynamic.Cookie{Name: "test", MaxAge: 3600}}, false)
+	balancer.SetDrainingCookieMaxAge(60)
+
+	for i, name := range []string{"first", "second"} {
+		balancer.Add(name, http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			rw.Header().Set("server", name)
+			rw.WriteHeader(http.StatusOK)
+		}), Int(100), Int(100), Int(1), Int(i+1))
+	}
+
+	recorder := &responseRecorder{ResponseRecorder: httptest.NewRecorder(), save: map[string]int{}}
+	balancer.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+	require.Equal(t, []string{"first"}, recorder.sequence)
+
+	firstCookies := recorder.Result().Cookies()
+	require.Len(t, firstCookies, 1)
+	assert.Equal(t, 3600, firstCookies[0].MaxAge)
+
+	assert.True(t, balancer.SetDraining("first", true))
+	assert.False(t, balancer.SetDraining("unknown", true))
+
+	// The client sticking to the draining server keeps being served by it, with a shorter cookie TTL.
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(firstCookies[0])
+
+	recorder = &responseRecorder{ResponseRecorder: httptest.NewRecorder(), save: map[string]int{}}
+	balancer.ServeHTTP(recorder, req)
+	assert.Equal(t, []string{"first"}, recorder.sequence)
+
+	cookies := recorder.Result().Cookies()
+	require.Len(t, cookies, 1)
+	assert.Equal(t, firstCookies[0].Value, cookies[0].Value)
+	assert.Equal(t, 60, cookies[0].MaxAge)
+
+	// New clients go to the healthy server, with the configured TTL.
+	recorder = &responseRecorder{ResponseRecorder: httptest.NewRecorder(), save: map[string]int{}}
+	balancer.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, []string{"second"}, recorder.sequence)
+
+	secondCookies := recorder.Result().Cookies()
+	require.Len(t, secondCookies, 1)
+	assert.Equal(t, 3600, secondCookies[0].MaxAge)
+
+	// The client sticking to the healthy server does not get its cookie rewritten.
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(secondCookies[0])
+
+	recorder = &responseRecorder{ResponseRecorder: httptest.NewRecorder(), save: map[string]int{}}
+	balancer.ServeHTTP(recorder, req)
+	assert.Equal(t, []string{"second"}, recorder.sequence)
+	assert.Empty(t, recorder.Result().Cookies())
+
+	// Once drained, the server gets new clients again.
+	assert.True(t, balancer.SetDraining("first", false))
+
+	recorder = &responseRecorder{ResponseRecorder: httptest.NewRecorder(), save: map[string]int{}}
+	balancer.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, []string{"first"}, recorder.sequence)
+}
+
+func TestLBBalancerFencedServer(t *testing.T) {
+	balancer := New(nil, false)
+
+	balancer.AddServer("first", http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Header().Set("server", "first")
+		rw.WriteHeader(http.StatusOK)
+	}), dynamic.Server{Burst: Int(100), Average: Int(100), Period: Int(1), Priority: Int(1), Fenced: true})
+
+	balancer.AddServer("second", http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Header().Set("server", "second")
+		rw.WriteHeader(http.StatusOK)
+	}), dynamic.Server{Burst: Int(100), Average: Int(100), Period: Int(1), Priority: Int(2)})
+
+	recorder := &responseRecorder{ResponseRecorder: httptest.NewRecorder(), save: map[string]int{}}
+	for range 3 {
+		balancer.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+	}
+
+	assert.Equal(t, 3, recorder.save["second"])
+}
+
+func TestLBBalancerDrainingConnectionClose(t *testing.T) {
+	balancer := New(&dynamic.Sticky{Cookie: &dynamic.Cookie{Name: "test"}}, false)
+
+	for i, name := range []string{"first", "second"} {
+		balancer.Add(name, http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			rw.Header().Set("server", name)
+			rw.WriteHeader(http.StatusOK)
+		}), Int(100), Int(100), Int(1), Int(i+1))
+	}
+
+	server := httptest.NewServer(balancer)
+	t.Cleanup(server.Close)
+
+	resp, err := http.Get(server.URL)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+
+	assert.Equal(t, "first", resp.Header.Get("server"))
+	assert.False(t, resp.Close)
+
+	cookies := resp.Cookies()
+	balancer.SetDraining("first", true)
+
+	// The response from the draining server asks the client to close the connection.
+	req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+	require.NoError(t, err)
+	for _, cookie := range cookies {
+		req.AddCookie(cookie)
+	}
+
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+
+	assert.Equal(t, "first", resp.Header.Get("server"))
+	assert.True(t, resp.Close)
+
+	// The response from the other server keeps the connection alive.
+	resp, err = http.Get(server.URL)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+
+	assert.Equal(t, "second", resp.Header.Get("server"))
+	assert.False(t, resp.Close)
+}
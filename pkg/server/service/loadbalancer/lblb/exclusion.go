@@ -0,0 +1,30 @@
+package lblb
+
+import (
+	"context"
+	"net/http"
+	"slices"
+)
+
+type excludedServersKey struct{}
+
+// WithExcludedServers returns a copy of ctx holding the names of servers which must not be selected for a request,
+// e.g. for a retry layer above the balancer to avoid the servers it already tried.
+// The exclusions held by ctx, if any, are kept.
+// The excluded servers are skipped by every selection strategy, and a request sticking to one of them
+// is sent to another server, as if the sticky server was unavailable.
+// A request whose exclusions leave no server to select is rejected as when no server is available.
+func WithExcludedServers(ctx context.Context, names ...string) context.Context {
+	return context.WithValue(ctx, excludedServersKey{}, slices.Concat(excludedServers(ctx), names))
+}
+
+// excludedServers returns the names of the servers which must not be selected, held by ctx.
+func excludedServers(ctx context.Context) []string {
+	names, _ := ctx.Value(excludedServersKey{}).([]string)
+	return names
+}
+
+// excludes reports whether the named server must not be selected for req.
+func excludes(req *http.Request, name string) bool {
+	return slices.Contains(excludedServers(req.Context()), name)
+}
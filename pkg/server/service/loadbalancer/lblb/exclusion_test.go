@@ -0,0 +1,59 @@
+package lblb
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/traefik/traefik/v3/pkg/config/dynamic"
+)
+
+func TestLBBalancerExcludedServers(t *testing.T) {
+	balancer := New(&dynamic.Sticky{Cookie: &dynamic.Cookie{Name: "lblb"}}, false)
+
+	for i, name := range []string{"first", "second", "third"} {
+		balancer.Add(name, http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			rw.Header().Set("server", name)
+			rw.WriteHeader(http.StatusOK)
+		}), Int(100), Int(100), Int(1), Int(i+1))
+	}
+
+	serve := func(ctx context.Context, cookie *http.Cookie) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx)
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		recorder := httptest.NewRecorder()
+		balancer.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	assert.Equal(t, "first", serve(context.Background(), nil).Header().Get("server"))
+
+	// The selection falls through to the highest priority server which is not excluded.
+	ctx := WithExcludedServers(context.Background(), "first")
+	var recorder *httptest.ResponseRecorder
+	for range 5 {
+		recorder = serve(ctx, nil)
+		assert.Equal(t, "second", recorder.Header().Get("server"))
+	}
+
+	cookies := recorder.Result().Cookies()
+	require.Len(t, cookies, 1)
+	assert.Equal(t, "second", serve(context.Background(), cookies[0]).Header().Get("server"))
+
+	// A request sticking to an excluded server goes to another one.
+	assert.Equal(t, "first", serve(WithExcludedServers(context.Background(), "second"), cookies[0]).Header().Get("server"))
+
+	ctx = WithExcludedServers(ctx, "second")
+	assert.Equal(t, []string{"first", "second"}, excludedServers(ctx))
+	assert.Equal(t, "third", serve(ctx, cookies[0]).Header().Get("server"))
+
+	// No server is left once all of them are excluded.
+	recorder = serve(WithExcludedServers(ctx, "third"), nil)
+	assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+	assert.Empty(t, recorder.Header().Get("server"))
+}
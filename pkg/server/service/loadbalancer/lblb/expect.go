@@ -0,0 +1,23 @@
+package lblb
+
+import (
+	"net/http"
+	"strings"
+)
+
+// SetExpectContinueStatus sets the status of the rejections of the requests expecting a 100-continue,
+// e.g. 417 Expectation Failed, so that their clients tell them apart from the other rejections.
+// Whatever the status, a rejected request expecting a 100-continue gets its final status without its body being read,
+// so that the client does not upload a body which would be discarded, and its connection is closed.
+// A non-positive status keeps the regular rejection response, which is the default.
+func (b *LBBalancer) SetExpectContinueStatus(status int) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.expectContinueStatus = max(status, 0)
+}
+
+// expectsContinue reports whether the client of req waits for a 100-continue before sending the body.
+func expectsContinue(req *http.Request) bool {
+	return strings.EqualFold(req.Header.Get("Expect"), "100-continue")
+}
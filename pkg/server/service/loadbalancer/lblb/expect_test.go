@@ -0,0 +1,104 @@
+package lblb
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// readTracker is a request body recording whether it was read.
+type readTracker struct {
+	io.Reader
+	read bool
+}
+
+func (r *readTracker) Read(p []byte) (int, error) {
+	r.read = true
+	return r.Reader.Read(p)
+}
+
+func TestLBBalancerExpectContinue(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		status   int
+		expected int
+	}{
+		{
+			desc:     "regular rejection",
+			expected: http.StatusServiceUnavailable,
+		},
+		{
+			desc:     "configured status",
+			status:   http.StatusExpectationFailed,
+			expected: http.StatusExpectationFailed,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			balancer := New(nil, false)
+			balancer.SetExpectContinueStatus(test.status)
+
+			balancer.Add("first", http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+				_, _ = io.Copy(io.Discard, req.Body)
+				rw.WriteHeader(http.StatusOK)
+			}), Int(1), Int(1), Int(1000), Int(1))
+
+			serve := func() (*httptest.ResponseRecorder, *readTracker) {
+				body := &readTracker{Reader: strings.NewReader("upload")}
+				req := httptest.NewRequest(http.MethodPost, "/", body)
+				req.Header.Set("Expect", "100-continue")
+
+				recorder := httptest.NewRecorder()
+				balancer.ServeHTTP(recorder, req)
+				return recorder, body
+			}
+
+			// The admitted request gets its body read by the server.
+			recorder, body := serve()
+			assert.Equal(t, http.StatusOK, recorder.Code)
+			assert.True(t, body.read)
+			assert.Empty(t, recorder.Header().Get("Connection"))
+
+			// The rejected request gets its final status, without its body being read.
+			recorder, body = serve()
+			assert.Equal(t, test.expected, recorder.Code)
+			assert.False(t, body.read)
+			assert.Equal(t, "close", recorder.Header().Get("Connection"))
+		})
+	}
+}
+
+func TestLBBalancerExpectContinueServer(t *testing.T) {
+	balancer := New(nil, false)
+	balancer.SetExpectContinueStatus(http.StatusExpectationFailed)
+
+	balancer.Add("first", http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		_, _ = io.Copy(io.Discard, req.Body)
+		rw.WriteHeader(http.StatusOK)
+	}), Int(1), Int(1), Int(3600000), Int(1))
+
+	server := httptest.NewServer(balancer)
+	defer server.Close()
+
+	post := func() int {
+		req, err := http.NewRequest(http.MethodPost, server.URL, strings.NewReader("upload"))
+		assert.NoError(t, err)
+		req.Header.Set("Expect", "100-continue")
+
+		resp, err := server.Client().Do(req)
+		assert.NoError(t, err)
+		defer resp.Body.Close()
+
+		return resp.StatusCode
+	}
+
+	assert.Equal(t, http.StatusOK, post())
+	assert.Equal(t, http.StatusExpectationFailed, post())
+}
@@ -0,0 +1,172 @@
+package lblb
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"slices"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// Explanation is the explanation of the selection of the server of a request, see Explain.
+type Explanation struct {
+	Time     time.Time `json:"time"`
+	Strategy Strategy  `json:"strategy"`
+	// Bypass is whether the request carries the bypass token, see SetBypassToken.
+	Bypass bool `json:"bypass,omitempty"`
+	// Sticky is the evaluation of the server the request sticks to, nil if it does not stick to any.
+	Sticky *StickyExplanation `json:"sticky,omitempty"`
+	// Candidates are the servers the selection would consider, in the order they would be evaluated,
+	// empty if the request sticks to a usable server.
+	Candidates []ExplainedCandidate `json:"candidates,omitempty"`
+	// Selected is the server which would be selected, empty if none.
+	Selected string `json:"selected,omitempty"`
+	// Error is why no server would be selected.
+	Error string `json:"error,omitempty"`
+}
+
+// StickyExplanation is the evaluation of the server a request sticks to.
+type StickyExplanation struct {
+	Server string `json:"server"`
+	// Usable is whether the request would be sent to the server.
+	Usable bool `json:"usable"`
+	// Removed is whether the server is not part of the balancer anymore.
+	Removed bool `json:"removed,omitempty"`
+	// Reason is why the server would not be used, as a skip reason of the candidates.
+	Reason string `json:"reason,omitempty"`
+}
+
+// ExplainedCandidate is a server considered by an explained selection.
+type ExplainedCandidate struct {
+	Name     string  `json:"name"`
+	Priority int64   `json:"priority"`
+	Up       bool    `json:"up"`
+	Draining bool    `json:"draining,omitempty"`
+	Tokens   float64 `json:"tokens"`
+	// Skipped is why the candidate would not be selected, empty if it would be.
+	Skipped string `json:"skipped,omitempty"`
+}
+
+// Explain explains how the server of req would be selected in the current state of the balancer,
+// to answer "why did this request go there?": the evaluation of its sticky server, the candidates,
+// with their state and eligibility, in the order they would be evaluated, and the server which would be selected.
+// It is a dry run: the request is not served, no token is consumed, and no state is changed,
+// not even recorded by the decision tracer or the counters.
+// Only the selection is explained: the caps of the balancer as a whole, e.g. its global rate limit,
+// the response cache, the warm pool, and the fallbacks of the requests without any server, e.g. per the
+// all draining policy, are not.
+func (b *LBBalancer) Explain(req *http.Request) Explanation {
+	b.mutex.RLock()
+	token := b.bypass
+	b.mutex.RUnlock()
+
+	explanation := Explanation{
+		Strategy: b.strategy(req),
+		Bypass:   token != nil && subtle.ConstantTimeCompare([]byte(req.Header.Get(token.header)), token.secret) == 1,
+	}
+
+	target := b.stickyServer(req, true)
+	if target.previous != "" || target.server != nil {
+		explanation.Sticky = &StickyExplanation{Server: target.previous, Removed: target.removed, Reason: target.reason}
+		if target.server != nil {
+			explanation.Sticky.Server = target.server.name
+			explanation.Sticky.Usable = true
+		}
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	now := b.clock.Now()
+	explanation.Time = now
+
+	if target.server != nil {
+		explanation.Selected = target.server.name
+		return explanation
+	}
+
+	if len(b.handlers) == 0 || len(b.status) == 0 {
+		explanation.Error = errNoAvailableServer.Error()
+		return explanation
+	}
+
+	sel := selection{
+		excluded: slices.Concat(target.excluded, excludedServers(req.Context())),
+		bypass:   explanation.Bypass,
+		strategy: explanation.Strategy,
+		dryRun:   true,
+	}
+
+	decision := &Decision{Time: now}
+	handler, err := b.pick(decision, sel)
+	if err != nil {
+		explanation.Error = err.Error()
+	} else {
+		explanation.Selected = handler.name
+	}
+
+	for _, c := range decision.Candidates {
+		candidate := ExplainedCandidate{Name: c.Name, Priority: c.Priority, Skipped: c.Skipped}
+		if h, ok := b.servers[c.Name]; ok {
+			_, candidate.Up = b.status[h.name]
+			_, candidate.Draining = b.draining[h.name]
+			candidate.Tokens = h.bucket.TokensAt(now)
+		}
+		explanation.Candidates = append(explanation.Candidates, candidate)
+	}
+
+	return explanation
+}
+
+// admit reports whether the bucket of h admits a request for sel at now,
+// consuming a token, and counting the decision, unless sel is a dry run.
+// It must be called with the mutex held.
+func (b *LBBalancer) admit(h *namedHandler, sel selection, now time.Time) bool {
+	if sel.dryRun {
+		return admits(h.bucket, sel, now)
+	}
+
+	return h.allow(now)
+}
+
+// admitCandidate is admit for a candidate of a selection,
+// recording whether its bucket admitted the request in its canAllow, unless sel is a dry run.
+// It must be called with the mutex held.
+func (b *LBBalancer) admitCandidate(h *namedHandler, sel selection, now time.Time) bool {
+	allowed := b.admit(h, sel, now)
+	if !sel.dryRun {
+		h.canAllow = allowed
+	}
+
+	return allowed
+}
+
+// admits reports whether bucket admits a request for sel at now, consuming a token unless sel is a dry run.
+func admits(bucket *rate.Limiter, sel selection, now time.Time) bool {
+	if sel.dryRun {
+		return bucket.TokensAt(now) >= 1
+	}
+
+	return bucket.AllowN(now, 1)
+}
+
+// availableFor reports whether h is available for sel at now, see available,
+// without ending its cooldown, nor probing it, if sel is a dry run.
+// It must be called with the mutex held.
+func (b *LBBalancer) availableFor(h *namedHandler, sel selection, now time.Time) bool {
+	if !sel.dryRun {
+		return b.available(h, now)
+	}
+
+	if until, ok := b.serverAvailability[h.name]; ok {
+		return !now.Before(until)
+	}
+
+	if !h.outlier.halfOpen {
+		return true
+	}
+
+	config := b.outlierDetection
+	return config == nil || !config.HalfOpen || now.Sub(h.outlier.probeAt) >= config.EjectionTime
+}
@@ -0,0 +1,134 @@
+package lblb
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/traefik/traefik/v3/pkg/config/dynamic"
+)
+
+func TestLBBalancerExplain(t *testing.T) {
+	clock := newFakeClock()
+	balancer := New(nil, false)
+	balancer.clock = clock
+
+	for i, name := range []string{"first", "second", "third"} {
+		balancer.Add(name, http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			rw.Header().Set("server", name)
+			rw.WriteHeader(http.StatusOK)
+		}), Int(2), Int(1), Int(1000), Int(i+1))
+	}
+	balancer.SetDraining("third", true)
+
+	tokens := func() map[string]float64 {
+		balancer.mutex.RLock()
+		defer balancer.mutex.RUnlock()
+
+		tokens := make(map[string]float64)
+		for _, h := range balancer.handlers {
+			tokens[h.name] = h.bucket.TokensAt(clock.Now())
+		}
+		return tokens
+	}
+
+	// The explained selection is the actual one, until no server is left.
+	for range 5 {
+		before := tokens()
+		var explanation Explanation
+		for range 3 {
+			explanation = balancer.Explain(httptest.NewRequest(http.MethodGet, "/", nil))
+		}
+		assert.Equal(t, before, tokens())
+
+		assert.Equal(t, explanation.Selected, serveOne(balancer))
+	}
+
+	explanation := balancer.Explain(httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, Explanation{
+		Time:     clock.Now(),
+		Strategy: StrategyPriority,
+		Candidates: []ExplainedCandidate{
+			{Name: "first", Priority: 1, Up: true, Skipped: skipRateLimited},
+			{Name: "second", Priority: 2, Up: true, Skipped: skipRateLimited},
+			{Name: "third", Priority: 3, Up: true, Draining: true, Tokens: 2, Skipped: skipDraining},
+		},
+		Error: errNoAvailableServer.Error(),
+	}, explanation)
+
+	// The explanations are neither traced nor counted.
+	stats := balancer.Stats()
+	assert.Equal(t, uint64(1), stats.Rejected)
+	assert.Equal(t, uint64(3), stats.Servers["first"].Throttled)
+}
+
+func TestLBBalancerExplainSticky(t *testing.T) {
+	clock := newFakeClock()
+	balancer := New(&dynamic.Sticky{Cookie: &dynamic.Cookie{Name: "test"}}, false)
+	balancer.clock = clock
+
+	for i, name := range []string{"first", "second"} {
+		balancer.Add(name, http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			rw.Header().Set("server", name)
+			rw.WriteHeader(http.StatusOK)
+		}), Int(1), Int(1), Int(1000), Int(i+1))
+	}
+
+	recorder := httptest.NewRecorder()
+	balancer.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+	require.Equal(t, "first", recorder.Header().Get("server"))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	for _, cookie := range recorder.Result().Cookies() {
+		req.AddCookie(cookie)
+	}
+
+	// The bucket of first is empty, so that the request would go to second.
+	explanation := balancer.Explain(req)
+	assert.Equal(t, &StickyExplanation{Server: "first", Reason: skipRateLimited}, explanation.Sticky)
+	assert.Equal(t, "second", explanation.Selected)
+
+	clock.Advance(time.Second)
+	explanation = balancer.Explain(req)
+	assert.Equal(t, &StickyExplanation{Server: "first", Usable: true}, explanation.Sticky)
+	assert.Equal(t, "first", explanation.Selected)
+	assert.Empty(t, explanation.Candidates)
+
+	recorder = httptest.NewRecorder()
+	balancer.ServeHTTP(recorder, req)
+	assert.Equal(t, "first", recorder.Header().Get("server"))
+}
+
+func TestLBBalancerExplainDryRun(t *testing.T) {
+	clock := newFakeClock()
+	balancer := New(nil, false)
+	balancer.clock = clock
+
+	for i, name := range []string{"first", "second", "third"} {
+		balancer.Add(name, http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			rw.Header().Set("server", name)
+			rw.WriteHeader(http.StatusOK)
+		}), Int(1), Int(1), Int(1000), Int(i+1))
+	}
+
+	// The bucket of first is denied the second request.
+	assert.Equal(t, "first", serveOne(balancer))
+	assert.Equal(t, "second", serveOne(balancer))
+	first := balancer.servers["first"]
+	require.False(t, first.canAllow)
+
+	clock.Advance(time.Second)
+	order := slices.Clone(balancer.handlers)
+
+	explanation := balancer.Explain(httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, "first", explanation.Selected)
+
+	// Neither the order of the servers, nor their admission state, are changed by the dry run.
+	assert.Equal(t, order, balancer.handlers)
+	assert.False(t, first.canAllow)
+	assert.Equal(t, explanation.Selected, serveOne(balancer))
+}
@@ -0,0 +1,161 @@
+package lblb
+
+import (
+	"expvar"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// selectionLatency is a summary of the durations of the selections.
+type selectionLatency struct {
+	count atomic.Uint64
+	total atomic.Int64
+	max   atomic.Int64
+}
+
+// record adds a selection which took d to the summary.
+func (l *selectionLatency) record(d time.Duration) {
+	l.count.Add(1)
+	l.total.Add(int64(d))
+
+	for {
+		current := l.max.Load()
+		if int64(d) <= current || l.max.CompareAndSwap(current, int64(d)) {
+			return
+		}
+	}
+}
+
+// SetServerTag sets the tag of the named server, e.g. its zone,
+// which its published metrics are aggregated by when enabled with SetMetricsTagAggregation.
+// An empty tag removes the tag of the server.
+// It returns an error if no such server exists.
+func (b *LBBalancer) SetServerTag(name, tag string) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	h, ok := b.servers[name]
+	if !ok {
+		return fmt.Errorf("unknown server %s", name)
+	}
+
+	h.tag = tag
+
+	return nil
+}
+
+// SetMetricsTagAggregation sets whether the published metrics of the tagged servers are aggregated by tag,
+// rather than published per server, which bounds the cardinality of the metrics of large pools.
+// The aggregated metrics are published under "tags", by tag, with the number of servers and of up servers,
+// and the total served and throttled requests, while the untagged servers are still published under "servers".
+// Stats is not affected, and stays per server.
+// The aggregation is disabled by default.
+func (b *LBBalancer) SetMetricsTagAggregation(enabled bool) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.aggregateTags = enabled
+}
+
+// PublishExpvar publishes the key counters of the balancer as expvar variables, under the given namespace:
+// a map holding the number of up servers, the rejected requests, a summary of the selection durations,
+// and the served and throttled requests of each server, or of each tag, see SetMetricsTagAggregation,
+// all read when the variables are scraped.
+// Nothing is published unless PublishExpvar is called.
+// As the expvar variables cannot be removed, a namespace can only be published once per process,
+// and PublishExpvar returns an error if it is already used.
+func (b *LBBalancer) PublishExpvar(namespace string) error {
+	if namespace == "" {
+		return fmt.Errorf("empty expvar namespace")
+	}
+	if expvar.Get(namespace) != nil {
+		return fmt.Errorf("expvar namespace %s already published", namespace)
+	}
+
+	vars := new(expvar.Map).Init()
+	vars.Set("up", expvar.Func(func() any {
+		b.mutex.RLock()
+		defer b.mutex.RUnlock()
+
+		return len(b.status)
+	}))
+	vars.Set("rejected", expvar.Func(func() any {
+		return b.rejected.Load()
+	}))
+	vars.Set("selection", expvar.Func(func() any {
+		count := b.selectionLatency.count.Load()
+
+		var mean time.Duration
+		if count > 0 {
+			mean = time.Duration(b.selectionLatency.total.Load() / int64(count))
+		}
+
+		return map[string]any{
+			"count":  count,
+			"meanNs": mean.Nanoseconds(),
+			"maxNs":  b.selectionLatency.max.Load(),
+		}
+	}))
+	vars.Set("servers", expvar.Func(func() any {
+		b.mutex.RLock()
+		defer b.mutex.RUnlock()
+
+		servers := make(map[string]any, len(b.handlers))
+		for _, h := range b.handlers {
+			if b.aggregateTags && h.tag != "" {
+				continue
+			}
+
+			_, up := b.status[h.name]
+			servers[h.name] = map[string]any{
+				"up":        up,
+				"served":    h.served.Load(),
+				"throttled": h.throttled.Load(),
+			}
+		}
+
+		return servers
+	}))
+	vars.Set("tags", expvar.Func(func() any {
+		b.mutex.RLock()
+		defer b.mutex.RUnlock()
+
+		type tagMetrics struct {
+			Servers   int    `json:"servers"`
+			Up        int    `json:"up"`
+			Served    uint64 `json:"served"`
+			Throttled uint64 `json:"throttled"`
+		}
+
+		tags := make(map[string]*tagMetrics)
+		if !b.aggregateTags {
+			return tags
+		}
+
+		for _, h := range b.handlers {
+			if h.tag == "" {
+				continue
+			}
+
+			m, ok := tags[h.tag]
+			if !ok {
+				m = &tagMetrics{}
+				tags[h.tag] = m
+			}
+
+			m.Servers++
+			if _, up := b.status[h.name]; up {
+				m.Up++
+			}
+			m.Served += h.served.Load()
+			m.Throttled += h.throttled.Load()
+		}
+
+		return tags
+	}))
+
+	expvar.Publish(namespace, vars)
+
+	return nil
+}
@@ -0,0 +1,107 @@
+package lblb
+
+import (
+	"context"
+	"encoding/json"
+	"expvar"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLBBalancerPublishExpvar(t *testing.T) {
+	balancer := New(nil, false)
+
+	// Tokens are not refilled during the test.
+	for i, name := range []string{"first", "second"} {
+		balancer.Add(name, http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			rw.WriteHeader(http.StatusOK)
+		}), Int(2), Int(1), Int(3600000), Int(i+1))
+	}
+
+	assert.Nil(t, expvar.Get("lblb_test"))
+	require.NoError(t, balancer.PublishExpvar("lblb_test"))
+	assert.Error(t, balancer.PublishExpvar("lblb_test"))
+	assert.Error(t, balancer.PublishExpvar(""))
+
+	for range 5 {
+		balancer.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	}
+
+	var vars struct {
+		Up        int    `json:"up"`
+		Rejected  uint64 `json:"rejected"`
+		Selection struct {
+			Count uint64 `json:"count"`
+			MaxNs int64  `json:"maxNs"`
+		} `json:"selection"`
+		Servers map[string]struct {
+			Up        bool   `json:"up"`
+			Served    uint64 `json:"served"`
+			Throttled uint64 `json:"throttled"`
+		} `json:"servers"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(expvar.Get("lblb_test").String()), &vars))
+
+	assert.Equal(t, 2, vars.Up)
+	assert.Equal(t, uint64(1), vars.Rejected)
+	assert.Equal(t, uint64(5), vars.Selection.Count)
+	assert.Positive(t, vars.Selection.MaxNs)
+	assert.Equal(t, uint64(2), vars.Servers["first"].Served)
+	assert.Equal(t, uint64(3), vars.Servers["first"].Throttled)
+	assert.Equal(t, uint64(2), vars.Servers["second"].Served)
+	assert.Equal(t, uint64(1), vars.Servers["second"].Throttled)
+	assert.True(t, vars.Servers["second"].Up)
+}
+
+func TestLBBalancerPublishExpvarTagAggregation(t *testing.T) {
+	balancer := New(nil, false)
+
+	// Tokens are not refilled during the test.
+	for i, name := range []string{"first", "second", "third"} {
+		balancer.Add(name, http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			rw.WriteHeader(http.StatusOK)
+		}), Int(1), Int(1), Int(3600000), Int(i+1))
+	}
+
+	require.NoError(t, balancer.SetServerTag("first", "zone-a"))
+	require.NoError(t, balancer.SetServerTag("second", "zone-a"))
+	assert.Error(t, balancer.SetServerTag("unknown", "zone-a"))
+	balancer.SetMetricsTagAggregation(true)
+	balancer.SetStatus(context.Background(), "second", false)
+
+	require.NoError(t, balancer.PublishExpvar("lblb_test_tags"))
+
+	for range 3 {
+		balancer.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	}
+
+	var vars struct {
+		Servers map[string]struct {
+			Served uint64 `json:"served"`
+		} `json:"servers"`
+		Tags map[string]struct {
+			Servers   int    `json:"servers"`
+			Up        int    `json:"up"`
+			Served    uint64 `json:"served"`
+			Throttled uint64 `json:"throttled"`
+		} `json:"tags"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(expvar.Get("lblb_test_tags").String()), &vars))
+
+	// The tagged servers are only published aggregated, the untagged one still per server.
+	assert.Len(t, vars.Servers, 1)
+	assert.Equal(t, uint64(1), vars.Servers["third"].Served)
+	require.Len(t, vars.Tags, 1)
+	assert.Equal(t, 2, vars.Tags["zone-a"].Servers)
+	assert.Equal(t, 1, vars.Tags["zone-a"].Up)
+	assert.Equal(t, uint64(1), vars.Tags["zone-a"].Served)
+	assert.Equal(t, uint64(2), vars.Tags["zone-a"].Throttled)
+
+	// Stats stays per server.
+	stats := balancer.Stats()
+	assert.Len(t, stats.Servers, 3)
+}
@@ -0,0 +1,43 @@
+package lblb
+
+import (
+	"math"
+
+	"golang.org/x/time/rate"
+)
+
+// SetNonStickyReservation reserves the given fraction, between 0 and 1, of the capacity of each server
+// for the non-sticky traffic, so that the clients sticking to a server cannot use all of its tokens.
+// A sticky request denied because the sticky traffic used up its share is balanced to another server.
+// A zero fraction disables the reservation, which is the default.
+func (b *LBBalancer) SetNonStickyReservation(fraction float64) {
+	fraction = math.Min(math.Max(fraction, 0), 1)
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.nonStickyReservation = fraction
+
+	for _, h := range b.handlers {
+		if fraction == 0 {
+			h.stickyBucket = nil
+			continue
+		}
+
+		h.stickyBucket = newStickyBucket(h.bucket.Limit(), h.bucket.Burst(), fraction)
+	}
+}
+
+// newStickyBucket returns the bucket limiting the sticky traffic of a server
+// to its share of the capacity given by limit and burst.
+func newStickyBucket(limit rate.Limit, burst int, reservation float64) *rate.Limiter {
+	return rate.NewLimiter(stickyLimit(limit, reservation), stickyBurst(burst, reservation))
+}
+
+func stickyLimit(limit rate.Limit, reservation float64) rate.Limit {
+	return limit * rate.Limit(1-reservation)
+}
+
+func stickyBurst(burst int, reservation float64) int {
+	return int(math.Floor(float64(burst) * (1 - reservation)))
+}
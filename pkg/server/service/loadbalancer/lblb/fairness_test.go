@@ -0,0 +1,128 @@
+package lblb
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/traefik/traefik/v3/pkg/config/dynamic"
+)
+
+func TestLBBalancerNonStickyReservation(t *testing.T) {
+	clock := newFakeClock()
+
+	balancer := New(&dynamic.Sticky{Cookie: &dynamic.Cookie{Name: "test"}}, false)
+	balancer.clock = clock
+	balancer.SetNonStickyReservation(0.4)
+
+	// 10 tokens per second, with a burst of 10.
+	balancer.Add("first", http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusOK)
+	}), Int(10), Int(10), Int(1000), Int(1))
+
+	recorder := httptest.NewRecorder()
+	balancer.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+	cookies := recorder.Result().Cookies()
+	require.Len(t, cookies, 1)
+
+	serve := func(sticky bool) bool {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if sticky {
+			req.AddCookie(cookies[0])
+		}
+
+		recorder := httptest.NewRecorder()
+		balancer.ServeHTTP(recorder, req)
+		return recorder.Code == http.StatusOK
+	}
+
+	var stickyServed, nonStickyServed int
+	for range 20 {
+		// Sticky clients always come first, and in large numbers.
+		for range 10 {
+			if serve(true) {
+				stickyServed++
+			}
+		}
+		for range 10 {
+			if serve(false) {
+				nonStickyServed++
+			}
+		}
+
+		clock.Advance(100 * time.Millisecond)
+	}
+
+	// 9 tokens left in the initial burst, plus 19 refilled tokens during the test.
+	assert.Equal(t, 28, stickyServed+nonStickyServed)
+	// The sticky traffic used at most 60% of the capacity: its burst of 6 tokens, and 60% of the refill (11.4).
+	assert.LessOrEqual(t, stickyServed, 17)
+	assert.GreaterOrEqual(t, nonStickyServed, 11)
+}
+
+func TestLBBalancerNonStickyReservationOverflow(t *testing.T) {
+	balancer := New(&dynamic.Sticky{Cookie: &dynamic.Cookie{Name: "test"}}, false)
+	balancer.SetNonStickyReservation(0.5)
+
+	for i, name := range []string{"first", "second"} {
+		balancer.Add(name, http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			rw.Header().Set("server", name)
+			rw.WriteHeader(http.StatusOK)
+		}), Int(4), Int(1), Int(1000), Int(i+1))
+	}
+
+	recorder := httptest.NewRecorder()
+	balancer.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+	cookies := recorder.Result().Cookies()
+	require.Len(t, cookies, 1)
+
+	rec := &responseRecorder{ResponseRecorder: httptest.NewRecorder(), save: map[string]int{}}
+	for range 3 {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.AddCookie(cookies[0])
+		balancer.ServeHTTP(rec, req)
+	}
+
+	// Once its share is used up, the sticky client is balanced to the other server,
+	// and the tokens of the first server are kept for the non-sticky traffic.
+	assert.Equal(t, []string{"first", "first", "second"}, rec.sequence)
+	assert.Equal(t, "first", serveOne(balancer))
+}
+
+func TestLBBalancerNonStickyReservationRefund(t *testing.T) {
+	clock := newFakeClock()
+
+	balancer := New(&dynamic.Sticky{Cookie: &dynamic.Cookie{Name: "test"}}, false)
+	balancer.clock = clock
+	balancer.SetNonStickyReservation(0.5)
+
+	// Tokens are not refilled during the test.
+	for i, name := range []string{"first", "second"} {
+		balancer.Add(name, http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			rw.Header().Set("server", name)
+			rw.WriteHeader(http.StatusOK)
+		}), Int(4), Int(1), Int(3600000), Int(i+1))
+	}
+
+	recorder := httptest.NewRecorder()
+	balancer.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+	cookies := recorder.Result().Cookies()
+	require.Len(t, cookies, 1)
+
+	// The non-sticky traffic uses up the bucket of the first server, but not its sticky share.
+	for range 3 {
+		assert.Equal(t, "first", serveOne(balancer))
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(cookies[0])
+	recorder = httptest.NewRecorder()
+	balancer.ServeHTTP(recorder, req)
+	assert.Equal(t, "second", recorder.Header().Get("server"))
+
+	// The sticky token taken before the bucket of the server refused the request is given back.
+	assert.InDelta(t, 2, balancer.servers["first"].stickyBucket.TokensAt(clock.Now()), 0.01)
+}
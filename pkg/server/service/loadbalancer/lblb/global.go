@@ -0,0 +1,47 @@
+package lblb
+
+import (
+	"errors"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+var errGlobalRateLimited = errors.New("global rate limit exceeded")
+
+// SetGlobalRateLimit sets a rate limit of the balancer as a whole, of average requests per period,
+// with the given burst, on top of the buckets of the servers, e.g. to protect a shared dependency of the servers.
+// A request denied by it is rejected as by a cap of the balancer, see SetCapRejection,
+// and counted with the RejectGlobalRate reason, while a request which could not be dispatched to any server
+// gets its global token back. The bypassing requests are not limited.
+// When the RateLimit headers are enabled, they describe the most restrictive of the global and server limits.
+// A non-positive average or period disables the limit, which is the default, and a non-positive burst is set to 1.
+func (b *LBBalancer) SetGlobalRateLimit(average int, period time.Duration, burst int) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if average <= 0 || period <= 0 {
+		b.globalBucket = nil
+		return
+	}
+
+	b.globalBucket = rate.NewLimiter(rate.Every(period/time.Duration(average)), max(burst, 1))
+}
+
+// allowGlobal reports whether the global rate limit admits a request, consuming a token if so.
+func (b *LBBalancer) allowGlobal() bool {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	return b.globalBucket == nil || b.globalBucket.AllowN(b.clock.Now(), 1)
+}
+
+// refundGlobal gives back the global token of a request which could not be dispatched to any server.
+func (b *LBBalancer) refundGlobal() {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	if b.globalBucket != nil {
+		b.globalBucket.ReserveN(b.clock.Now(), -1)
+	}
+}
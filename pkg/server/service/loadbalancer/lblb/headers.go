@@ -0,0 +1,63 @@
+package lblb
+
+import (
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+)
+
+// SetServerHeaders sets the request headers set on the requests dispatched to the named server,
+// e.g. to tell it its zone or version for its own routing or logging, replacing the previous ones.
+// The headers are set right before the dispatch, and override the headers of the same name sent by the client,
+// so that a client cannot impersonate them. An empty headers map removes them.
+// They are also set from dynamic.Server.Headers by AddServer and SetServers.
+// It returns an error if no such server exists.
+func (b *LBBalancer) SetServerHeaders(name string, headers map[string]string) error {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	h, ok := b.servers[name]
+	if !ok {
+		return fmt.Errorf("unknown server %s", name)
+	}
+
+	h.setHeaders(headers)
+
+	return nil
+}
+
+// setHeaders sets the request headers of the handler.
+func (h *namedHandler) setHeaders(headers map[string]string) {
+	if len(headers) == 0 {
+		h.headers.Store(nil)
+		return
+	}
+
+	header := make(http.Header, len(headers))
+	for name, value := range headers {
+		header.Set(name, value)
+	}
+
+	h.headers.Store(&header)
+}
+
+// injectHeaders returns req with the request headers of the handler set, overriding the ones of the same name.
+// The headers are set on a shallow copy of req, so that neither req, nor the headers of the handler are modified.
+func (h *namedHandler) injectHeaders(req *http.Request) *http.Request {
+	header := h.headers.Load()
+	if header == nil {
+		return req
+	}
+
+	injected := *req
+	injected.Header = maps.Clone(req.Header)
+	if injected.Header == nil {
+		injected.Header = make(http.Header, len(*header))
+	}
+	for name, values := range *header {
+		injected.Header[name] = slices.Clone(values)
+	}
+
+	return &injected
+}
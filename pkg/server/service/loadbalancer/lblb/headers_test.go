@@ -0,0 +1,60 @@
+package lblb
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/traefik/traefik/v3/pkg/config/dynamic"
+)
+
+func TestLBBalancerServerHeaders(t *testing.T) {
+	balancer := New(nil, false)
+
+	received := map[string]http.Header{}
+	handler := func(name string) http.Handler {
+		return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			received[name] = req.Header.Clone()
+			// A server modifying the headers it was sent does not modify the headers of the balancer.
+			req.Header["X-Backend-Zone"][0] = "modified"
+			rw.WriteHeader(http.StatusOK)
+		})
+	}
+
+	// Tokens are not refilled during the test.
+	balancer.AddServer("first", handler("first"), dynamic.Server{
+		Burst: Int(1), Average: Int(1), Period: Int(3600000), Priority: Int(1),
+		Headers: map[string]string{"x-backend-zone": "zone-a", "X-Backend-Version": "v1"},
+	})
+	balancer.AddServer("second", handler("second"), dynamic.Server{
+		Burst: Int(1), Average: Int(1), Period: Int(3600000), Priority: Int(2),
+	})
+
+	for range 2 {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("X-Backend-Zone", "spoofed")
+		balancer.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	// The headers are injected into a copy of the request of the client.
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-Backend-Zone", "spoofed")
+	balancer.servers["first"].injectHeaders(req)
+	assert.Equal(t, "spoofed", req.Header.Get("X-Backend-Zone"))
+	assert.Equal(t, []string{"zone-a"}, (*balancer.servers["first"].headers.Load()).Values("X-Backend-Zone"))
+
+	// The headers of the selected server override the ones of the client, and are not set for the other servers.
+	require.Len(t, received, 2)
+	assert.Equal(t, []string{"zone-a"}, received["first"].Values("X-Backend-Zone"))
+	assert.Equal(t, "v1", received["first"].Get("X-Backend-Version"))
+	assert.Equal(t, "spoofed", received["second"].Get("X-Backend-Zone"))
+	assert.Empty(t, received["second"].Get("X-Backend-Version"))
+
+	require.NoError(t, balancer.SetServerHeaders("second", map[string]string{"X-Backend-Version": "v2"}))
+	require.NoError(t, balancer.SetServerHeaders("first", nil))
+	assert.Error(t, balancer.SetServerHeaders("unknown", nil))
+	assert.Nil(t, balancer.servers["first"].headers.Load())
+	assert.Equal(t, http.Header{"X-Backend-Version": {"v2"}}, *balancer.servers["second"].headers.Load())
+}
@@ -0,0 +1,65 @@
+package lblb
+
+import (
+	"context"
+	"fmt"
+)
+
+// HealthState is the health of a server, as reported by a Checker.
+type HealthState int
+
+const (
+	// Ready means the server is alive and accepts traffic.
+	Ready HealthState = iota
+	// NotReady means the server is alive but must not receive traffic yet (e.g. warming up).
+	// It is kept in the balancer and marked as down.
+	NotReady
+	// Dead means the server is gone, and it is removed from the balancer.
+	Dead
+)
+
+func (s HealthState) String() string {
+	switch s {
+	case Ready:
+		return "ready"
+	case NotReady:
+		return "not-ready"
+	case Dead:
+		return "dead"
+	default:
+		return fmt.Sprintf("HealthState(%d)", int(s))
+	}
+}
+
+// Checker reports the health of a server of the balancer.
+type Checker interface {
+	Check(ctx context.Context, name string) HealthState
+}
+
+// Check runs checker against every server of the balancer, and applies the reported states.
+// The checker is not called with the mutex held, so it may take its time probing the servers.
+func (b *LBBalancer) Check(ctx context.Context, checker Checker) {
+	b.mutex.RLock()
+	names := make([]string, 0, len(b.handlers))
+	for _, h := range b.handlers {
+		names = append(names, h.name)
+	}
+	b.mutex.RUnlock()
+
+	for _, name := range names {
+		b.SetHealthState(ctx, name, checker.Check(ctx, name))
+	}
+}
+
+// SetHealthState applies the given health state to the named server:
+// a Ready server is marked as up, a NotReady one as down, and a Dead one is removed.
+func (b *LBBalancer) SetHealthState(ctx context.Context, name string, state HealthState) {
+	switch state {
+	case Ready:
+		b.SetStatus(ctx, name, true)
+	case NotReady:
+		b.SetStatus(ctx, name, false)
+	case Dead:
+		b.RemoveServer(ctx, name)
+	}
+}
@@ -0,0 +1,134 @@
+package lblb
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeChecker map[string]HealthState
+
+func (c fakeChecker) Check(_ context.Context, name string) HealthState {
+	return c[name]
+}
+
+func TestLBBalancerCheck(t *testing.T) {
+	balancer := New(nil, false)
+
+	for i, name := range []string{"first", "second", "third"} {
+		balancer.Add(name, http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			rw.Header().Set("server", name)
+			rw.WriteHeader(http.StatusOK)
+		}), Int(10), Int(10), Int(1000), Int(i+1))
+	}
+
+	checker := fakeChecker{"first": NotReady, "second": Dead, "third": Ready}
+	balancer.Check(context.Background(), checker)
+
+	recorder := &responseRecorder{ResponseRecorder: httptest.NewRecorder(), save: map[string]int{}}
+	for range 3 {
+		balancer.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+	}
+
+	assert.Equal(t, 3, recorder.save["third"])
+	assert.Equal(t, []string{"first", "third"}, serverNames(balancer))
+
+	// Once ready, the kept server receives traffic again.
+	checker["first"] = Ready
+	balancer.Check(context.Background(), checker)
+
+	recorder = &responseRecorder{ResponseRecorder: httptest.NewRecorder(), save: map[string]int{}}
+	balancer.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	assert.Equal(t, 1, recorder.save["first"])
+}
+
+func TestLBBalancerRemoveServerPropagate(t *testing.T) {
+	balancer := New(nil, true)
+
+	balancer.Add("first", http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {}), Int(1), Int(1), Int(1), Int(1))
+
+	var updates []bool
+	assert.NoError(t, balancer.RegisterStatusUpdater(func(up bool) {
+		updates = append(updates, up)
+	}))
+
+	assert.False(t, balancer.RemoveServer(context.Background(), "unknown"))
+	assert.True(t, balancer.RemoveServer(context.Background(), "first"))
+	assert.Equal(t, []bool{false}, updates)
+
+	recorder := httptest.NewRecorder()
+	balancer.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+}
+
+// serverNames returns the sorted names of the servers of the balancer.
+func serverNames(b *LBBalancer) []string {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	var names []string
+	for _, h := range b.handlers {
+		names = append(names, h.name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+func TestLBBalancerProbeChecker(t *testing.T) {
+	balancer := New(nil, false)
+
+	traffic := map[string]int{}
+	for i, name := range []string{"first", "second", "third"} {
+		balancer.Add(name, http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			traffic[name]++
+			rw.Header().Set("server", name)
+			rw.WriteHeader(http.StatusOK)
+		}), Int(10), Int(10), Int(1000), Int(i+1))
+	}
+
+	probes := map[string][]string{}
+	healthy := map[string]bool{"first": false, "second": true}
+	for _, name := range []string{"first", "second"} {
+		assert.NoError(t, balancer.SetHealthCheckEndpoint(name, http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			probes[name] = append(probes[name], req.Method+" "+req.URL.Path)
+			if !healthy[name] {
+				rw.WriteHeader(http.StatusServiceUnavailable)
+			}
+		}), "/ready"))
+	}
+	assert.Error(t, balancer.SetHealthCheckEndpoint("unknown", nil, "/ready"))
+
+	balancer.Check(context.Background(), NewProbeChecker(balancer, time.Second))
+
+	// The probes hit the health check endpoints, never the traffic handlers.
+	assert.Equal(t, map[string][]string{"first": {"GET /ready"}, "second": {"GET /ready"}}, probes)
+	assert.Empty(t, traffic)
+
+	// first failed its probe, and third, without endpoint, is kept up.
+	assert.False(t, balancer.Stats().Servers["first"].Up)
+	assert.True(t, balancer.Stats().Servers["second"].Up)
+	assert.True(t, balancer.Stats().Servers["third"].Up)
+
+	// The traffic still goes to the traffic handlers.
+	recorder := httptest.NewRecorder()
+	balancer.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, "second", recorder.Header().Get("server"))
+	assert.Equal(t, map[string]int{"second": 1}, traffic)
+	assert.Len(t, probes["second"], 1)
+
+	healthy["first"] = true
+	balancer.Check(context.Background(), NewProbeChecker(balancer, 0))
+	assert.True(t, balancer.Stats().Servers["first"].Up)
+
+	// Without a handler of its own, the endpoint is probed on the traffic handler.
+	assert.NoError(t, balancer.SetHealthCheckEndpoint("third", nil, ""))
+	balancer.Check(context.Background(), NewProbeChecker(balancer, 0))
+	assert.Equal(t, 1, traffic["third"])
+}
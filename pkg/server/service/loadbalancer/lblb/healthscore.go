@@ -0,0 +1,103 @@
+package lblb
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// healthScoreWindow is the duration over which the rejection rate of the health score is measured.
+const healthScoreWindow = 10 * time.Second
+
+// healthSample is the measurement of the rejection rate of the health score.
+type healthSample struct {
+	at       time.Time
+	served   uint64
+	rejected uint64
+	// rate is the rejection rate measured over the window ending at.
+	rate float64
+}
+
+// HealthScore returns the health of the balancer as a whole, between 0 and 100,
+// e.g. for the health check of an external load balancer in front of it, which needs more than whether a server is up.
+// The score weights the share of the servers which are up for 40%, the share of the bursts of the up servers
+// available in their buckets for 30%, and the share of the requests which were not rejected for 30%,
+// the rejections being measured over the latest completed window of 10 seconds.
+// A balancer without any up server has a score of 0.
+func (b *LBBalancer) HealthScore() int {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	now := b.clock.Now()
+	b.updateHealthSample(now)
+
+	if len(b.handlers) == 0 || len(b.status) == 0 {
+		return 0
+	}
+
+	var up int
+	var tokens, bursts float64
+	for _, h := range b.handlers {
+		if _, ok := b.status[h.name]; !ok {
+			continue
+		}
+
+		up++
+		tokens += min(max(h.bucket.TokensAt(now), 0), float64(h.bucket.Burst()))
+		bursts += float64(h.bucket.Burst())
+	}
+
+	score := 0.4 * float64(up) / float64(len(b.handlers))
+	if bursts > 0 {
+		score += 0.3 * tokens / bursts
+	}
+	score += 0.3 * (1 - b.healthSample.rate)
+
+	return int(math.Round(100 * score))
+}
+
+// updateHealthSample measures the rejection rate over the window ending at now, if the previous one is over.
+// It must be called with the mutex held.
+func (b *LBBalancer) updateHealthSample(now time.Time) {
+	served, rejected := b.demand()
+
+	sample := b.healthSample
+	if sample == nil {
+		b.healthSample = &healthSample{at: now, served: served, rejected: rejected}
+		return
+	}
+
+	if now.Sub(sample.at) < healthScoreWindow {
+		return
+	}
+
+	// The served count goes down when a server is removed.
+	servedDelta := served - min(sample.served, served)
+	rejectedDelta := rejected - min(sample.rejected, rejected)
+
+	var rate float64
+	if total := servedDelta + rejectedDelta; total > 0 {
+		rate = float64(rejectedDelta) / float64(total)
+	}
+
+	b.healthSample = &healthSample{at: now, served: served, rejected: rejected, rate: rate}
+}
+
+// HealthScoreHandler returns a handler responding with the health score of the balancer, see HealthScore,
+// with a 200 if it is at least threshold, and a 503 otherwise,
+// e.g. to be probed by the health check of an external load balancer.
+func (b *LBBalancer) HealthScoreHandler(threshold int) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		score := b.HealthScore()
+
+		rw.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		if score < threshold {
+			rw.WriteHeader(http.StatusServiceUnavailable)
+		} else {
+			rw.WriteHeader(http.StatusOK)
+		}
+
+		_, _ = rw.Write([]byte(strconv.Itoa(score) + "\n"))
+	})
+}
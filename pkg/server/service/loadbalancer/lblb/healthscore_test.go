@@ -0,0 +1,71 @@
+package lblb
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLBBalancerHealthScore(t *testing.T) {
+	clock := newFakeClock()
+
+	balancer := New(nil, false)
+	balancer.clock = clock
+	assert.Equal(t, 0, balancer.HealthScore())
+
+	// Tokens are barely refilled during the test.
+	for i, name := range []string{"first", "second", "third", "fourth"} {
+		balancer.Add(name, http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			rw.WriteHeader(http.StatusOK)
+		}), Int(10), Int(10), Int(3600000), Int(i+1))
+	}
+
+	handler := balancer.HealthScoreHandler(60)
+	probe := func() *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/health", nil))
+		return recorder
+	}
+
+	assert.Equal(t, 100, balancer.HealthScore())
+
+	balancer.SetStatus(context.Background(), "fourth", false)
+	assert.Equal(t, 90, balancer.HealthScore())
+
+	recorder := probe()
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "90\n", recorder.Body.String())
+
+	// The score drops as the tokens of the up servers are used up.
+	for range 15 {
+		balancer.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	}
+	assert.Equal(t, 75, balancer.HealthScore())
+
+	for range 15 {
+		balancer.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	}
+	assert.Equal(t, 60, balancer.HealthScore())
+	assert.Equal(t, http.StatusOK, probe().Code)
+
+	// And as the requests get rejected, once measured over a whole window.
+	for range 10 {
+		balancer.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	}
+	assert.Equal(t, 60, balancer.HealthScore())
+
+	clock.Advance(healthScoreWindow)
+	assert.InDelta(t, 53, balancer.HealthScore(), 1)
+
+	recorder = probe()
+	assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+	assert.Equal(t, "53\n", recorder.Body.String())
+
+	balancer.SetStatus(context.Background(), "first", false)
+	balancer.SetStatus(context.Background(), "second", false)
+	balancer.SetStatus(context.Background(), "third", false)
+	assert.Equal(t, 0, balancer.HealthScore())
+}
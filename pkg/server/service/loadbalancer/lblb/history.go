@@ -0,0 +1,104 @@
+package lblb
+
+import "time"
+
+// AdmissionSample is the number of admission decisions made by the bucket of a server during a second.
+type AdmissionSample struct {
+	Time    time.Time `json:"time"`
+	Allowed uint64    `json:"allowed"`
+	Denied  uint64    `json:"denied"`
+}
+
+// admissionHistory is a ring of per-second admission counters, guarded by the balancer mutex.
+type admissionHistory struct {
+	slots []admissionSlot
+}
+
+type admissionSlot struct {
+	// second is the Unix time of the second the counters are about.
+	second  int64
+	allowed uint64
+	denied  uint64
+}
+
+// SetAdmissionHistory enables the recording of the admission decisions of the server buckets,
+// per second over the last given number of seconds, to be retrieved with AdmissionHistory.
+// The storage is bounded by the number of seconds, and the recording is done without any additional lock.
+// Changing the number of seconds resets the history, and a non-positive one disables it, which is the default.
+func (b *LBBalancer) SetAdmissionHistory(seconds int) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.admissionSeconds = max(seconds, 0)
+	for _, h := range b.handlers {
+		h.history = newAdmissionHistory(b.admissionSeconds)
+	}
+}
+
+// AdmissionHistory returns the per-second admission decisions of the buckets of the servers,
+// oldest first, over the seconds configured with SetAdmissionHistory, by server name.
+// The seconds without decisions are included with zero counts.
+func (b *LBBalancer) AdmissionHistory() map[string][]AdmissionSample {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	if b.admissionSeconds == 0 {
+		return nil
+	}
+
+	now := b.clock.Now().Unix()
+
+	history := make(map[string][]AdmissionSample, len(b.handlers))
+	for _, h := range b.handlers {
+		history[h.name] = h.history.samples(now)
+	}
+
+	return history
+}
+
+func newAdmissionHistory(seconds int) *admissionHistory {
+	if seconds <= 0 {
+		return nil
+	}
+
+	return &admissionHistory{slots: make([]admissionSlot, seconds)}
+}
+
+// record counts an admission decision made at now.
+// It must be called with the balancer mutex held.
+func (a *admissionHistory) record(now time.Time, allowed bool) {
+	if a == nil {
+		return
+	}
+
+	second := now.Unix()
+	slot := &a.slots[second%int64(len(a.slots))]
+	if slot.second != second {
+		*slot = admissionSlot{second: second}
+	}
+
+	if allowed {
+		slot.allowed++
+	} else {
+		slot.denied++
+	}
+}
+
+// samples returns the counters of the seconds of the history ending at the now second.
+func (a *admissionHistory) samples(now int64) []AdmissionSample {
+	if a == nil {
+		return nil
+	}
+
+	samples := make([]AdmissionSample, 0, len(a.slots))
+	for second := now - int64(len(a.slots)) + 1; second <= now; second++ {
+		sample := AdmissionSample{Time: time.Unix(second, 0)}
+		if slot := a.slots[second%int64(len(a.slots))]; slot.second == second {
+			sample.Allowed = slot.allowed
+			sample.Denied = slot.denied
+		}
+		samples = append(samples, sample)
+	}
+
+	return samples
+}
@@ -0,0 +1,59 @@
+package lblb
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLBBalancerAdmissionHistory(t *testing.T) {
+	clock := newFakeClock()
+	balancer := New(nil, false)
+	balancer.clock = clock
+
+	assert.Nil(t, balancer.AdmissionHistory())
+
+	// 5 requests per second, with a burst of 5.
+	balancer.Add("first", http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusOK)
+	}), Int(5), Int(5), Int(1000), Int(1))
+	balancer.SetAdmissionHistory(4)
+
+	serve := func(n int) {
+		for range n {
+			balancer.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+		}
+	}
+
+	// A burst of traffic: the bucket gets emptied and denies the rest.
+	serve(10)
+	clock.Advance(time.Second)
+	// Recovery: the bucket got refilled.
+	serve(3)
+	clock.Advance(time.Second)
+
+	history := balancer.AdmissionHistory()
+	require.Len(t, history["first"], 4)
+
+	var counts [][2]uint64
+	for _, sample := range history["first"] {
+		counts = append(counts, [2]uint64{sample.Allowed, sample.Denied})
+	}
+	assert.Equal(t, [][2]uint64{{0, 0}, {5, 5}, {3, 0}, {0, 0}}, counts)
+
+	now := clock.Now().Unix()
+	assert.Equal(t, time.Unix(now-3, 0), history["first"][0].Time)
+	assert.Equal(t, time.Unix(now, 0), history["first"][3].Time)
+
+	// The oldest seconds get overwritten.
+	clock.Advance(2 * time.Second)
+	counts = nil
+	for _, sample := range balancer.AdmissionHistory()["first"] {
+		counts = append(counts, [2]uint64{sample.Allowed, sample.Denied})
+	}
+	assert.Equal(t, [][2]uint64{{3, 0}, {0, 0}, {0, 0}, {0, 0}}, counts)
+}
@@ -0,0 +1,40 @@
+package lblb
+
+import "net/http"
+
+// IsIdempotent is the default idempotency classifier:
+// it reports whether the method of req is idempotent per RFC 9110, i.e. GET, HEAD, OPTIONS, TRACE, PUT or DELETE.
+func IsIdempotent(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
+
+// SetIdempotencyClassifier sets the function deciding whether a request is idempotent,
+// shared by all the paths which would send a request to a server more than once, so that they behave consistently.
+// A request classified as non-idempotent is never sent again, nor duplicated.
+// The classifier must not block, nor read the body of the request.
+// A nil classifier restores IsIdempotent, which is the default.
+func (b *LBBalancer) SetIdempotencyClassifier(fn func(*http.Request) bool) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.idempotent = fn
+}
+
+// Idempotent reports whether req is idempotent, per the idempotency classifier,
+// e.g. for a retry in front of the balancer to follow the same policy.
+func (b *LBBalancer) Idempotent(req *http.Request) bool {
+	b.mutex.RLock()
+	fn := b.idempotent
+	b.mutex.RUnlock()
+
+	if fn == nil {
+		return IsIdempotent(req)
+	}
+
+	return fn(req)
+}
@@ -0,0 +1,48 @@
+package lblb
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLBBalancerIdempotencyClassifier(t *testing.T) {
+	balancer := New(nil, false)
+
+	testCases := []struct {
+		method     string
+		path       string
+		idempotent bool
+		custom     bool
+	}{
+		{method: http.MethodGet, path: "/", idempotent: true, custom: true},
+		{method: http.MethodHead, path: "/", idempotent: true, custom: true},
+		{method: http.MethodOptions, path: "/", idempotent: true, custom: true},
+		{method: http.MethodTrace, path: "/", idempotent: true, custom: true},
+		{method: http.MethodPut, path: "/", idempotent: true, custom: true},
+		{method: http.MethodDelete, path: "/", idempotent: true, custom: true},
+		{method: http.MethodPost, path: "/"},
+		{method: http.MethodPatch, path: "/"},
+		{method: http.MethodPost, path: "/search", custom: true},
+	}
+
+	for _, test := range testCases {
+		req := httptest.NewRequest(test.method, test.path, nil)
+		assert.Equal(t, test.idempotent, balancer.Idempotent(req), "%s %s", test.method, test.path)
+	}
+
+	// The searches are safe to send again, even though they are POST requests.
+	balancer.SetIdempotencyClassifier(func(req *http.Request) bool {
+		return IsIdempotent(req) || (req.Method == http.MethodPost && req.URL.Path == "/search")
+	})
+
+	for _, test := range testCases {
+		req := httptest.NewRequest(test.method, test.path, nil)
+		assert.Equal(t, test.custom, balancer.Idempotent(req), "%s %s", test.method, test.path)
+	}
+
+	balancer.SetIdempotencyClassifier(nil)
+	assert.False(t, balancer.Idempotent(httptest.NewRequest(http.MethodPost, "/search", nil)))
+}
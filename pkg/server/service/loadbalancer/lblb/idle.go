@@ -0,0 +1,29 @@
+package lblb
+
+import "time"
+
+// NeverSelected is the idle time of a server which never served a request, see IdleTimes.
+const NeverSelected time.Duration = -1
+
+// IdleTimes returns the time since each server last served a request, by server name,
+// NeverSelected for a server which never did, e.g. to detect the servers starved by misconfigured priorities.
+// A server re-created by SetServers starts over as never selected.
+func (b *LBBalancer) IdleTimes() map[string]time.Duration {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	now := b.clock.Now()
+
+	idle := make(map[string]time.Duration, len(b.handlers))
+	for _, h := range b.handlers {
+		last := h.lastSelected.Load()
+		if last == 0 {
+			idle[h.name] = NeverSelected
+			continue
+		}
+
+		idle[h.name] = max(now.Sub(time.Unix(0, last)), 0)
+	}
+
+	return idle
+}
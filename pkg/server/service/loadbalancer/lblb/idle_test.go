@@ -0,0 +1,38 @@
+package lblb
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLBBalancerIdleTimes(t *testing.T) {
+	clock := newFakeClock()
+	balancer := New(nil, false)
+	balancer.clock = clock
+
+	for i, name := range []string{"first", "second", "third"} {
+		balancer.Add(name, http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			rw.Header().Set("server", name)
+			rw.WriteHeader(http.StatusOK)
+		}), Int(100), Int(100), Int(1000), Int(i+1))
+	}
+
+	assert.Equal(t, map[string]time.Duration{"first": NeverSelected, "second": NeverSelected, "third": NeverSelected}, balancer.IdleTimes())
+
+	// first takes all the traffic, second only once, and third is starved.
+	assert.Equal(t, "first", serveOne(balancer))
+	balancer.SetDraining("first", true)
+	assert.Equal(t, "second", serveOne(balancer))
+	balancer.SetDraining("first", false)
+
+	clock.Advance(time.Second)
+	assert.Equal(t, "first", serveOne(balancer))
+	assert.Equal(t, map[string]time.Duration{"first": 0, "second": time.Second, "third": NeverSelected}, balancer.IdleTimes())
+
+	clock.Advance(time.Second)
+	assert.Equal(t, "first", serveOne(balancer))
+	assert.Equal(t, map[string]time.Duration{"first": 0, "second": 2 * time.Second, "third": NeverSelected}, balancer.IdleTimes())
+}
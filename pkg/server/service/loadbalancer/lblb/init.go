@@ -0,0 +1,44 @@
+package lblb
+
+import (
+	"net/http"
+	"time"
+)
+
+// SetInitTimeout sets how long a request arriving before the first server is added waits for it,
+// before the balancer is considered empty.
+// It avoids spurious unavailable responses while the balancer is being built, e.g. during a fast reload.
+// A non-positive timeout disables the wait, which is the default.
+func (b *LBBalancer) SetInitTimeout(timeout time.Duration) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.initTimeout = timeout
+}
+
+// waitInitialized waits for the first server to be added, up to the init timeout,
+// or until the request is canceled.
+func (b *LBBalancer) waitInitialized(req *http.Request) {
+	select {
+	case <-b.initialized:
+		return
+	default:
+	}
+
+	b.mutex.RLock()
+	timeout := b.initTimeout
+	b.mutex.RUnlock()
+
+	if timeout <= 0 {
+		return
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case <-b.initialized:
+	case <-timer.C:
+	case <-req.Context().Done():
+	}
+}
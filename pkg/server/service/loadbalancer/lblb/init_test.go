@@ -0,0 +1,52 @@
+package lblb
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLBBalancerInitTimeout(t *testing.T) {
+	balancer := New(nil, false)
+	balancer.SetInitTimeout(5 * time.Second)
+
+	done := make(chan *httptest.ResponseRecorder)
+	go func() {
+		recorder := httptest.NewRecorder()
+		balancer.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+		done <- recorder
+	}()
+
+	// The request waits for the first server instead of failing.
+	select {
+	case <-done:
+		t.Fatal("request served before the first server was added")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	balancer.Add("first", http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusOK)
+	}), Int(1), Int(1), Int(1), Int(1))
+
+	select {
+	case recorder := <-done:
+		assert.Equal(t, http.StatusOK, recorder.Code)
+	case <-time.After(5 * time.Second):
+		t.Fatal("request not served after the first server was added")
+	}
+}
+
+func TestLBBalancerInitTimeoutExpired(t *testing.T) {
+	balancer := New(nil, false)
+	balancer.SetInitTimeout(20 * time.Millisecond)
+
+	start := time.Now()
+	recorder := httptest.NewRecorder()
+	balancer.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+	assert.GreaterOrEqual(t, time.Since(start), 20*time.Millisecond)
+}
@@ -0,0 +1,37 @@
+package lblb
+
+// SetLastResortPriority sets how a zero priority is interpreted for the servers added or updated afterwards.
+// By default, a non-positive priority is clamped to 1, so that a priority 0 server is merged with the priority 1 ones.
+// When enabled, a priority 0 server is a last resort instead:
+// it is only selected when no server of a positive priority is available, whatever their priorities.
+// Negative priorities are still clamped to 1.
+func (b *LBBalancer) SetLastResortPriority(enabled bool) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.lastResortPriority = enabled
+}
+
+// withLastResort applies the last resort interpretation of a zero priority, if enabled, to params,
+// resolved from the given configured priority.
+func (b *LBBalancer) withLastResort(params serverParams, priority *int) serverParams {
+	b.mutex.RLock()
+	enabled := b.lastResortPriority
+	b.mutex.RUnlock()
+
+	if enabled && priority != nil && *priority == 0 {
+		params.priority = 0
+	}
+
+	return params
+}
+
+// higherPriority reports whether the priority of a is higher than the one of c, i.e. lower,
+// except for the last resort zero priority which is the lowest.
+func higherPriority(a, c *namedHandler) bool {
+	if (a.priority == 0) != (c.priority == 0) {
+		return c.priority == 0
+	}
+
+	return a.priority < c.priority
+}
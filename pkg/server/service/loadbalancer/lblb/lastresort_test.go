@@ -0,0 +1,97 @@
+package lblb
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/traefik/traefik/v3/pkg/config/dynamic"
+)
+
+func TestLBBalancerLastResortPriority(t *testing.T) {
+	testCases := []struct {
+		desc       string
+		lastResort bool
+		expected   []string
+	}{
+		{
+			desc: "clamped",
+			// The priority 0 server is merged with the priority 1 ones, ahead of the priority 2 one.
+			expected: []string{"zero", "two", "two"},
+		},
+		{
+			desc:       "last resort",
+			lastResort: true,
+			expected:   []string{"two", "two", "zero"},
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			balancer := New(nil, false)
+			balancer.clock = newFakeClock()
+			balancer.SetLastResortPriority(test.lastResort)
+
+			// Tokens are not refilled during the test.
+			balancer.Add("zero", http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+				rw.Header().Set("server", "zero")
+			}), Int(1), Int(1), Int(3600000), Int(0))
+			balancer.Add("two", http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+				rw.Header().Set("server", "two")
+			}), Int(2), Int(1), Int(3600000), Int(2))
+
+			for _, expected := range test.expected {
+				recorder := httptest.NewRecorder()
+				balancer.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+				assert.Equal(t, expected, recorder.Header().Get("server"))
+			}
+		})
+	}
+}
+
+func TestLBBalancerLastResortUnavailable(t *testing.T) {
+	balancer := New(nil, false)
+	balancer.SetLastResortPriority(true)
+
+	for i, name := range []string{"zero", "one", "two"} {
+		balancer.Add(name, http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			rw.Header().Set("server", name)
+		}), Int(100), Int(100), Int(1000), Int(i))
+	}
+
+	serve := func() string {
+		recorder := httptest.NewRecorder()
+		balancer.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+		return recorder.Header().Get("server")
+	}
+
+	assert.Equal(t, "one", serve())
+
+	balancer.SetStatus(context.Background(), "one", false)
+	assert.Equal(t, "two", serve())
+
+	// The last resort is ordered after the others with score weights as well.
+	balancer.SetScoreWeights(&ScoreWeights{Priority: 1})
+	assert.Equal(t, "two", serve())
+	balancer.SetScoreWeights(nil)
+
+	balancer.SetStatus(context.Background(), "two", false)
+	assert.Equal(t, "zero", serve())
+
+	// An update can move a server to the last resort tier, and a boost out of it.
+	balancer.SetStatus(context.Background(), "one", true)
+	require.NoError(t, balancer.UpdateServer("one", dynamic.Server{Burst: Int(100), Average: Int(100), Period: Int(1000), Priority: Int(0)}))
+	balancer.SetStatus(context.Background(), "two", true)
+	assert.Equal(t, "two", serve())
+	balancer.SetStatus(context.Background(), "two", false)
+
+	require.NoError(t, balancer.BoostPriority("one", 0, time.Minute))
+	assert.Equal(t, "one", serve())
+	balancer.Close()
+}
@@ -4,8 +4,11 @@ import (
 	"container/heap"
 	"context"
 	"errors"
+	"fmt"
 	"net/http"
+	"slices"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/rs/zerolog/log"
@@ -23,6 +26,49 @@ type namedHandler struct {
 	priority int64
 	bucket   *rate.Limiter
 	canAllow bool
+	// stickyBucket, when set, limits the sticky traffic to its share of the bucket capacity.
+	stickyBucket *rate.Limiter
+
+	// served is the number of requests dispatched to the handler.
+	served atomic.Uint64
+	// disabled is whether the handler is registered as disabled, see SetZeroAverageDisabled, guarded by the balancer mutex.
+	disabled bool
+	// lastSelected is when a request was last dispatched to the handler, in Unix nanoseconds, 0 if never.
+	lastSelected atomic.Int64
+	// inflight is the number of requests currently dispatched to the handler.
+	inflight atomic.Int64
+	// throttled is the number of times the bucket denied a request, before any dispatch.
+	throttled atomic.Uint64
+	// maxResponseSize is the maximum size of the response bodies, 0 meaning no limit.
+	maxResponseSize atomic.Int64
+	// oversized is the number of responses which exceeded maxResponseSize.
+	oversized atomic.Uint64
+	// clientCanceled is the number of requests canceled by their client before the end of their response.
+	clientCanceled atomic.Uint64
+	// maxRequestBodySize is the maximum size of the request bodies, 0 meaning no limit.
+	maxRequestBodySize atomic.Int64
+	// requestTimeout is the time budget of the requests, 0 meaning the one of the balancer.
+	requestTimeout atomic.Int64
+	// latency is the moving average response time, guarded by the balancer mutex.
+	latency time.Duration
+	// external is the score set with SetServerScore, guarded by the balancer mutex.
+	external externalScore
+	// responses are the number of responses of the handler, by status class (1xx to 5xx).
+	responses [5]atomic.Uint64
+	// outlier is the outlier detection state, guarded by the balancer mutex.
+	outlier outlierState
+	// history, when set, records the admission decisions of the bucket.
+	history *admissionHistory
+	// trace, when set, records a sample of the states of the bucket around its admission decisions.
+	trace *bucketTrace
+	// health, when set, is the endpoint probed by the ProbeChecker, guarded by the balancer mutex.
+	health *healthEndpoint
+	// tag is the tag the metrics of the handler are aggregated by, guarded by the balancer mutex.
+	tag string
+	// backpressure, when set, is the estimated queue depth reported by the handler, guarded by the balancer mutex.
+	backpressure *backpressureEstimate
+	// headers, when set, are the request headers set on the requests dispatched to the handler.
+	headers atomic.Pointer[http.Header]
 }
 
 // type stickyCookie struct {
@@ -38,6 +84,10 @@ type LBBalancer struct {
 
 	mutex    sync.RWMutex
 	handlers []*namedHandler
+	// servers references the handlers by name.
+	servers map[string]*namedHandler
+	// children are the servers which are child balancers added with AddChild, by name.
+	children map[string]*LBBalancer
 	// curDeadline float64
 	// status is a record of which child services of the Balancer are healthy, keyed
 	// by name of child service. A service is initially added to the map when it is
@@ -46,18 +96,150 @@ type LBBalancer struct {
 	status map[string]struct{}
 	// updaters is the list of hooks that are run (to update the Balancer
 	// parent(s)), whenever the Balancer status changes.
-	updaters           []func(bool)
+	updaters []statusUpdater
+	// maxUpdaters is the maximum number of updaters, 0 meaning no limit.
+	maxUpdaters        int
 	serverAvailability map[string]time.Time
-	sticky             *loadbalancer.Sticky
+	// draining is the list of terminating yet still serving child services:
+	// they only receive the requests sticking to them.
+	draining map[string]struct{}
+	sticky   *loadbalancer.Sticky
+	// auditHook, when set, is called with the record of every administrative operation.
+	auditHook func(AuditEvent)
+	// stickyMigration, when set, is called when a sticky session is remapped to another server.
+	stickyMigration func(StickyMigration)
+	// allDraining, when set, is the handling of the requests when all the up servers are draining.
+	allDraining *allDraining
+	// silentRemovalMigrations disables the notification of the migrations away from a removed server.
+	silentRemovalMigrations bool
+	// drainingCookieMaxAge, when positive, is the MaxAge of the sticky cookies pinning a client to a draining server.
+	drainingCookieMaxAge int
+	// nonStickyReservation is the fraction of the capacity of each server which sticky traffic cannot use.
+	nonStickyReservation float64
+	// tracer, when set, is called with the record of every selection.
+	tracer func(Decision)
+	// observers are called with the record of every selection, in addition to the tracer.
+	// The slice is replaced, never modified, when an observer is added or removed.
+	observers      []selectionObserver
+	nextObserverID uint64
+	// outlierDetection, when set, enables the ejection of servers with a high error rate.
+	outlierDetection *OutlierDetection
+	// unavailable, when set, is the response written when no server is available.
+	unavailable *unavailableResponse
+	// rejected is the number of requests which could not be dispatched to any server.
+	rejected atomic.Uint64
+	// selectionLatency is a summary of the durations of the selections.
+	selectionLatency selectionLatency
+	// healthSample is the measurement of the rejection rate of the health score.
+	healthSample *healthSample
+	// rejections are the numbers of rejected requests, by reason.
+	rejections rejectionCounters
+	// capped is the number of rejected requests which were rejected by a cap of the balancer.
+	capped atomic.Uint64
+	// capRejection is the response to the requests rejected by a cap of the balancer.
+	capRejection capRejection
+	// stickySessions, when set, tracks the lifetimes of the sticky sessions.
+	stickySessions *stickySessions
+	// tierShares, when set, are the shares of the priority tiers of the proportional tiers strategy.
+	tierShares *tierShares
+	// followRedirects, when set, is the following of the redirects of the servers.
+	followRedirects *followRedirects
+	// responseCache, when set, caches the responses to the GET requests.
+	responseCache *responseCache
+	// zeroAverageDisabled is whether a server with an explicit zero average is registered as disabled.
+	zeroAverageDisabled bool
+	// aggregateBucket, when set, caps the burst of the balancer as a whole.
+	aggregateBucket *rate.Limiter
+	// aggregateBurst is the sum of the bursts of the up servers, updated along with the aggregate bucket.
+	aggregateBurst int64
+	// created is when the balancer was created, which starts its startup phase.
+	created time.Time
+	// startupRamp, when set, ramps the admission of the balancer during its startup phase.
+	startupRamp *startupRamp
+	// globalBucket, when set, is the rate limit of the balancer as a whole.
+	globalBucket *rate.Limiter
+	// maxOverflowHops is the maximum number of hops of a selection from a server denied by its bucket to the next one,
+	// 0 meaning no limit, and overflowFallback, when set, serves the requests reaching it.
+	maxOverflowHops  int
+	overflowFallback http.Handler
+	// bucketTrace, when set, records a sample of the states of the buckets around their admission decisions.
+	bucketTrace *bucketTrace
+	// admissionSeconds is the number of seconds of admission history recorded for each server.
+	admissionSeconds int
+	// bypass, when set, is the token allowing a request to bypass the rate limiting.
+	bypass *bypassToken
+	// bypassed is the number of requests which bypassed the rate limiting.
+	bypassed atomic.Uint64
+	// strategySelector, when set, chooses the selection strategy of each request.
+	strategySelector func(*http.Request) Strategy
+	// scoreWeights, when set, orders the servers by a score combining priority and latency.
+	scoreWeights *ScoreWeights
+	// seedBucketsOnReload seeds the buckets rebuilt by SetServers with the tokens of the previous ones.
+	seedBucketsOnReload bool
+	// idempotent, when set, classifies the requests which can be sent to a server more than once.
+	idempotent func(*http.Request) bool
+	// lastResortPriority makes the priority 0 servers a last resort, instead of clamping their priority to 1.
+	lastResortPriority bool
+	// scanLimit is the maximum number of candidates considered by a selection, 0 meaning no limit.
+	scanLimit int
+
+	// inflight is the number of requests currently dispatched by the balancer.
+	inflight atomic.Int64
+	// concurrencyLimit is the maximum value of inflight, 0 meaning no limit.
+	concurrencyLimit int64
+	shedding         *LoadShedding
+	// rateLimitHeaders enables the RateLimit headers on the responses.
+	rateLimitHeaders bool
+	// accessLogFields enables the access log fields describing the selection.
+	accessLogFields bool
+	// expectContinueStatus, when positive, is the status of the rejections of the requests expecting a 100-continue.
+	expectContinueStatus int
+	// requestTimeout is the time budget of the requests dispatched to the servers, 0 meaning no budget.
+	requestTimeout time.Duration
+	// backpressure, when set, reduces the rates of the servers reporting a high queue depth.
+	backpressure *Backpressure
+	// aggregateTags enables the aggregation of the published metrics of the tagged servers by tag.
+	aggregateTags bool
+
+	// rateScale, when set, is the temporary scaling of the rates of all the servers.
+	rateScale *rateScale
+	// boosts are the temporary priority boosts in progress, by server name.
+	boosts map[string]*boost
+	// schedules are the priority schedules of the servers, by server name.
+	schedules map[string]*prioritySchedule
+	// capacityCheck, when set, is the periodic comparison of the demand with the capacity.
+	capacityCheck *capacityCheck
+	// warmPool, when set, holds servers selected ahead of the requests.
+	warmPool *warmPool
+	// autoWeighting, when set, is the periodic adjustment of the rates to the observed capacity of the servers.
+	autoWeighting *autoWeighting
+
+	// initialized is closed once the first server is added.
+	initialized     chan struct{}
+	initializedOnce sync.Once
+	// initTimeout is how long a request waits for the first server to be added.
+	initTimeout time.Duration
+
+	// clock is the source of time of the balancer, and is overridden in tests.
+	clock clock
 }
 
 // New creates a new load balancer.
 func New(sticky *dynamic.Sticky, wantHealthCheck bool) *LBBalancer {
 	balancer := &LBBalancer{
 		status:             make(map[string]struct{}),
+		servers:            make(map[string]*namedHandler),
+		children:           make(map[string]*LBBalancer),
 		serverAvailability: make(map[string]time.Time),
+		draining:           make(map[string]struct{}),
 		wantsHealthCheck:   wantHealthCheck,
+		boosts:             make(map[string]*boost),
+		schedules:          make(map[string]*prioritySchedule),
+		capRejection:       defaultCapRejection,
+		initialized:        make(chan struct{}),
+		clock:              realClock{},
 	}
+	balancer.created = balancer.clock.Now()
 	if sticky != nil && sticky.Cookie != nil {
 		balancer.sticky = loadbalancer.NewSticky(*sticky.Cookie)
 	}
@@ -65,6 +247,38 @@ func New(sticky *dynamic.Sticky, wantHealthCheck bool) *LBBalancer {
 	return balancer
 }
 
+// Close stops the timers of the balancer, e.g. when it is dropped on a reload of the configuration.
+// A rate scaling in progress is ended, restoring the configured rates,
+// while the other temporary changes in progress, such as priority boosts and scheduled priorities, are not reverted.
+func (b *LBBalancer) Close() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	for name, bst := range b.boosts {
+		bst.stop()
+		delete(b.boosts, name)
+	}
+	b.stopSchedules()
+
+	if b.capacityCheck != nil {
+		b.capacityCheck.stop()
+		b.capacityCheck = nil
+	}
+
+	if b.autoWeighting != nil {
+		b.autoWeighting.stop()
+		b.autoWeighting = nil
+	}
+
+	if b.rateScale != nil {
+		b.rateScale.stop()
+		b.rateScale = nil
+		b.applyRates()
+	}
+
+	b.stopWarmPool()
+}
+
 // Len implements heap.Interface/sort.Interface.
 func (b *LBBalancer) Len() int { return len(b.handlers) }
 
@@ -74,7 +288,16 @@ func (b *LBBalancer) Len() int { return len(b.handlers) }
 // }
 
 func (b *LBBalancer) Less(i, j int) bool {
-	return b.handlers[i].priority < b.handlers[j].priority
+	if b.scoreWeights != nil {
+		// The last resort servers come after all the others, whatever their scores.
+		if lastResort := b.handlers[j].priority == 0; (b.handlers[i].priority == 0) != lastResort {
+			return lastResort
+		}
+
+		return b.score(b.handlers[i]) < b.score(b.handlers[j])
+	}
+
+	return higherPriority(b.handlers[i], b.handlers[j])
 }
 
 // Swap implements heap.Interface/sort.Interface.
@@ -113,6 +336,11 @@ func (b *LBBalancer) SetStatus(ctx context.Context, childName string, up bool) {
 		status = "UP"
 	}
 
+	if h, ok := b.servers[childName]; ok && h.disabled {
+		log.Ctx(ctx).Debug().Msgf("Ignoring status %v of disabled server %s", status, childName)
+		return
+	}
+
 	log.Ctx(ctx).Debug().Msgf("Setting status of %s to %v", childName, status)
 
 	if up {
@@ -121,8 +349,18 @@ func (b *LBBalancer) SetStatus(ctx context.Context, childName string, up bool) {
 		delete(b.status, childName)
 	}
 
+	b.audit(AuditSetStatus, map[string]any{"name": childName, "up": up})
+
+	b.updateAggregateBurst()
+
+	b.propagateStatus(ctx, upBefore)
+}
+
+// propagateStatus runs the updaters if the status of the balancer changed from upBefore.
+// It must be called with the mutex held.
+func (b *LBBalancer) propagateStatus(ctx context.Context, upBefore bool) {
 	upAfter := len(b.status) > 0
-	status = "DOWN"
+	status := "DOWN"
 	if upAfter {
 		status = "UP"
 	}
@@ -134,38 +372,117 @@ func (b *LBBalancer) SetStatus(ctx context.Context, childName string, up bool) {
 		return
 	}
 
+	if !b.wantsHealthCheck {
+		log.Ctx(ctx).Debug().Msgf("Now %s, health check propagation disabled", status)
+		return
+	}
+
 	// Status Change
 	log.Ctx(ctx).Debug().Msgf("Propagating new %s status", status)
-	for _, fn := range b.updaters {
-		fn(upAfter)
+	for _, u := range b.updaters {
+		u.fn(upAfter)
 	}
 }
 
 // RegisterStatusUpdater adds fn to the list of hooks that are run when the
 // status of the Balancer changes.
-// Not thread safe.
+// It returns an error if the maximum number of updaters is reached, see SetMaxStatusUpdaters.
 func (b *LBBalancer) RegisterStatusUpdater(fn func(up bool)) error {
-	if !b.wantsHealthCheck {
-		return errors.New("healthCheck not enabled in config for this leaky bucket service")
-	}
-	b.updaters = append(b.updaters, fn)
-	return nil
+	return b.RegisterKeyedStatusUpdater("", fn)
 }
 
 var errNoAvailableServer = errors.New("no available server")
 
-func (b *LBBalancer) nextServer() (*namedHandler, error) {
+// selection are the constraints of a server selection.
+type selection struct {
+	// excluded are the names of the servers which must not be selected.
+	excluded []string
+	// bypass ignores the buckets of the servers.
+	bypass bool
+	// strategy is how the server is selected among the eligible ones.
+	strategy Strategy
+	// dryRun only explains the selection, without consuming any token nor changing any state, see Explain.
+	dryRun bool
+}
+
+func (b *LBBalancer) nextServer(excluded ...string) (*namedHandler, error) {
+	return b.selectServer(selection{excluded: excluded})
+}
+
+func (b *LBBalancer) selectServer(sel selection) (*namedHandler, error) {
 	b.mutex.Lock()
-	defer b.mutex.Unlock()
+	tracer := b.tracer
+	observers := b.observers
+	var decision *Decision
+	if tracer != nil || len(observers) > 0 {
+		decision = &Decision{Time: time.Now()}
+	}
+	handler, err := b.pick(decision, sel)
+	b.mutex.Unlock()
+
+	// The tracer and observers are called outside of the lock, so that a slow one only delays its own request.
+	if decision != nil {
+		decision.Duration = time.Since(decision.Time)
+		if err != nil {
+			decision.Error = err.Error()
+		} else {
+			decision.Selected = handler.name
+		}
+		if tracer != nil {
+			tracer(*decision)
+		}
+		for _, o := range observers {
+			o.fn(*decision)
+		}
+	}
+
+	return handler, err
+}
 
+// pick selects a server for sel per its strategy, recording the considered candidates in decision if it is not nil.
+// It must be called with the mutex held.
+func (b *LBBalancer) pick(decision *Decision, sel selection) (*namedHandler, error) {
+	// A dry run restores the order of the heap, and does not decay the scores, which would reorder it.
+	if sel.dryRun {
+		order := slices.Clone(b.handlers)
+		defer func() { b.handlers = order }()
+	} else {
+		b.decayExternalScores(b.clock.Now())
+	}
+
+	// With a single up server, there is nothing to choose from, whatever the strategy.
+	single := b.singleUp()
+	switch {
+	case single != nil:
+		return b.pickSingle(decision, sel, single)
+	case sel.strategy == StrategyMostTokens:
+		return b.pickMostTokens(decision, sel)
+	case sel.strategy == StrategyPredictedCompletion:
+		return b.pickPredictedCompletion(decision, sel)
+	case sel.strategy == StrategyProportionalTiers:
+		return b.pickProportionalTier(decision, sel)
+	default:
+		return b.pickServer(decision, sel)
+	}
+}
+
+// pickServer selects the highest priority server which is up, not excluded, and allowed by its bucket unless bypassed.
+// The considered candidates are recorded in decision if it is not nil.
+// It must be called with the mutex held.
+func (b *LBBalancer) pickServer(decision *Decision, sel selection) (*namedHandler, error) {
 	if len(b.handlers) == 0 || len(b.status) == 0 {
 		return nil, errNoAvailableServer
 	}
 
+	now := b.clock.Now()
+
 	var handler *namedHandler
 	poppedHandlers := []*namedHandler{}
+	// throttled is the number of candidates denied by their bucket, i.e. of overflow hops.
+	var throttled int
 	for {
-		if b.Len() == 0 {
+		// With a scan limit, the selection fails after that many candidates, however large the pool is.
+		if b.Len() == 0 || (b.scanLimit > 0 && len(poppedHandlers) >= b.scanLimit) {
 			for _, handler := range poppedHandlers {
 				heap.Push(b, handler)
 			}
@@ -174,17 +491,39 @@ func (b *LBBalancer) nextServer() (*namedHandler, error) {
 		// Pick handler with highest priority.
 		handler = heap.Pop(b).(*namedHandler)
 		// log.Debug().Msgf("Handler poped: %s", handler.name)
-		// admissionStart := time.Now()
-		handler.canAllow = handler.bucket.Allow()
-		// log.Info().Msgf("admission decision: %s allow=%t in %d us", handler.name, handler.canAllow, time.Since(admissionStart).Microseconds())
 		poppedHandlers = append(poppedHandlers, handler)
 		// heap.Push(b, handler) // not to be immediately pushed back
 
-		if _, ok := b.status[handler.name]; ok && handler.canAllow {
+		// The server state is checked before its bucket, so that only an eligible server consumes a token.
+		if reason := b.skipReason(handler, sel, now); reason != "" {
+			decision.add(handler, reason)
+			continue
+		}
+
+		if sel.bypass {
+			decision.add(handler, "")
+			break
+		}
+
+		// admissionStart := time.Now()
+		allowed := b.admitCandidate(handler, sel, now)
+		// log.Info().Msgf("admission decision: %s allow=%t in %d us", handler.name, allowed, time.Since(admissionStart).Microseconds())
+
+		if allowed {
+			decision.add(handler, "")
 			break
 		}
+
+		decision.add(handler, skipRateLimited)
 		// log.Debug().Msgf("Service bucket not allowed: %s", handler.name)
 
+		throttled++
+		if b.overflowLimitReached(throttled) {
+			for _, handler := range poppedHandlers {
+				heap.Push(b, handler)
+			}
+			return nil, errOverflowLimit
+		}
 	}
 	for _, handler := range poppedHandlers {
 		heap.Push(b, handler)
@@ -193,6 +532,34 @@ func (b *LBBalancer) nextServer() (*namedHandler, error) {
 	return handler, nil
 }
 
+// skipReason returns why h cannot be selected, regardless of its bucket, or an empty string if it is eligible.
+// It must be called with the mutex held.
+func (b *LBBalancer) skipReason(h *namedHandler, sel selection, now time.Time) string {
+	if h.disabled {
+		return skipDisabled
+	}
+
+	if slices.Contains(sel.excluded, h.name) {
+		return skipExcluded
+	}
+
+	if _, up := b.status[h.name]; !up || !h.dispatchable() {
+		return skipDown
+	}
+
+	// A server in cooldown is skipped.
+	if !b.availableFor(h, sel, now) {
+		return skipEjected
+	}
+
+	// A draining server only serves the requests sticking to it.
+	if _, ok := b.draining[h.name]; ok {
+		return skipDraining
+	}
+
+	return ""
+}
+
 // func (b *LBBalancer) bucketDelay(handler *namedHandler, delay time.Duration) {
 // 	b.mutex.Lock()
 // 	defer b.mutex.Unlock()
@@ -200,64 +567,507 @@ func (b *LBBalancer) nextServer() (*namedHandler, error) {
 // }
 
 func (b *LBBalancer) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if follow := b.redirectsFollowed(req); follow != nil {
+		b.serveFollowingRedirects(w, req, follow)
+		return
+	}
+
+	b.serveHTTP(w, req)
+}
+
+// serveHTTP implements ServeHTTP, for a single dispatch.
+func (b *LBBalancer) serveHTTP(w http.ResponseWriter, req *http.Request) {
 	// Start timing for load balancer overhead
 	lbStart := time.Now()
 
-	if len(b.handlers) == 0 || len(b.status) == 0 {
-		http.Error(w, errNoAvailableServer.Error(), http.StatusServiceUnavailable)
+	b.waitInitialized(req)
+
+	cache, cacheKey := b.cacheOf(req)
+	if cache != nil {
+		if response := cache.get(cacheKey, hasCredentials(req), b.clock.Now()); response != nil {
+			b.logSelection(req, nil, AdmissionCached, time.Since(lbStart))
+			response.write(w)
+			return
+		}
+	}
+
+	if b.empty() {
+		b.logSelection(req, nil, AdmissionRejected, time.Since(lbStart))
+		b.rejections.count(RejectAllDown)
+		b.writeUnavailable(w, req, errNoAvailableServer)
 		return
 	}
-	server, err := b.nextServer()
-	
+
+	bypass := b.bypasses(req)
+
+	if ok, shed := b.acquire(req, bypass); !ok {
+		b.logSelection(req, nil, AdmissionCapped, time.Since(lbStart))
+		if shed {
+			b.rejections.count(RejectShed)
+		} else {
+			b.rejections.count(RejectGlobalCap)
+		}
+		b.writeCapped(w, req, errOverloaded)
+		return
+	}
+	defer b.release()
+
+	if !bypass && !b.allowGlobal() {
+		b.logSelection(req, nil, AdmissionCapped, time.Since(lbStart))
+		b.rejections.count(RejectGlobalRate)
+		b.writeRejectedRateLimitHeaders(w.Header())
+		b.writeCapped(w, req, errGlobalRateLimited)
+		return
+	}
+
+	allowed, started := true, false
+	if !bypass {
+		allowed, started = b.allowStartup()
+	}
+	if !allowed {
+		b.logSelection(req, nil, AdmissionCapped, time.Since(lbStart))
+		b.rejections.count(RejectStartupRamp)
+		b.refundGlobal()
+		b.writeCapped(w, req, errStartupRamp)
+		return
+	}
+
+	aggregated := false
+	if !bypass {
+		allowed, aggregated = b.allowAggregateBurst()
+	}
+	if !allowed {
+		b.logSelection(req, nil, AdmissionCapped, time.Since(lbStart))
+		b.rejections.count(RejectAggregateBurst)
+		b.refundAdmission(bypass, started, false)
+		b.writeCapped(w, req, errAggregateBurst)
+		return
+	}
+
+	target := b.stickyServer(req, false)
+	server, writeCookie := target.server, target.rewrite
+
+	var err error
+	if server == nil {
+		excluded := slices.Concat(target.excluded, excludedServers(req.Context()))
+		sel := selection{excluded: excluded, bypass: bypass, strategy: b.strategy(req)}
+		if server = b.warmServer(sel); server == nil {
+			server, err = b.selectServer(sel)
+		}
+		writeCookie = b.sticky != nil
+
+		if err == nil && target.previous != "" && target.previous != server.name {
+			b.notifyStickyMigration(req, target, server.name)
+		}
+
+		if errors.Is(err, errOverflowLimit) {
+			if fallback := b.overflowLimitFallback(); fallback != nil {
+				b.logSelection(req, nil, AdmissionOverflowFallback, time.Since(lbStart))
+				fallback.ServeHTTP(w, req)
+				return
+			}
+		} else if errors.Is(err, errNoAvailableServer) {
+			fallback, backend := b.allDrainingFallback(sel)
+			if backend != nil {
+				b.logSelection(req, nil, AdmissionDefaultBackend, time.Since(lbStart))
+				backend.ServeHTTP(w, req)
+				return
+			}
+			if fallback != nil {
+				server, err = fallback, nil
+			}
+		}
+	}
+
 	// Measure load balancer duration (without OpenTelemetry overhead)
 	lbDuration := time.Since(lbStart)
-	
+	b.selectionLatency.record(lbDuration)
+
 	if err != nil {
+		b.logSelection(req, nil, AdmissionRejected, lbDuration)
+		b.refundAdmission(bypass, started, aggregated)
+
 		if errors.Is(err, errNoAvailableServer) {
-			http.Error(w, errNoAvailableServer.Error(), http.StatusServiceUnavailable)
+			if errors.Is(err, errOverflowLimit) {
+				b.rejections.count(RejectOverflowLimit)
+			} else {
+				b.rejections.count(b.unavailableReason())
+			}
+			b.writeRejectedRateLimitHeaders(w.Header())
+			b.writeUnavailable(w, req, err)
 		} else {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 
 		}
 		return
 	}
-	
+
 	log.Debug().Msgf("load balancer response time: %d us (server=%s)", lbDuration.Microseconds(), server.name)
 
+	// A request with a body too large for the server is rejected as if it was not admitted.
+	if !b.limitRequestBody(w, req, server) {
+		b.logSelection(req, nil, AdmissionRejected, lbDuration)
+		b.rejections.count(RejectBodyTooLarge)
+		b.refundServer(server, target.server != nil, bypass)
+		b.refundAdmission(bypass, started, aggregated)
+		http.Error(w, http.StatusText(http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	admission := AdmissionSelected
+	switch {
+	case target.server != nil:
+		admission = AdmissionSticky
+	case bypass:
+		admission = AdmissionBypassed
+	}
+	b.logSelection(req, server, admission, lbDuration)
+
+	if b.sticky != nil {
+		b.trackStickySession(req, target.server != nil, target.previous != "" && target.previous != server.name)
+	}
+
+	if writeCookie {
+		b.writeStickyCookie(w, server)
+	}
+
+	b.writeRateLimitHeaders(w.Header(), server)
+
+	// Closing the connection of the clients of a draining server lets them reconnect elsewhere.
+	if b.isDraining(server) {
+		w.Header().Set("Connection", "close")
+	}
+
 	// res := server.bucket.Reserve()
 	// if !res.OK() {
 	// 	http.Error(w, errNoAvailableServer.Error(), http.StatusServiceUnavailable)
 	// 	return
 	// }
 	// b.bucketDelay(server, res.Delay())
-	server.ServeHTTP(w, req)
+	server.served.Add(1)
+	server.lastSelected.Store(b.clock.Now().UnixNano())
+
+	b.mutex.RLock()
+	detectOutliers := b.outlierDetection != nil
+	backpressure := b.backpressure != nil
+	// The latency drives the score ordering, the automatic weighting, and the predicted completion strategy.
+	measureLatency := b.scoreWeights != nil || b.autoWeighting != nil || b.strategySelector != nil
+	b.mutex.RUnlock()
+
+	// The response is recorded for the cache as sent to the client, e.g. not when replaced for being too large.
+	var recorder *cacheRecorder
+	if cache != nil {
+		recorder = &cacheRecorder{ResponseWriter: w, limit: cache.config.MaxBytes}
+		w = recorder
+	}
+
+	rw := &statusRecorder{ResponseWriter: w}
+	var next http.ResponseWriter = rw
+	var limiter *sizeLimiter
+	if limit := server.maxResponseSize.Load(); limit > 0 {
+		limiter = &sizeLimiter{ResponseWriter: rw, limit: limit}
+		next = limiter
+	}
+
+	req = server.injectHeaders(req)
+
+	// The context of the client is only canceled by the client going away, unlike the one with the deadline.
+	client := req.Context()
+
+	req, cancel := b.withDeadline(req, server)
+	defer cancel()
+
+	start := b.clock.Now()
+	server.serve(next, req)
+
+	// A client gone before the end of its response says nothing about the server,
+	// which is not blamed for it, e.g. by the outlier detection, and the partial response is not cached.
+	if client.Err() != nil {
+		server.clientCanceled.Add(1)
+		log.Ctx(client).Debug().Msgf("Client canceled its request to server %s", server.name)
+		return
+	}
+
+	if measureLatency {
+		b.recordLatency(server, b.clock.Now().Sub(start))
+	}
+
+	status := rw.code()
+	server.recordResponse(status)
+	if backpressure {
+		b.recordBackpressure(server, rw.Header())
+	}
+	if detectOutliers {
+		b.recordOutcome(req.Context(), server, status)
+	}
 
+	if recorder != nil && (limiter == nil || !limiter.exceeded) {
+		if status, body, ok := recorder.cached(); ok {
+			cache.put(cacheKey, hasCredentials(req), status, w.Header(), body, b.clock.Now())
+		}
+	}
+
+	if limiter != nil && limiter.exceeded {
+		server.oversized.Add(1)
+		log.Ctx(req.Context()).Warn().Msgf("Response of server %s exceeded the maximum size of %d bytes", server.name, limiter.limit)
+
+		// The headers are already sent, so only closing the connection tells the client that the response is incomplete.
+		if limiter.truncated {
+			panic(http.ErrAbortHandler)
+		}
+	}
+}
+
+// empty reports whether the balancer has no up server.
+func (b *LBBalancer) empty() bool {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	return len(b.handlers) == 0 || len(b.status) == 0
+}
+
+// allow reports whether the bucket of the handler admits a request at now, consuming a token if so.
+// The decision is counted in the stats and the admission history, and recorded in the bucket trace if sampled.
+// It must be called with the balancer mutex held.
+func (h *namedHandler) allow(now time.Time) bool {
+	var allowed bool
+	if h.trace.sampled() {
+		before := h.bucket.TokensAt(now)
+		allowed = h.bucket.AllowN(now, 1)
+		h.trace.record(BucketEvent{Time: now, Server: h.name, Before: before, After: h.bucket.TokensAt(now), Allowed: allowed})
+	} else {
+		allowed = h.bucket.AllowN(now, 1)
+	}
+
+	h.history.record(now, allowed)
+	if !allowed {
+		h.throttled.Add(1)
+	}
+
+	return allowed
+}
+
+// refundAdmission gives back the tokens of the balancer taken by a request which is not dispatched to any server:
+// the global one unless it bypassed the rate limiting, the startup credit if started, and the aggregate one if aggregated.
+func (b *LBBalancer) refundAdmission(bypass, started, aggregated bool) {
+	if !bypass {
+		b.refundGlobal()
+	}
+	if started {
+		b.refundStartup()
+	}
+	if aggregated {
+		b.refundAggregateBurst()
+	}
+}
+
+// refundServer gives back the tokens of server taken by a request which is not dispatched to it:
+// the one of its bucket unless the request bypassed it, and the one of its sticky bucket if the request stuck to it.
+func (b *LBBalancer) refundServer(server *namedHandler, sticky, bypass bool) {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	now := b.clock.Now()
+	if sticky || !bypass {
+		refund(server, now)
+	}
+	if sticky && server.stickyBucket != nil {
+		server.stickyBucket.ReserveN(now, -1)
+	}
+}
+
+// refund gives back to the bucket of h a token it admitted, up to its burst.
+// It must be called with the mutex held.
+func refund(h *namedHandler, now time.Time) {
+	// A reservation of a negative number of tokens adds them to the bucket,
+	// and the bucket is capped to its burst at its next use.
+	h.bucket.ReserveN(now, -1)
+}
+
+// serve dispatches req to the handler, counting it in flight while it is served.
+func (h *namedHandler) serve(rw http.ResponseWriter, req *http.Request) {
+	h.inflight.Add(1)
+	defer h.inflight.Add(-1)
+
+	h.ServeHTTP(rw, req)
+}
+
+// recordResponse counts a response of the handler with the given status code.
+func (h *namedHandler) recordResponse(status int) {
+	if class := status / 100; class >= 1 && class <= len(h.responses) {
+		h.responses[class-1].Add(1)
+	}
 }
 
 // AddServer adds a handler with a server.
+// A fenced server is added as draining, and the headers of the server are set on its requests, see SetServerHeaders.
 func (b *LBBalancer) AddServer(name string, handler http.Handler, server dynamic.Server) {
 	b.Add(name, handler, server.Burst, server.Average, server.Period, server.Priority)
+
+	if len(server.Headers) > 0 {
+		_ = b.SetServerHeaders(name, server.Headers)
+	}
+
+	if server.Fenced {
+		b.SetDraining(name, true)
+	}
 }
 
 // Add adds a handler.
-// A handler with a non-positive values is ignored.
+// A handler with a non-positive values is ignored, and so is a nil handler.
+// An explicit zero average registers the server as disabled instead, if enabled with SetZeroAverageDisabled.
+// A non-positive priority is clamped to 1, unless a zero priority is a last resort, see SetLastResortPriority.
+// An explicit burst is applied as is, a non-positive one being clamped to 1,
+// while a nil burst defaults to the average, i.e. a bucket absorbing one period of traffic at once.
 func (b *LBBalancer) Add(name string, handler http.Handler, burst *int, average *int, period *int, priority *int) {
-	bu := 1
-	if burst != nil {
-		bu = *burst
+	if handler == nil {
+		log.Error().Msgf("Ignoring server %s without handler", name)
+		return
 	}
 
-	if bu <= 1 {
-		bu = 1
+	params, ok := b.resolveServerParams(burst, average, period, priority)
+	if !ok {
+		return
 	}
+	params = b.withLastResort(params, priority)
+
+	b.mutex.Lock()
+	h := b.newHandler(name, handler, params)
+	heap.Push(b, h)
+	b.servers[name] = h
+	if !h.disabled {
+		b.status[name] = struct{}{}
+	}
+	b.updateAggregateBurst()
+	b.mutex.Unlock()
+
+	if b.sticky != nil {
+		b.sticky.AddHandler(name, handler)
+	}
+
+	b.initializedOnce.Do(func() { close(b.initialized) })
+}
 
+// newHandler creates the handler of a server, with a full bucket.
+// It must be called with the mutex held.
+func (b *LBBalancer) newHandler(name string, handler http.Handler, params serverParams) *namedHandler {
+	bucket := rate.NewLimiter(b.scaledLimit(params.limit()), params.burst)
+	canAllow := true
+	h := &namedHandler{Handler: handler, name: name, burst: int64(params.burst), average: int64(params.average), period: params.period(), priority: int64(params.priority), bucket: bucket, canAllow: canAllow}
+	h.disabled = params.disabled
+
+	if b.nonStickyReservation > 0 {
+		h.stickyBucket = newStickyBucket(bucket.Limit(), params.burst, b.nonStickyReservation)
+	}
+	h.history = newAdmissionHistory(b.admissionSeconds)
+	h.trace = b.bucketTrace
+
+	return h
+}
+
+// UpdateServer updates the rate and priority parameters of the named server.
+// The tokens currently available in the bucket of the server are kept, up to the new burst.
+// A disabled server, see SetZeroAverageDisabled, is enabled, as up, with a full bucket.
+func (b *LBBalancer) UpdateServer(name string, server dynamic.Server) error {
+	params, ok := newServerParams(server.Burst, server.Average, server.Period, server.Priority)
+	if !ok {
+		return fmt.Errorf("invalid average for server %s", name)
+	}
+	params = b.withLastResort(params, server.Priority)
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	h, ok := b.servers[name]
+	if !ok {
+		return fmt.Errorf("unknown server %s", name)
+	}
+
+	now := b.clock.Now()
+	limit := b.scaledLimit(params.limit()) * rate.Limit(h.backpressure.rateFactor())
+	h.bucket.SetLimitAt(now, limit)
+	h.bucket.SetBurstAt(now, params.burst)
+	if h.stickyBucket != nil {
+		h.stickyBucket.SetLimitAt(now, stickyLimit(limit, b.nonStickyReservation))
+		h.stickyBucket.SetBurstAt(now, stickyBurst(params.burst, b.nonStickyReservation))
+	}
+
+	h.burst = int64(params.burst)
+	h.average = int64(params.average)
+	h.period = params.period()
+
+	// A disabled server never had any token, and starts with a full bucket as a new server.
+	if h.disabled {
+		h.bucket = rate.NewLimiter(limit, params.burst)
+		if h.stickyBucket != nil {
+			h.stickyBucket = newStickyBucket(limit, params.burst, b.nonStickyReservation)
+		}
+
+		upBefore := len(b.status) > 0
+		h.disabled = false
+		b.status[name] = struct{}{}
+		defer b.propagateStatus(context.Background(), upBefore)
+	}
+
+	b.audit(AuditUpdateServer, map[string]any{
+		"name":     name,
+		"burst":    params.burst,
+		"average":  params.average,
+		"period":   params.period(),
+		"priority": params.priority,
+	})
+
+	// The priority of a scheduled server is the one outside of its windows,
+	// and the one of a boosted server is applied when the boost ends.
+	priority := int64(params.priority)
+	if s, ok := b.schedules[name]; ok {
+		s.base = priority
+		priority = s.priorityAt(now)
+	}
+	b.setPriority(h, priority)
+	b.updateAggregateBurst()
+
+	return nil
+}
+
+// fix re-establishes the heap ordering after the priority of h changed.
+// It must be called with the mutex held.
+func (b *LBBalancer) fix(h *namedHandler) {
+	for i, handler := range b.handlers {
+		if handler == h {
+			heap.Fix(b, i)
+			return
+		}
+	}
+}
+
+// serverParams are the resolved rate and priority parameters of a server.
+type serverParams struct {
+	burst    int
+	average  int
+	periodMs int
+	priority int
+	// disabled is whether the server is registered as disabled, with a zero average and burst.
+	disabled bool
+}
+
+// newServerParams resolves the parameters of a server, applying the defaults.
+// It returns false if the server must be ignored because of a non-positive average.
+func newServerParams(burst *int, average *int, period *int, priority *int) (serverParams, bool) {
 	a := 1
 	if average != nil {
 		a = *average
 	}
 
 	if a <= 0 {
-		return
+		return serverParams{}, false
+	}
+
+	// The default burst only applies to an unset burst, an explicit one being at least 1,
+	// as a bucket without any token would never admit a request.
+	bu := a
+	if burst != nil {
+		bu = max(*burst, 1)
 	}
 
 	p := 1
@@ -278,12 +1088,77 @@ func (b *LBBalancer) Add(name string, handler http.Handler, burst *int, average
 		prio = 1
 	}
 
-	bucket := rate.NewLimiter(rate.Every((time.Millisecond*time.Duration(p))/time.Duration(a)), bu)
-	canAllow := true
-	h := &namedHandler{Handler: handler, name: name, burst: int64(bu), average: int64(a), period: time.Millisecond * time.Duration(p), priority: int64(prio), bucket: bucket, canAllow: canAllow}
+	return serverParams{burst: bu, average: a, periodMs: p, priority: prio}, true
+}
+
+func (p serverParams) period() time.Duration {
+	return time.Millisecond * time.Duration(p.periodMs)
+}
+
+// limit returns the bucket rate, i.e. average tokens per period, zero for a disabled server.
+func (p serverParams) limit() rate.Limit {
+	if p.average == 0 {
+		return 0
+	}
+
+	return rate.Every(p.period() / time.Duration(p.average))
+}
+
+// RemoveServer removes the named server from the balancer.
+// It returns false if no such server exists.
+func (b *LBBalancer) RemoveServer(ctx context.Context, name string) bool {
+	// The child is detached after the mutex is released, see detachChildren.
+	detached := make(map[string]*LBBalancer, 1)
+	defer func() { b.detachChildren(detached) }()
 
 	b.mutex.Lock()
-	heap.Push(b, h)
-	b.status[name] = struct{}{}
-	b.mutex.Unlock()
+	defer b.mutex.Unlock()
+
+	for i, h := range b.handlers {
+		if h.name != name {
+			continue
+		}
+
+		upBefore := len(b.status) > 0
+
+		heap.Remove(b, i)
+		delete(b.servers, name)
+		if child, ok := b.children[name]; ok {
+			detached[name] = child
+			delete(b.children, name)
+		}
+		delete(b.status, name)
+		delete(b.serverAvailability, name)
+		delete(b.draining, name)
+		if bst, ok := b.boosts[name]; ok {
+			bst.stop()
+			delete(b.boosts, name)
+		}
+		if s, ok := b.schedules[name]; ok {
+			s.stop()
+			delete(b.schedules, name)
+		}
+
+		log.Ctx(ctx).Debug().Msgf("Removed server %s", name)
+
+		b.audit(AuditRemoveServer, map[string]any{"name": name})
+
+		b.updateAggregateBurst()
+
+		b.propagateStatus(ctx, upBefore)
+		return true
+	}
+
+	return false
+}
+
+// dispatchable reports whether the handler can serve requests.
+// A handler without a http.Handler is a bug, and it is treated as down rather than panicking on the request path.
+func (h *namedHandler) dispatchable() bool {
+	if h.Handler == nil {
+		log.Error().Msgf("Server %s has no handler, treating it as down", h.name)
+		return false
+	}
+
+	return true
 }
@@ -62,3 +62,78 @@ func BenchmarkNextServer(b *testing.B) {
 		})
 	}
 }
+
+func BenchmarkNextServerScanLimit(b *testing.B) {
+	const scanLimit = 16
+
+	for _, bucketCount := range []int{128, 1024, 8192} {
+		b.Run(fmt.Sprintf("buckets_%d", bucketCount), func(b *testing.B) {
+			balancer := New(nil, false)
+
+			// Empty buckets which do not refill during the benchmark: every selection overflows up to the limit.
+			burst, average, period, priority := 1, 1, 3600000, 1
+			handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+			for i := 0; i < bucketCount; i++ {
+				balancer.Add(fmt.Sprintf("srv-%d", i), handler, &burst, &average, &period, &priority)
+			}
+			for i := 0; i < bucketCount; i++ {
+				_, _ = balancer.nextServer()
+			}
+			balancer.SetScanLimit(scanLimit)
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := balancer.nextServer(); err == nil {
+					b.Fatal("unexpected admission")
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkWarmServer(b *testing.B) {
+	const poolSize = 64
+
+	for _, warm := range []bool{false, true} {
+		b.Run(fmt.Sprintf("warm_%t", warm), func(b *testing.B) {
+			balancer := New(nil, false)
+
+			// The servers of the highest priorities are exhausted, so that a live selection scans them all.
+			handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+			burst, average, period := 1, 1, 3600000
+			for i := range 32 {
+				priority := i + 1
+				balancer.Add(fmt.Sprintf("srv-%d", i), handler, &burst, &average, &period, &priority)
+				_, _ = balancer.nextServer()
+			}
+			unlimited, last := 1000000, 100
+			balancer.Add("unlimited", handler, &unlimited, &unlimited, &period, &last)
+
+			if warm {
+				balancer.SetWarmPool(poolSize, time.Hour)
+				defer balancer.Close()
+			}
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if warm && i%poolSize == 0 {
+					b.StopTimer()
+					balancer.mutex.Lock()
+					balancer.refillWarmPool(balancer.warmPool)
+					balancer.mutex.Unlock()
+					b.StartTimer()
+				}
+
+				sel := selection{strategy: StrategyPriority}
+				if balancer.warmServer(sel) != nil {
+					continue
+				}
+				if _, err := balancer.selectServer(sel); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -2,8 +2,11 @@ package lblb
 
 import (
 	"context"
+	"maps"
 	"net/http"
 	"net/http/httptest"
+	"slices"
+	"sync"
 	"testing"
 	"time"
 
@@ -256,6 +259,45 @@ func TestLBBalancerAllServersZeroWeight(t *testing.T) {
 	assert.Equal(t, http.StatusServiceUnavailable, recorder.Result().StatusCode)
 }
 
+func TestLBBalancerZeroAverageDisabled(t *testing.T) {
+	balancer := New(nil, false)
+	balancer.clock = newFakeClock()
+
+	handler := func(name string) http.Handler {
+		return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			rw.Header().Set("server", name)
+			rw.WriteHeader(http.StatusOK)
+		})
+	}
+
+	// Without SetZeroAverageDisabled, an explicit zero average is ignored.
+	balancer.Add("ignored", handler("ignored"), Int(10), Int(0), Int(1000), Int(1))
+	balancer.SetZeroAverageDisabled(true)
+	balancer.Add("disabled", handler("disabled"), Int(10), Int(0), Int(1000), Int(1))
+	balancer.Add("negative", handler("negative"), Int(10), Int(-1), Int(1000), Int(1))
+
+	// A balancer with only disabled servers is not up.
+	assert.True(t, balancer.empty())
+	balancer.SetStatus(context.Background(), "disabled", true)
+	assert.True(t, balancer.empty())
+
+	// A nil average defaults to 1, and so does the burst.
+	balancer.Add("default", handler("default"), nil, nil, Int(1000), Int(2))
+
+	assert.Equal(t, "default", serveOne(balancer))
+	assert.Empty(t, serveOne(balancer))
+
+	stats := balancer.Stats().Servers
+	assert.Equal(t, []string{"default", "disabled"}, slices.Sorted(maps.Keys(stats)))
+	assert.Equal(t, ServerStats{Disabled: true}, stats["disabled"])
+	assert.True(t, stats["default"].Up)
+
+	// The disabled server is enabled by an update.
+	assert.NoError(t, balancer.UpdateServer("disabled", dynamic.Server{Burst: Int(10), Average: Int(10), Period: Int(1000), Priority: Int(1)}))
+	assert.Equal(t, "disabled", serveOne(balancer))
+	assert.True(t, balancer.Stats().Servers["disabled"].Up)
+}
+
 func TestSticky(t *testing.T) {
 	balancer := New(&dynamic.Sticky{
 		Cookie: &dynamic.Cookie{Name: "test"},
@@ -328,3 +370,145 @@ func (r *responseRecorder) WriteHeader(statusCode int) {
 	r.status = append(r.status, statusCode)
 	r.ResponseRecorder.WriteHeader(statusCode)
 }
+
+// fakeClock is a manually advanced clock, to be injected as the balancer clock.
+type fakeClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers []*fakeTimer
+}
+
+type fakeTimer struct {
+	at      time.Time
+	f       func()
+	stopped bool
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Now()}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.now
+}
+
+func (c *fakeClock) AfterFunc(d time.Duration, f func()) func() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	timer := &fakeTimer{at: c.now.Add(d), f: f}
+	c.timers = append(c.timers, timer)
+
+	return func() bool {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+
+		stopped := timer.stopped
+		timer.stopped = true
+		return !stopped
+	}
+}
+
+// Advance moves the clock forward, synchronously firing the timers which are due, in order.
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	end := c.now.Add(d)
+	c.mu.Unlock()
+
+	for {
+		c.mu.Lock()
+		var next *fakeTimer
+		for _, timer := range c.timers {
+			if !timer.stopped && !timer.at.After(end) && (next == nil || timer.at.Before(next.at)) {
+				next = timer
+			}
+		}
+
+		if next == nil {
+			c.now = end
+			c.mu.Unlock()
+			return
+		}
+
+		next.stopped = true
+		c.now = next.at
+		c.mu.Unlock()
+
+		next.f()
+	}
+}
+
+func TestLBBalancerBurst(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		burst    *int
+		expected int
+	}{
+		{desc: "explicit burst of 1", burst: Int(1), expected: 1},
+		{desc: "explicit burst", burst: Int(3), expected: 3},
+		{desc: "non-positive burst", burst: Int(0), expected: 1},
+		{desc: "nil burst defaults to the average", expected: 5},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			clock := newFakeClock()
+
+			balancer := New(nil, false)
+			balancer.clock = clock
+			balancer.Add("first", http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+				rw.WriteHeader(http.StatusOK)
+			}), test.burst, Int(5), Int(3600000), Int(1))
+
+			first := balancer.servers["first"]
+			assert.Equal(t, test.expected, first.bucket.Burst())
+
+			// Exactly the burst is admitted at once.
+			for range 10 {
+				balancer.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+			}
+			assert.Equal(t, uint64(test.expected), first.served.Load())
+		})
+	}
+}
+
+func TestLBBalancerClose(t *testing.T) {
+	clock := newFakeClock()
+	balancer := New(nil, false)
+	balancer.clock = clock
+
+	handler := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusOK)
+	})
+	balancer.Add("first", handler, Int(10), Int(10), Int(1000), Int(1))
+	balancer.Add("second", handler, Int(10), Int(10), Int(1000), Int(2))
+
+	balancer.SetCapacityCheck(context.Background(), time.Second)
+	balancer.SetAutoWeighting(context.Background(), &AutoWeighting{Interval: time.Second, LearningRate: 0.5, MinAverage: 1})
+	balancer.SetWarmPool(2, time.Second)
+	assert.NoError(t, balancer.BoostPriority("second", 1, time.Minute))
+	assert.NoError(t, balancer.ScaleAllRates(2, time.Minute))
+	assert.NoError(t, balancer.SetPrioritySchedule("first", []PriorityWindow{{Start: time.Hour, End: 2 * time.Hour, Priority: 3}}, time.UTC))
+
+	balancer.Close()
+
+	// No timer fires after Close.
+	clock.mu.Lock()
+	for _, timer := range clock.timers {
+		timer.f = func() { t.Error("timer fired after Close") }
+	}
+	clock.mu.Unlock()
+	clock.Advance(24 * time.Hour)
+
+	// The rate scaling in progress is ended.
+	balancer.mutex.RLock()
+	defer balancer.mutex.RUnlock()
+
+	assert.InDelta(t, 10, float64(balancer.servers["first"].bucket.Limit()), 0.01)
+	assert.InDelta(t, 10, float64(balancer.servers["second"].bucket.Limit()), 0.01)
+}
@@ -0,0 +1,55 @@
+package lblb
+
+import "net/http"
+
+// StickyMigration is the remapping of a sticky session from a server to another,
+// e.g. because the server it was sticking to went down.
+type StickyMigration struct {
+	// Key is the value of the sticky cookie identifying the session before the migration.
+	Key  string `json:"key"`
+	From string `json:"from"`
+	To   string `json:"to"`
+	// Removed is whether the server the session was sticking to was removed from the balancer,
+	// in which case the client got a sticky cookie for the new server.
+	Removed bool `json:"removed,omitempty"`
+}
+
+// SetStickyMigrationHandler sets the hook called when a sticky session is remapped to another server,
+// e.g. to invalidate the session affinity cached by an external system.
+// It is called in its own goroutine, so that it does not block the request path.
+// A nil fn disables the notifications, which is the default.
+func (b *LBBalancer) SetStickyMigrationHandler(fn func(StickyMigration)) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.stickyMigration = fn
+}
+
+// SetStickyMigrationOnRemoval sets whether the migrations of the sessions sticking to a server removed with RemoveServer
+// or SetServers are notified to the sticky migration handler, which is the default.
+// Either way, such a session is served by another server, and the client gets a sticky cookie for it.
+func (b *LBBalancer) SetStickyMigrationOnRemoval(notify bool) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.silentRemovalMigrations = !notify
+}
+
+// notifyStickyMigration notifies the remapping of the sticky session of req from the server of target to the server to.
+func (b *LBBalancer) notifyStickyMigration(req *http.Request, target stickyTarget, to string) {
+	b.mutex.RLock()
+	fn := b.stickyMigration
+	silent := target.removed && b.silentRemovalMigrations
+	b.mutex.RUnlock()
+
+	if fn == nil || silent {
+		return
+	}
+
+	migration := StickyMigration{From: target.previous, To: to, Removed: target.removed}
+	if cookie, err := req.Cookie(b.sticky.CookieName()); err == nil {
+		migration.Key = cookie.Value
+	}
+
+	go fn(migration)
+}
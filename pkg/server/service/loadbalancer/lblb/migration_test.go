@@ -0,0 +1,138 @@
+package lblb
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/traefik/traefik/v3/pkg/config/dynamic"
+)
+
+func TestLBBalancerStickyMigration(t *testing.T) {
+	balancer := New(&dynamic.Sticky{Cookie: &dynamic.Cookie{Name: "test"}}, false)
+
+	for i, name := range []string{"first", "second"} {
+		balancer.Add(name, http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			rw.Header().Set("server", name)
+			rw.WriteHeader(http.StatusOK)
+		}), Int(10), Int(10), Int(1000), Int(i+1))
+	}
+
+	migrations := make(chan StickyMigration, 1)
+	balancer.SetStickyMigrationHandler(func(m StickyMigration) {
+		migrations <- m
+	})
+
+	recorder := httptest.NewRecorder()
+	balancer.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, "first", recorder.Header().Get("server"))
+
+	cookies := recorder.Result().Cookies()
+	require.Len(t, cookies, 1)
+
+	serve := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.AddCookie(cookies[0])
+
+		recorder := httptest.NewRecorder()
+		balancer.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	// Sticking to first does not migrate the session.
+	assert.Equal(t, "first", serve().Header().Get("server"))
+
+	// first goes down, and the session fails over to second.
+	balancer.SetStatus(context.Background(), "first", false)
+	assert.Equal(t, "second", serve().Header().Get("server"))
+
+	select {
+	case m := <-migrations:
+		assert.Equal(t, StickyMigration{Key: cookies[0].Value, From: "first", To: "second"}, m)
+	case <-time.After(5 * time.Second):
+		t.Fatal("no migration notified")
+	}
+
+	select {
+	case m := <-migrations:
+		t.Fatalf("unexpected migration %+v", m)
+	default:
+	}
+}
+
+func TestLBBalancerStickyCookieOfRemovedServer(t *testing.T) {
+	testCases := []struct {
+		desc   string
+		notify bool
+	}{
+		{desc: "notified", notify: true},
+		{desc: "not notified"},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			balancer := New(&dynamic.Sticky{Cookie: &dynamic.Cookie{Name: "test"}}, false)
+			for i, name := range []string{"first", "second"} {
+				balancer.Add(name, http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+					rw.Header().Set("server", name)
+					rw.WriteHeader(http.StatusOK)
+				}), Int(10), Int(10), Int(1000), Int(i+1))
+			}
+
+			migrations := make(chan StickyMigration, 1)
+			balancer.SetStickyMigrationHandler(func(m StickyMigration) {
+				migrations <- m
+			})
+			balancer.SetStickyMigrationOnRemoval(test.notify)
+
+			recorder := httptest.NewRecorder()
+			balancer.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+			cookies := recorder.Result().Cookies()
+			require.Len(t, cookies, 1)
+
+			require.True(t, balancer.RemoveServer(context.Background(), "first"))
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.AddCookie(cookies[0])
+			recorder = httptest.NewRecorder()
+			balancer.ServeHTTP(recorder, req)
+
+			assert.Equal(t, http.StatusOK, recorder.Code)
+			assert.Equal(t, "second", recorder.Header().Get("server"))
+
+			// The client gets a cookie for second right away, which it then sticks to.
+			replaced := recorder.Result().Cookies()
+			require.Len(t, replaced, 1)
+			assert.NotEqual(t, cookies[0].Value, replaced[0].Value)
+
+			req = httptest.NewRequest(http.MethodGet, "/", nil)
+			req.AddCookie(replaced[0])
+			recorder = httptest.NewRecorder()
+			balancer.ServeHTTP(recorder, req)
+			assert.Equal(t, "second", recorder.Header().Get("server"))
+			assert.Empty(t, recorder.Result().Cookies())
+
+			if !test.notify {
+				select {
+				case m := <-migrations:
+					t.Fatalf("unexpected migration %+v", m)
+				case <-time.After(10 * time.Millisecond):
+				}
+				return
+			}
+
+			select {
+			case m := <-migrations:
+				assert.Equal(t, StickyMigration{Key: cookies[0].Value, From: "first", To: "second", Removed: true}, m)
+			case <-time.After(5 * time.Second):
+				t.Fatal("no migration notified")
+			}
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package lblb
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/traefik/traefik/v3/pkg/config/dynamic"
+)
+
+func TestLBBalancerNilHandler(t *testing.T) {
+	balancer := New(nil, false)
+
+	balancer.Add("nil", nil, Int(1), Int(1), Int(1), Int(1))
+	balancer.AddServer("nilServer", nil, dynamic.Server{})
+	assert.Error(t, balancer.AddChild("nilChild", nil, dynamic.Server{}))
+	assert.Empty(t, serverNames(balancer))
+
+	recorder := httptest.NewRecorder()
+	balancer.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+}
+
+func TestLBBalancerNilHandlerAtRuntime(t *testing.T) {
+	balancer := New(nil, false)
+
+	for i, name := range []string{"first", "second"} {
+		balancer.Add(name, http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			rw.Header().Set("server", name)
+			rw.WriteHeader(http.StatusOK)
+		}), Int(10), Int(10), Int(1000), Int(i+1))
+	}
+
+	// Simulates a caller bug leaving the highest priority server without handler.
+	balancer.servers["first"].Handler = nil
+
+	var decisions []Decision
+	balancer.SetDecisionTracer(func(d Decision) {
+		decisions = append(decisions, d)
+	})
+
+	for range 3 {
+		assert.Equal(t, "second", serveOne(balancer))
+	}
+
+	assert.Len(t, decisions, 3)
+	assert.Equal(t, Candidate{Name: "first", Priority: 1, Skipped: skipDown}, decisions[0].Candidates[0])
+	// No token of the server without handler is consumed.
+	assert.InDelta(t, 10, balancer.servers["first"].bucket.Tokens(), 1)
+}
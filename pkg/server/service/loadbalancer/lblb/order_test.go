@@ -0,0 +1,75 @@
+package lblb
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/traefik/traefik/v3/pkg/config/dynamic"
+)
+
+func TestLBBalancerDownServerKeepsTokens(t *testing.T) {
+	clock := newFakeClock()
+
+	balancer := New(nil, false)
+	balancer.clock = clock
+
+	handler := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusOK)
+	})
+
+	balancer.Add("down", handler, Int(3), Int(1), Int(1000), Int(1))
+	balancer.Add("draining", handler, Int(3), Int(1), Int(1000), Int(2))
+	balancer.Add("up", handler, Int(3), Int(1), Int(1000), Int(3))
+
+	balancer.SetStatus(context.Background(), "down", false)
+	balancer.SetDraining("draining", true)
+
+	for range 3 {
+		balancer.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	}
+
+	assert.InDelta(t, 3, balancer.servers["down"].bucket.TokensAt(clock.Now()), 0)
+	assert.InDelta(t, 3, balancer.servers["draining"].bucket.TokensAt(clock.Now()), 0)
+	assert.InDelta(t, 0, balancer.servers["up"].bucket.TokensAt(clock.Now()), 0)
+
+	// Once back up, the server has its whole burst available.
+	balancer.SetStatus(context.Background(), "down", true)
+
+	recorder := httptest.NewRecorder()
+	for range 3 {
+		balancer.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+	}
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, uint64(3), balancer.Stats().Servers["down"].Served)
+}
+
+func TestLBBalancerDownStickyServerKeepsTokens(t *testing.T) {
+	clock := newFakeClock()
+
+	balancer := New(&dynamic.Sticky{Cookie: &dynamic.Cookie{Name: "test"}}, false)
+	balancer.clock = clock
+
+	handler := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusOK)
+	})
+
+	balancer.Add("first", handler, Int(3), Int(1), Int(1000), Int(1))
+	balancer.Add("second", handler, Int(3), Int(1), Int(1000), Int(2))
+
+	recorder := httptest.NewRecorder()
+	balancer.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	balancer.SetStatus(context.Background(), "first", false)
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	for _, cookie := range recorder.Result().Cookies() {
+		req.AddCookie(cookie)
+	}
+	balancer.ServeHTTP(httptest.NewRecorder(), req)
+
+	assert.InDelta(t, 2, balancer.servers["first"].bucket.TokensAt(clock.Now()), 0)
+	assert.InDelta(t, 2, balancer.servers["second"].bucket.TokensAt(clock.Now()), 0)
+}
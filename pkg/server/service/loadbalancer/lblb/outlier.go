@@ -0,0 +1,276 @@
+package lblb
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// OutlierDetection configures the ejection of the servers having a high error rate.
+// A response with a 5xx status code is counted as an error.
+type OutlierDetection struct {
+	// Window is the duration over which the error rate of a server is computed.
+	Window time.Duration
+	// MinRequests is the minimum number of requests in a window for the error rate to be evaluated.
+	MinRequests int
+	// MaxErrorRate is the error rate, between 0 and 1, above which a server is ejected.
+	MaxErrorRate float64
+	// EjectionTime is the duration during which an ejected server does not receive any traffic.
+	EjectionTime time.Duration
+	// MaxEjectionTime, when greater than EjectionTime, enables the exponential backoff of the ejections:
+	// the ejection time doubles with every consecutive ejection of a server, up to MaxEjectionTime.
+	// The ejections are consecutive when the server is ejected again within the window following its re-admission,
+	// or when its half-open probe fails.
+	MaxEjectionTime time.Duration
+	// HalfOpen enables the half-open re-admission of the ejected servers:
+	// once its ejection is over, a server only receives a single probe request,
+	// and is fully re-admitted if the probe succeeds, or ejected again if it fails.
+	// A probe which got no response within EjectionTime, e.g. because it was denied by the bucket, is replaced.
+	HalfOpen bool
+}
+
+// ejectionTime returns the duration of the given consecutive ejection of a server, starting at 1.
+func (o *OutlierDetection) ejectionTime(consecutive int) time.Duration {
+	if o.MaxEjectionTime <= o.EjectionTime {
+		return o.EjectionTime
+	}
+
+	d := o.EjectionTime
+	for range consecutive - 1 {
+		d *= 2
+		if d >= o.MaxEjectionTime {
+			return o.MaxEjectionTime
+		}
+	}
+
+	return d
+}
+
+// SetDefaults sets the default values.
+func (o *OutlierDetection) SetDefaults() {
+	o.Window = 10 * time.Second
+	o.MinRequests = 10
+	o.MaxErrorRate = 0.5
+	o.EjectionTime = 30 * time.Second
+}
+
+type outlierState struct {
+	windowStart time.Time
+	requests    int
+	errors      int
+
+	// ejected is whether the server is currently ejected.
+	ejected      bool
+	ejections    uint64
+	readmissions uint64
+	// consecutive is the number of consecutive ejections of the server, driving the backoff.
+	consecutive int
+	// halfOpen is whether the ejected server is being probed, since probeAt.
+	halfOpen bool
+	probeAt  time.Time
+}
+
+// SetOutlierDetection enables the outlier detection with the given configuration,
+// or disables it if config is nil.
+func (b *LBBalancer) SetOutlierDetection(config *OutlierDetection) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.outlierDetection = config
+}
+
+// available reports whether the server is not in cooldown at the given time,
+// and re-admits it if its cooldown is over, or lets it receive a probe if it is half-open.
+// It must be called with the mutex held.
+func (b *LBBalancer) available(h *namedHandler, now time.Time) bool {
+	until, ok := b.serverAvailability[h.name]
+	if ok {
+		if now.Before(until) {
+			return false
+		}
+
+		delete(b.serverAvailability, h.name)
+
+		if !h.outlier.ejected {
+			return true
+		}
+
+		if config := b.outlierDetection; config != nil && config.HalfOpen {
+			h.outlier.halfOpen = true
+			h.outlier.probeAt = now
+
+			log.Info().Msgf("Server %s half-open after ejection, probing", h.name)
+			return true
+		}
+
+		h.outlier.readmit(now)
+		log.Info().Msgf("Server %s re-admitted after ejection", h.name)
+		return true
+	}
+
+	if !h.outlier.halfOpen {
+		return true
+	}
+
+	// A half-open server only receives another probe once the previous one is overdue,
+	// or is fully re-admitted if the half-open re-admission got disabled.
+	config := b.outlierDetection
+	if config == nil || !config.HalfOpen {
+		h.outlier.readmit(now)
+		return true
+	}
+
+	if now.Sub(h.outlier.probeAt) < config.EjectionTime {
+		return false
+	}
+
+	h.outlier.probeAt = now
+	return true
+}
+
+// readmit fully re-admits an ejected server.
+func (o *outlierState) readmit(now time.Time) {
+	o.ejected = false
+	o.halfOpen = false
+	o.readmissions++
+	o.windowStart = now
+	o.requests = 0
+	o.errors = 0
+}
+
+// eject ejects h, for a duration depending on its consecutive ejections.
+// It must be called with the mutex held.
+func (b *LBBalancer) eject(ctx context.Context, h *namedHandler, config *OutlierDetection, now time.Time) {
+	o := &h.outlier
+	o.ejected = true
+	o.halfOpen = false
+	o.ejections++
+	o.consecutive++
+
+	ejectionTime := config.ejectionTime(o.consecutive)
+	b.serverAvailability[h.name] = now.Add(ejectionTime)
+
+	log.Ctx(ctx).Warn().Msgf("Server %s ejected for %s: %d errors out of %d requests", h.name, ejectionTime, o.errors, o.requests)
+}
+
+// recordOutcome accounts the response status of a request served by h,
+// and ejects h if its error rate exceeds the configured one.
+func (b *LBBalancer) recordOutcome(ctx context.Context, h *namedHandler, status int) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	config := b.outlierDetection
+	if config == nil {
+		return
+	}
+
+	now := b.clock.Now()
+
+	o := &h.outlier
+	if o.halfOpen {
+		b.resolveProbe(ctx, h, config, status, now)
+		return
+	}
+
+	if o.ejected {
+		return
+	}
+
+	if now.Sub(o.windowStart) >= config.Window {
+		// A whole window without ejection ends the consecutive ejections.
+		if o.requests > 0 {
+			o.consecutive = 0
+		}
+
+		o.windowStart = now
+		o.requests = 0
+		o.errors = 0
+	}
+
+	o.requests++
+	if status >= http.StatusInternalServerError {
+		o.errors++
+	}
+
+	if o.requests < config.MinRequests || float64(o.errors)/float64(o.requests) <= config.MaxErrorRate {
+		return
+	}
+
+	// The last available server is never ejected, as there would be no one left to take over its traffic.
+	if !b.hasAvailablePeer(h, now) {
+		log.Ctx(ctx).Debug().Msgf("Not ejecting server %s: no other server available", h.name)
+		return
+	}
+
+	b.eject(ctx, h, config, now)
+}
+
+// resolveProbe fully re-admits the half-open h if the status of its probe is not an error,
+// or ejects it again with an increased backoff otherwise.
+// It must be called with the mutex held.
+func (b *LBBalancer) resolveProbe(ctx context.Context, h *namedHandler, config *OutlierDetection, status int, now time.Time) {
+	o := &h.outlier
+	if status < http.StatusInternalServerError {
+		o.consecutive = 0
+		o.readmit(now)
+
+		log.Ctx(ctx).Info().Msgf("Server %s re-admitted after a successful probe", h.name)
+		return
+	}
+
+	o.requests = 1
+	o.errors = 1
+
+	// As for the ejections, the last available server is never ejected again.
+	if !b.hasAvailablePeer(h, now) {
+		o.readmit(now)
+
+		log.Ctx(ctx).Debug().Msgf("Re-admitting server %s after a failed probe: no other server available", h.name)
+		return
+	}
+
+	b.eject(ctx, h, config, now)
+}
+
+// hasAvailablePeer reports whether another server than h is up and not in cooldown.
+// It must be called with the mutex held.
+func (b *LBBalancer) hasAvailablePeer(h *namedHandler, now time.Time) bool {
+	for _, peer := range b.handlers {
+		if peer == h {
+			continue
+		}
+
+		if _, up := b.status[peer.name]; up && !b.coolingDown(peer.name, now) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// coolingDown reports whether the named server is in cooldown at the given time.
+// Unlike available, it does not re-admit the server, so it only requires the read lock.
+func (b *LBBalancer) coolingDown(name string, now time.Time) bool {
+	until, ok := b.serverAvailability[name]
+	return ok && now.Before(until)
+}
+
+// CooldownSnapshot returns the servers currently in cooldown, e.g. ejected by the outlier detection,
+// along with the end of their cooldown, to explain why they do not receive any traffic.
+func (b *LBBalancer) CooldownSnapshot() map[string]time.Time {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	now := b.clock.Now()
+
+	cooldowns := make(map[string]time.Time)
+	for name, until := range b.serverAvailability {
+		if now.Before(until) {
+			cooldowns[name] = until
+		}
+	}
+
+	return cooldowns
+}
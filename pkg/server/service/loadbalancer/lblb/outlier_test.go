@@ -0,0 +1,274 @@
+package lblb
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLBBalancerOutlierDetection(t *testing.T) {
+	clock := newFakeClock()
+
+	balancer := New(nil, false)
+	balancer.clock = clock
+	balancer.SetOutlierDetection(&OutlierDetection{
+		Window:       10 * time.Second,
+		MinRequests:  4,
+		MaxErrorRate: 0.5,
+		EjectionTime: 30 * time.Second,
+	})
+
+	balancer.Add("first", http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Header().Set("server", "first")
+		rw.WriteHeader(http.StatusBadGateway)
+	}), Int(100), Int(100), Int(1), Int(1))
+
+	balancer.Add("second", http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Header().Set("server", "second")
+		rw.WriteHeader(http.StatusOK)
+	}), Int(100), Int(100), Int(1), Int(2))
+
+	recorder := &responseRecorder{ResponseRecorder: httptest.NewRecorder(), save: map[string]int{}}
+	for range 6 {
+		balancer.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+	}
+
+	assert.Equal(t, 4, recorder.save["first"])
+	assert.Equal(t, 2, recorder.save["second"])
+
+	stats := balancer.Stats()
+	assert.Equal(t, uint64(1), stats.Servers["first"].Ejections)
+	assert.Equal(t, clock.Now().Add(30*time.Second), stats.Servers["first"].EjectedUntil)
+
+	// Still ejected right before the end of the ejection time.
+	clock.Advance(30*time.Second - time.Millisecond)
+	recorder = &responseRecorder{ResponseRecorder: httptest.NewRecorder(), save: map[string]int{}}
+	balancer.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, 1, recorder.save["second"])
+
+	clock.Advance(time.Millisecond)
+	recorder = &responseRecorder{ResponseRecorder: httptest.NewRecorder(), save: map[string]int{}}
+	balancer.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, 1, recorder.save["first"])
+
+	stats = balancer.Stats()
+	assert.Equal(t, uint64(1), stats.Servers["first"].Readmissions)
+	assert.True(t, stats.Servers["first"].EjectedUntil.IsZero())
+	assert.Equal(t, uint64(5), stats.Servers["first"].Served)
+	assert.Equal(t, uint64(3), stats.Servers["second"].Served)
+}
+
+func TestLBBalancerOutlierDetectionLastServer(t *testing.T) {
+	balancer := New(nil, false)
+	balancer.SetOutlierDetection(&OutlierDetection{
+		Window:       10 * time.Second,
+		MinRequests:  1,
+		MaxErrorRate: 0.5,
+		EjectionTime: 30 * time.Second,
+	})
+
+	balancer.Add("first", http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusInternalServerError)
+	}), Int(100), Int(100), Int(1), Int(1))
+
+	balancer.Add("second", http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusInternalServerError)
+	}), Int(100), Int(100), Int(1), Int(2))
+
+	for range 4 {
+		balancer.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	}
+
+	// The first server is ejected, but the second one is kept as it is the last one available.
+	stats := balancer.Stats()
+	assert.Equal(t, uint64(1), stats.Servers["first"].Ejections)
+	assert.Equal(t, uint64(0), stats.Servers["second"].Ejections)
+	assert.Equal(t, uint64(3), stats.Servers["second"].Served)
+}
+
+func TestLBBalancerCooldownSnapshot(t *testing.T) {
+	clock := newFakeClock()
+
+	balancer := New(nil, false)
+	balancer.clock = clock
+	balancer.SetOutlierDetection(&OutlierDetection{
+		Window:       10 * time.Second,
+		MinRequests:  2,
+		MaxErrorRate: 0.5,
+		EjectionTime: 30 * time.Second,
+	})
+
+	for i, name := range []string{"first", "second", "third"} {
+		status := http.StatusBadGateway
+		if name == "third" {
+			status = http.StatusOK
+		}
+		balancer.Add(name, http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			rw.WriteHeader(status)
+		}), Int(2), Int(2), Int(1000), Int(i+1))
+	}
+
+	assert.Empty(t, balancer.CooldownSnapshot())
+
+	// first is ejected, then second 10 seconds later.
+	firstEjection := clock.Now()
+	for range 2 {
+		balancer.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	}
+	clock.Advance(10 * time.Second)
+	secondEjection := clock.Now()
+	for range 2 {
+		balancer.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	}
+
+	assert.Equal(t, map[string]time.Time{
+		"first":  firstEjection.Add(30 * time.Second),
+		"second": secondEjection.Add(30 * time.Second),
+	}, balancer.CooldownSnapshot())
+
+	// The cooldown of first is over, even though it was not re-admitted by a selection yet.
+	clock.Advance(20 * time.Second)
+	assert.Equal(t, map[string]time.Time{
+		"second": secondEjection.Add(30 * time.Second),
+	}, balancer.CooldownSnapshot())
+
+	clock.Advance(10 * time.Second)
+	assert.Empty(t, balancer.CooldownSnapshot())
+}
+
+func TestLBBalancerOutlierDetectionBackoff(t *testing.T) {
+	clock := newFakeClock()
+
+	balancer := New(nil, false)
+	balancer.clock = clock
+	balancer.SetOutlierDetection(&OutlierDetection{
+		Window:          10 * time.Second,
+		MinRequests:     1,
+		MaxErrorRate:    0,
+		EjectionTime:    time.Second,
+		MaxEjectionTime: 4 * time.Second,
+		HalfOpen:        true,
+	})
+
+	var healthy atomic.Bool
+	release := make(chan struct{})
+	balancer.Add("first", http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Header().Set("server", "first")
+		if !healthy.Load() {
+			rw.WriteHeader(http.StatusBadGateway)
+			return
+		}
+
+		<-release
+		rw.WriteHeader(http.StatusOK)
+	}), Int(100), Int(100), Int(1), Int(1))
+
+	balancer.Add("second", http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Header().Set("server", "second")
+		rw.WriteHeader(http.StatusOK)
+	}), Int(100), Int(100), Int(1), Int(2))
+
+	balancer.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, clock.Now().Add(time.Second), balancer.Stats().Servers["first"].EjectedUntil)
+
+	// Every failed probe ejects the server again, for twice as long, up to the maximum ejection time.
+	for _, ejectionTime := range []time.Duration{2 * time.Second, 4 * time.Second, 4 * time.Second} {
+		clock.Advance(balancer.Stats().Servers["first"].EjectedUntil.Sub(clock.Now()))
+
+		recorder := &responseRecorder{ResponseRecorder: httptest.NewRecorder(), save: map[string]int{}}
+		balancer.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+		assert.Equal(t, 1, recorder.save["first"])
+
+		stats := balancer.Stats().Servers["first"]
+		assert.Equal(t, clock.Now().Add(ejectionTime), stats.EjectedUntil)
+		assert.False(t, stats.HalfOpen)
+	}
+
+	healthy.Store(true)
+	clock.Advance(4 * time.Second)
+
+	// A single probe is sent to the half-open server, while the other requests go to the next one.
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		balancer.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	}()
+	require.Eventually(t, func() bool {
+		return balancer.servers["first"].inflight.Load() == 1
+	}, time.Second, time.Millisecond)
+	assert.True(t, balancer.Stats().Servers["first"].HalfOpen)
+
+	recorder := &responseRecorder{ResponseRecorder: httptest.NewRecorder(), save: map[string]int{}}
+	balancer.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, 1, recorder.save["second"])
+
+	// A successful probe restores the whole traffic of the server.
+	close(release)
+	<-done
+
+	recorder = &responseRecorder{ResponseRecorder: httptest.NewRecorder(), save: map[string]int{}}
+	for range 3 {
+		balancer.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+	}
+	assert.Equal(t, 3, recorder.save["first"])
+
+	stats := balancer.Stats().Servers["first"]
+	assert.Equal(t, uint64(4), stats.Ejections)
+	assert.Equal(t, uint64(1), stats.Readmissions)
+	assert.False(t, stats.HalfOpen)
+	assert.True(t, stats.EjectedUntil.IsZero())
+}
+
+func TestOutlierDetectionEjectionTime(t *testing.T) {
+	config := OutlierDetection{EjectionTime: time.Second}
+	assert.Equal(t, time.Second, config.ejectionTime(3))
+
+	config.MaxEjectionTime = 5 * time.Second
+	assert.Equal(t, time.Second, config.ejectionTime(1))
+	assert.Equal(t, 2*time.Second, config.ejectionTime(2))
+	assert.Equal(t, 4*time.Second, config.ejectionTime(3))
+	assert.Equal(t, 5*time.Second, config.ejectionTime(4))
+	assert.Equal(t, 5*time.Second, config.ejectionTime(100))
+}
+
+func TestLBBalancerOutlierDetectionClientCanceled(t *testing.T) {
+	balancer := New(nil, false)
+	balancer.clock = newFakeClock()
+	balancer.SetOutlierDetection(&OutlierDetection{
+		Window:       10 * time.Second,
+		MinRequests:  4,
+		MaxErrorRate: 0.5,
+		EjectionTime: 30 * time.Second,
+	})
+
+	var cancel context.CancelFunc
+	balancer.Add("first", http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		// The client goes away mid-request, which the proxy reports as an error.
+		cancel()
+		<-req.Context().Done()
+		rw.WriteHeader(http.StatusBadGateway)
+	}), Int(100), Int(100), Int(1), Int(1))
+
+	balancer.Add("second", http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusOK)
+	}), Int(100), Int(100), Int(1), Int(2))
+
+	for range 6 {
+		ctx, cancelClient := context.WithCancel(context.Background())
+		cancel = cancelClient
+		balancer.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx))
+		cancelClient()
+	}
+
+	stats := balancer.Stats().Servers["first"]
+	assert.Equal(t, uint64(6), stats.Served)
+	assert.Equal(t, uint64(6), stats.ClientCanceled)
+	assert.Empty(t, stats.Responses)
+	assert.Zero(t, stats.Ejections)
+}
@@ -0,0 +1,40 @@
+package lblb
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// errOverflowLimit is the error of a selection which overflowed through the maximum number of servers.
+var errOverflowLimit = fmt.Errorf("%w: maximum overflow hops reached", errNoAvailableServer)
+
+// SetMaxOverflowHops caps the number of hops a selection makes from a server denied by its bucket to the next one,
+// bounding how far a single request spreads, and the latency it adds, when the throttling cascades in large pools:
+// at most hops+1 buckets are tried.
+// Unlike the scan limit, only the hops count: the servers skipped because they are down, draining,
+// or in cooldown, do not. It applies to the strategies overflowing to the next servers,
+// i.e. the priority and predicted completion ones.
+// A request reaching the limit is sent to fallback if it is not nil, and otherwise rejected with a 503,
+// which is counted with the RejectOverflowLimit reason.
+// A non-positive hops disables the limit, which is the default.
+func (b *LBBalancer) SetMaxOverflowHops(hops int, fallback http.Handler) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.maxOverflowHops = max(hops, 0)
+	b.overflowFallback = fallback
+}
+
+// overflowLimitReached reports whether a selection having had throttled candidates denied by their bucket must stop.
+// It must be called with the mutex held.
+func (b *LBBalancer) overflowLimitReached(throttled int) bool {
+	return b.maxOverflowHops > 0 && throttled > b.maxOverflowHops
+}
+
+// overflowLimitFallback returns the handler of the requests reaching the maximum overflow hops, if any.
+func (b *LBBalancer) overflowLimitFallback() http.Handler {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	return b.overflowFallback
+}
@@ -0,0 +1,64 @@
+package lblb
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLBBalancerMaxOverflowHops(t *testing.T) {
+	clock := newFakeClock()
+
+	balancer := New(nil, false)
+	balancer.clock = clock
+	balancer.SetMaxOverflowHops(2, nil)
+
+	// Tokens are not refilled during the test, and srv-i has a burst of i+1 tokens.
+	for i := range 5 {
+		name := fmt.Sprintf("srv-%d", i)
+		balancer.Add(name, http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			rw.Header().Set("server", name)
+			rw.WriteHeader(http.StatusOK)
+		}), Int(i+1), Int(1), Int(3600000), Int(i+1))
+	}
+
+	var decisions []Decision
+	balancer.SetDecisionTracer(func(d Decision) {
+		decisions = append(decisions, d)
+	})
+
+	serve := func() *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		balancer.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+		return recorder
+	}
+
+	// As the servers get throttled one after the other, the requests overflow further, up to 2 hops.
+	var served []string
+	for range 6 {
+		recorder := serve()
+		require.Equal(t, http.StatusOK, recorder.Code)
+		served = append(served, recorder.Header().Get("server"))
+	}
+	assert.Equal(t, []string{"srv-0", "srv-1", "srv-1", "srv-2", "srv-2", "srv-2"}, served)
+	assert.Len(t, decisions[len(decisions)-1].Candidates, 3)
+
+	// srv-3 and srv-4 still have tokens, but are beyond the hops.
+	recorder := serve()
+	assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+	assert.Len(t, decisions[len(decisions)-1].Candidates, 3)
+	assert.Equal(t, map[string]uint64{RejectOverflowLimit: 1}, balancer.Stats().RejectionReasons)
+
+	balancer.SetMaxOverflowHops(2, http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Header().Set("server", "fallback")
+		rw.WriteHeader(http.StatusOK)
+	}))
+	assert.Equal(t, "fallback", serve().Header().Get("server"))
+
+	balancer.SetMaxOverflowHops(0, nil)
+	assert.Equal(t, "srv-3", serve().Header().Get("server"))
+}
@@ -0,0 +1,54 @@
+package lblb
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/traefik/traefik/v3/pkg/config/dynamic"
+)
+
+// MaxThroughput returns the sustained rate, in requests per second, that the given servers can serve together,
+// i.e. the sum of their average rates. The burst does not count, as it is only available once.
+// The servers ignored by the balancer, because of a non-positive average, do not count either.
+func MaxThroughput(servers ...dynamic.Server) float64 {
+	var throughput float64
+	for _, server := range servers {
+		params, ok := newServerParams(server.Burst, server.Average, server.Period, server.Priority)
+		if !ok {
+			continue
+		}
+
+		throughput += float64(params.limit())
+	}
+
+	return throughput
+}
+
+// SuggestConfig returns the rate parameters of each of the given number of servers,
+// for them to sustain together the target rate, in requests per second, when the load is evenly distributed.
+// The suggested burst absorbs one second of traffic, and the priority is left to the caller.
+// It is a planning helper, and does not depend on any balancer state.
+func SuggestConfig(targetRPS float64, servers int) (dynamic.Server, error) {
+	if servers <= 0 {
+		return dynamic.Server{}, fmt.Errorf("invalid number of servers %d", servers)
+	}
+	if targetRPS <= 0 || math.IsInf(targetRPS, 0) || math.IsNaN(targetRPS) {
+		return dynamic.Server{}, fmt.Errorf("invalid target rate %v", targetRPS)
+	}
+
+	perServer := targetRPS / float64(servers)
+
+	// The average and the period are integers, so they are rounded towards a higher rate.
+	average, period := int(math.Ceil(perServer)), 1000
+	if perServer < 1 {
+		periodMs := math.Floor(1000 / perServer)
+		if periodMs > math.MaxInt32 {
+			return dynamic.Server{}, fmt.Errorf("target rate %v too low", targetRPS)
+		}
+		average, period = 1, int(periodMs)
+	}
+
+	burst := max(int(math.Ceil(perServer)), 1)
+
+	return dynamic.Server{Burst: &burst, Average: &average, Period: &period}, nil
+}
@@ -0,0 +1,76 @@
+package lblb
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/traefik/traefik/v3/pkg/config/dynamic"
+)
+
+func TestMaxThroughput(t *testing.T) {
+	assert.InDelta(t, 15.5, MaxThroughput(
+		dynamic.Server{Average: Int(10), Period: Int(1000)},
+		dynamic.Server{Burst: Int(100), Average: Int(1), Period: Int(200)},
+		dynamic.Server{Average: Int(1), Period: Int(2000)},
+		// Ignored by the balancer.
+		dynamic.Server{Average: Int(0), Period: Int(1000)},
+	), 1e-6)
+
+	assert.Zero(t, MaxThroughput())
+}
+
+func TestSuggestConfig(t *testing.T) {
+	testCases := []struct {
+		desc      string
+		targetRPS float64
+		servers   int
+		expected  dynamic.Server
+	}{
+		{
+			desc:      "whole rate per server",
+			targetRPS: 1000,
+			servers:   4,
+			expected:  dynamic.Server{Burst: Int(250), Average: Int(250), Period: Int(1000)},
+		},
+		{
+			desc:      "fractional rate per server",
+			targetRPS: 1000,
+			servers:   3,
+			expected:  dynamic.Server{Burst: Int(334), Average: Int(334), Period: Int(1000)},
+		},
+		{
+			desc:      "less than a request per second per server",
+			targetRPS: 1,
+			servers:   3,
+			expected:  dynamic.Server{Burst: Int(1), Average: Int(1), Period: Int(3000)},
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			suggested, err := SuggestConfig(test.targetRPS, test.servers)
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, suggested)
+
+			servers := make([]dynamic.Server, test.servers)
+			for i := range servers {
+				servers[i] = suggested
+			}
+			assert.GreaterOrEqual(t, MaxThroughput(servers...), test.targetRPS)
+		})
+	}
+}
+
+func TestSuggestConfigInvalid(t *testing.T) {
+	for _, target := range []float64{0, -1, math.Inf(1), math.NaN(), 1e-9} {
+		_, err := SuggestConfig(target, 1)
+		assert.Error(t, err, target)
+	}
+
+	_, err := SuggestConfig(100, 0)
+	assert.Error(t, err)
+}
@@ -0,0 +1,132 @@
+package lblb
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// healthEndpoint is the endpoint of a server probed by the ProbeChecker.
+type healthEndpoint struct {
+	handler http.Handler
+	path    string
+}
+
+// SetHealthCheckEndpoint sets the endpoint of the named server probed by the ProbeChecker:
+// a GET request to path, served by handler, e.g. a dedicated readiness endpoint of the backend,
+// so that the health probes do not go through the traffic handler.
+// A nil handler probes path on the traffic handler of the server.
+// It returns an error if no such server exists.
+func (b *LBBalancer) SetHealthCheckEndpoint(name string, handler http.Handler, path string) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	h, ok := b.servers[name]
+	if !ok {
+		return fmt.Errorf("unknown server %s", name)
+	}
+
+	if handler == nil {
+		handler = h.Handler
+	}
+	if path == "" {
+		path = "/"
+	}
+
+	h.health = &healthEndpoint{handler: handler, path: path}
+
+	return nil
+}
+
+// ProbeChecker is a Checker probing the health check endpoints of the servers of a balancer,
+// set with SetHealthCheckEndpoint.
+// A server answering its probe with a 2xx or 3xx status is Ready, and NotReady otherwise.
+// A server without health check endpoint is never probed, and is Ready.
+type ProbeChecker struct {
+	balancer *LBBalancer
+	// timeout is the time budget of a probe, 0 meaning none.
+	timeout time.Duration
+}
+
+// NewProbeChecker creates a ProbeChecker for the servers of balancer, with the given probe timeout.
+// A non-positive timeout lets the probes take as long as the context of Check.
+func NewProbeChecker(balancer *LBBalancer, timeout time.Duration) *ProbeChecker {
+	return &ProbeChecker{balancer: balancer, timeout: max(timeout, 0)}
+}
+
+// Check probes the health check endpoint of the named server.
+func (c *ProbeChecker) Check(ctx context.Context, name string) HealthState {
+	c.balancer.mutex.RLock()
+	h, ok := c.balancer.servers[name]
+	var endpoint *healthEndpoint
+	if ok {
+		endpoint = h.health
+	}
+	c.balancer.mutex.RUnlock()
+
+	if !ok {
+		return Dead
+	}
+	if endpoint == nil {
+		return Ready
+	}
+
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint.path, http.NoBody)
+	if err != nil {
+		log.Ctx(ctx).Debug().Err(err).Msgf("Invalid health check endpoint of server %s", name)
+		return NotReady
+	}
+
+	rw := &probeWriter{header: make(http.Header)}
+	endpoint.handler.ServeHTTP(rw, req)
+
+	if status := rw.code(); status < http.StatusOK || status >= http.StatusBadRequest {
+		log.Ctx(ctx).Debug().Msgf("Health check of server %s failed with status %d", name, status)
+		return NotReady
+	}
+
+	return Ready
+}
+
+// probeWriter is a http.ResponseWriter discarding the body of a health check response.
+type probeWriter struct {
+	header http.Header
+	status int
+}
+
+func (w *probeWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *probeWriter) WriteHeader(statusCode int) {
+	// Informational responses are not final.
+	if w.status == 0 && statusCode >= http.StatusOK {
+		w.status = statusCode
+	}
+}
+
+func (w *probeWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+
+	return len(b), nil
+}
+
+// code returns the status code of the response, which is 200 if the handler did not write anything.
+func (w *probeWriter) code() int {
+	if w.status == 0 {
+		return http.StatusOK
+	}
+
+	return w.status
+}
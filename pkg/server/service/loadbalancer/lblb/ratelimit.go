@@ -0,0 +1,117 @@
+package lblb
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// RateLimit headers, as described by the IETF draft "RateLimit header fields for HTTP".
+const (
+	headerRateLimitLimit     = "RateLimit-Limit"
+	headerRateLimitRemaining = "RateLimit-Remaining"
+	headerRateLimitReset     = "RateLimit-Reset"
+)
+
+// SetRateLimitHeaders enables the RateLimit headers on the responses, so that clients can throttle themselves.
+// On an admitted request, they describe the bucket of the selected server,
+// or the global rate limit if it is more restrictive, i.e. if it has fewer remaining tokens, see SetGlobalRateLimit.
+// On a request rejected because all the buckets are empty, they describe the aggregated capacity of the up servers,
+// with no remaining token, and a reset at the next refill.
+// On a request rejected by the global rate limit, they describe it.
+func (b *LBBalancer) SetRateLimitHeaders(enabled bool) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.rateLimitHeaders = enabled
+}
+
+// writeRateLimitHeaders sets the RateLimit headers describing the bucket of the selected server.
+func (b *LBBalancer) writeRateLimitHeaders(header http.Header, h *namedHandler) {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	if !b.rateLimitHeaders {
+		return
+	}
+
+	now := b.clock.Now()
+	limit, remaining, reset := bucketRateLimit(h.bucket, now)
+
+	// The global rate limit is reported when it is the binding constraint.
+	if b.globalBucket != nil {
+		globalLimit, globalRemaining, globalReset := bucketRateLimit(b.globalBucket, now)
+		if globalRemaining < remaining || (globalRemaining == remaining && globalReset > reset) {
+			limit, remaining, reset = globalLimit, globalRemaining, globalReset
+		}
+	}
+
+	setRateLimitHeaders(header, limit, remaining, reset)
+}
+
+// bucketRateLimit returns the RateLimit values describing bucket at now:
+// its burst, its remaining tokens, and the delay until it is full again.
+func bucketRateLimit(bucket *rate.Limiter, now time.Time) (limit, remaining int, reset time.Duration) {
+	tokens := max(bucket.TokensAt(now), 0)
+	burst := bucket.Burst()
+
+	return burst, int(math.Floor(tokens)), refillDelay(float64(bucket.Limit()), float64(burst)-tokens)
+}
+
+// writeRejectedRateLimitHeaders sets the RateLimit headers of a request rejected because all the buckets are empty.
+func (b *LBBalancer) writeRejectedRateLimitHeaders(header http.Header) {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	if !b.rateLimitHeaders {
+		return
+	}
+
+	now := b.clock.Now()
+
+	// A request rejected by the global rate limit is told when the global limit admits a request again.
+	if b.globalBucket != nil && b.globalBucket.TokensAt(now) < 1 {
+		setRateLimitHeaders(header, b.globalBucket.Burst(), 0, refillDelay(float64(b.globalBucket.Limit()), 1-b.globalBucket.TokensAt(now)))
+		return
+	}
+
+	limit := 0
+	reset := time.Duration(-1)
+	for _, h := range b.handlers {
+		if _, up := b.status[h.name]; !up {
+			continue
+		}
+
+		limit += h.bucket.Burst()
+
+		delay := refillDelay(float64(h.bucket.Limit()), 1-h.bucket.TokensAt(now))
+		if reset < 0 || delay < reset {
+			reset = delay
+		}
+	}
+
+	if reset < 0 {
+		return
+	}
+
+	setRateLimitHeaders(header, limit, 0, reset)
+}
+
+// refillDelay returns how long a bucket refilling at limit tokens per second takes to gain the missing tokens.
+func refillDelay(limit, missing float64) time.Duration {
+	if missing <= 0 || limit <= 0 {
+		return 0
+	}
+
+	return time.Duration(missing / limit * float64(time.Second))
+}
+
+func setRateLimitHeaders(header http.Header, limit, remaining int, reset time.Duration) {
+	header.Set(headerRateLimitLimit, strconv.Itoa(limit))
+	header.Set(headerRateLimitRemaining, strconv.Itoa(remaining))
+	// The reset is a number of seconds, rounded up so that a client waiting for it finds a token.
+	header.Set(headerRateLimitReset, strconv.FormatInt(int64(math.Ceil(reset.Seconds())), 10))
+}
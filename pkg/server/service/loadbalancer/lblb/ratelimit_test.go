@@ -0,0 +1,117 @@
+package lblb
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLBBalancerRateLimitHeaders(t *testing.T) {
+	clock := newFakeClock()
+	balancer := New(nil, false)
+	balancer.clock = clock
+	balancer.SetRateLimitHeaders(true)
+
+	// 2 requests per second each, with a burst of 2.
+	balancer.Add("first", http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusOK)
+	}), Int(2), Int(2), Int(1000), Int(1))
+	balancer.Add("second", http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusOK)
+	}), Int(2), Int(2), Int(1000), Int(2))
+
+	type headers struct {
+		code                    int
+		limit, remaining, reset string
+	}
+	serve := func() headers {
+		recorder := httptest.NewRecorder()
+		balancer.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		return headers{
+			code:      recorder.Code,
+			limit:     recorder.Header().Get("RateLimit-Limit"),
+			remaining: recorder.Header().Get("RateLimit-Remaining"),
+			reset:     recorder.Header().Get("RateLimit-Reset"),
+		}
+	}
+
+	// Each token takes 500ms to refill.
+	assert.Equal(t, headers{code: http.StatusOK, limit: "2", remaining: "1", reset: "1"}, serve())
+	assert.Equal(t, headers{code: http.StatusOK, limit: "2", remaining: "0", reset: "1"}, serve())
+	assert.Equal(t, headers{code: http.StatusOK, limit: "2", remaining: "1", reset: "1"}, serve())
+	assert.Equal(t, headers{code: http.StatusOK, limit: "2", remaining: "0", reset: "1"}, serve())
+
+	// All the buckets are empty: the aggregated capacity is reported, with nothing remaining.
+	assert.Equal(t, headers{code: http.StatusServiceUnavailable, limit: "4", remaining: "0", reset: "1"}, serve())
+
+	clock.Advance(2 * time.Second)
+	assert.Equal(t, headers{code: http.StatusOK, limit: "2", remaining: "1", reset: "1"}, serve())
+}
+
+func TestLBBalancerRateLimitHeadersDisabled(t *testing.T) {
+	balancer := New(nil, false)
+
+	balancer.Add("first", http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusOK)
+	}), Int(1), Int(1), Int(1000), Int(1))
+
+	for range 2 {
+		recorder := httptest.NewRecorder()
+		balancer.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		assert.Empty(t, recorder.Header().Get("RateLimit-Limit"))
+		assert.Empty(t, recorder.Header().Get("RateLimit-Remaining"))
+		assert.Empty(t, recorder.Header().Get("RateLimit-Reset"))
+	}
+}
+
+func TestLBBalancerRateLimitHeadersGlobalLimit(t *testing.T) {
+	clock := newFakeClock()
+	balancer := New(nil, false)
+	balancer.clock = clock
+	balancer.SetRateLimitHeaders(true)
+	// 1 request per second, with a burst of 2.
+	balancer.SetGlobalRateLimit(1, time.Second, 2)
+
+	// 10 requests per second, with a burst of 10.
+	balancer.Add("first", http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusOK)
+	}), Int(10), Int(10), Int(1000), Int(1))
+
+	type headers struct {
+		code                    int
+		limit, remaining, reset string
+	}
+	serve := func() headers {
+		recorder := httptest.NewRecorder()
+		balancer.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		return headers{
+			code:      recorder.Code,
+			limit:     recorder.Header().Get("RateLimit-Limit"),
+			remaining: recorder.Header().Get("RateLimit-Remaining"),
+			reset:     recorder.Header().Get("RateLimit-Reset"),
+		}
+	}
+
+	// The global limit is the binding constraint.
+	assert.Equal(t, headers{code: http.StatusOK, limit: "2", remaining: "1", reset: "1"}, serve())
+	assert.Equal(t, headers{code: http.StatusOK, limit: "2", remaining: "0", reset: "2"}, serve())
+	assert.Equal(t, headers{code: http.StatusTooManyRequests, limit: "2", remaining: "0", reset: "1"}, serve())
+	assert.Equal(t, map[string]uint64{RejectGlobalRate: 1}, balancer.Stats().RejectionReasons)
+
+	// The bucket of the server is, once the global limit is relaxed.
+	balancer.SetGlobalRateLimit(100, time.Second, 100)
+	assert.Equal(t, headers{code: http.StatusOK, limit: "10", remaining: "7", reset: "1"}, serve())
+
+	// A request which could not be dispatched gets its global token back.
+	balancer.SetGlobalRateLimit(1, time.Hour, 1)
+	balancer.SetDraining("first", true)
+	assert.Equal(t, http.StatusServiceUnavailable, serve().code)
+	balancer.SetDraining("first", false)
+	assert.Equal(t, http.StatusOK, serve().code)
+}
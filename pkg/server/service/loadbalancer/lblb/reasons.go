@@ -0,0 +1,93 @@
+package lblb
+
+import "sync/atomic"
+
+// Reasons of the rejected requests, as counted in Stats.RejectionReasons.
+const (
+	// RejectAllDown is a request rejected because no server is up.
+	RejectAllDown = "all-down"
+	// RejectAllRateLimited is a request rejected because no bucket of the up servers admitted it.
+	RejectAllRateLimited = "all-rate-limited"
+	// RejectAllDraining is a non-sticky request rejected because all the up servers are draining.
+	RejectAllDraining = "all-draining"
+	// RejectGlobalCap is a request rejected by the concurrency limit of the balancer.
+	RejectGlobalCap = "global-cap"
+	// RejectShed is a non-critical request rejected because only the slots reserved for the critical requests are left.
+	RejectShed = "shed"
+	// RejectOverflowLimit is a request rejected because it overflowed through the maximum number of servers,
+	// see SetMaxOverflowHops.
+	RejectOverflowLimit = "overflow-limit"
+	// RejectGlobalRate is a request rejected by the global rate limit of the balancer, see SetGlobalRateLimit.
+	RejectGlobalRate = "global-rate"
+	// RejectStartupRamp is a request rejected by the admission ramp of the startup phase, see SetStartupRamp.
+	RejectStartupRamp = "startup-ramp"
+	// RejectAggregateBurst is a request rejected by the aggregate burst of the balancer, see SetMaxAggregateBurst.
+	RejectAggregateBurst = "aggregate-burst"
+	// RejectBodyTooLarge is a request rejected because its body is larger than the maximum of its server,
+	// see SetMaxRequestBodySize.
+	RejectBodyTooLarge = "body-too-large"
+)
+
+// rejectionReasons are the reasons of the rejected requests, in the order of their counters.
+var rejectionReasons = [...]string{RejectAllDown, RejectAllRateLimited, RejectAllDraining, RejectGlobalCap, RejectShed, RejectOverflowLimit, RejectGlobalRate, RejectStartupRamp, RejectAggregateBurst, RejectBodyTooLarge}
+
+// rejectionCounters are the numbers of rejected requests, by reason, in the order of rejectionReasons.
+type rejectionCounters [len(rejectionReasons)]atomic.Uint64
+
+// count counts a request rejected for the given reason.
+func (c *rejectionCounters) count(reason string) {
+	for i, r := range rejectionReasons {
+		if r == reason {
+			c[i].Add(1)
+			return
+		}
+	}
+}
+
+// snapshot returns the numbers of rejected requests by reason, omitting the reasons without any.
+func (c *rejectionCounters) snapshot() map[string]uint64 {
+	var reasons map[string]uint64
+	for i, reason := range rejectionReasons {
+		if count := c[i].Load(); count > 0 {
+			if reasons == nil {
+				reasons = make(map[string]uint64)
+			}
+			reasons[reason] = count
+		}
+	}
+
+	return reasons
+}
+
+// add adds the numbers of rejected requests by reason to the counters, ignoring the unknown reasons.
+func (c *rejectionCounters) add(reasons map[string]uint64) {
+	for i, reason := range rejectionReasons {
+		c[i].Add(reasons[reason])
+	}
+}
+
+// unavailableReason returns why no server could be selected for a request.
+func (b *LBBalancer) unavailableReason() string {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	now := b.clock.Now()
+
+	var up bool
+	for _, h := range b.handlers {
+		if _, ok := b.status[h.name]; !ok || h.Handler == nil || b.coolingDown(h.name, now) {
+			continue
+		}
+
+		up = true
+		if _, draining := b.draining[h.name]; !draining {
+			return RejectAllRateLimited
+		}
+	}
+
+	if up {
+		return RejectAllDraining
+	}
+
+	return RejectAllDown
+}
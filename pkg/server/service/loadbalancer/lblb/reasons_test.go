@@ -0,0 +1,81 @@
+package lblb
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLBBalancerRejectionReasons(t *testing.T) {
+	balancer := New(nil, false)
+	balancer.clock = newFakeClock()
+
+	// Tokens are not refilled during the test.
+	for i, name := range []string{"first", "second"} {
+		balancer.Add(name, http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			rw.WriteHeader(http.StatusOK)
+		}), Int(1), Int(1), Int(3600000), Int(i+1))
+	}
+
+	serve := func(priority string) int {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if priority != "" {
+			req.Header.Set("X-Priority", priority)
+		}
+
+		recorder := httptest.NewRecorder()
+		balancer.ServeHTTP(recorder, req)
+		return recorder.Code
+	}
+
+	expected := map[string]uint64{}
+	reject := func(reason string, code int, priority string) {
+		t.Helper()
+
+		assert.Equal(t, code, serve(priority))
+		expected[reason]++
+		assert.Equal(t, expected, balancer.Stats().RejectionReasons)
+	}
+
+	assert.Nil(t, balancer.Stats().RejectionReasons)
+
+	// Both buckets are emptied.
+	require.Equal(t, http.StatusOK, serve(""))
+	require.Equal(t, http.StatusOK, serve(""))
+	reject(RejectAllRateLimited, http.StatusServiceUnavailable, "")
+
+	balancer.SetDraining("first", true)
+	balancer.SetDraining("second", true)
+	reject(RejectAllDraining, http.StatusServiceUnavailable, "")
+
+	balancer.SetStatus(context.Background(), "first", false)
+	reject(RejectAllDraining, http.StatusServiceUnavailable, "")
+
+	balancer.SetStatus(context.Background(), "second", false)
+	reject(RejectAllDown, http.StatusServiceUnavailable, "")
+
+	balancer.SetStatus(context.Background(), "first", true)
+	balancer.SetStatus(context.Background(), "second", true)
+	balancer.SetConcurrencyLimit(2, &LoadShedding{Header: "X-Priority", CriticalPriority: 1, Reserved: 1})
+	balancer.inflight.Add(1)
+	reject(RejectShed, http.StatusTooManyRequests, "5")
+
+	balancer.inflight.Add(1)
+	reject(RejectGlobalCap, http.StatusTooManyRequests, "1")
+	reject(RejectGlobalCap, http.StatusTooManyRequests, "5")
+
+	stats := balancer.Stats()
+	assert.Equal(t, uint64(7), stats.Rejected)
+
+	// The reasons are persisted with the other counters.
+	data, err := balancer.MarshalStats()
+	require.NoError(t, err)
+
+	restored := New(nil, false)
+	require.NoError(t, restored.LoadStats(data))
+	assert.Equal(t, expected, restored.Stats().RejectionReasons)
+}
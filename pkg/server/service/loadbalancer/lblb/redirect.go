@@ -0,0 +1,203 @@
+package lblb
+
+import (
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+// followRedirects is the following of the redirects of the servers.
+type followRedirects struct {
+	maxHops int
+	codes   []int
+}
+
+// SetFollowRedirects enables the following of the redirects returned by the servers with the given status codes,
+// 307 and 308 if none is given: instead of being passed to the client, such a redirect is followed internally,
+// the request being rewritten with the path and query of its location, and dispatched again through the selection,
+// up to maxHops times, the last redirect being passed to the client.
+// Only the locations without a host, or with the host of the request, are followed,
+// the redirects to another host being passed to the client.
+// Each hop is built from the request of the client, e.g. without any header injected for the previous hop.
+// Only the idempotent requests, per the idempotency classifier, and without a body, are followed,
+// and a 303 is followed with a GET.
+// A non-positive maxHops disables the following, which is the default.
+func (b *LBBalancer) SetFollowRedirects(maxHops int, codes ...int) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if maxHops <= 0 {
+		b.followRedirects = nil
+		return
+	}
+
+	if len(codes) == 0 {
+		codes = []int{http.StatusTemporaryRedirect, http.StatusPermanentRedirect}
+	}
+
+	b.followRedirects = &followRedirects{maxHops: maxHops, codes: slices.Clone(codes)}
+}
+
+// redirectsFollowed returns the following of the redirects of req, nil if they are not followed.
+func (b *LBBalancer) redirectsFollowed(req *http.Request) *followRedirects {
+	b.mutex.RLock()
+	follow := b.followRedirects
+	b.mutex.RUnlock()
+
+	if follow == nil || req.ContentLength != 0 || !b.Idempotent(req) {
+		return nil
+	}
+
+	return follow
+}
+
+// serveFollowingRedirects serves req, following the redirects of the servers per follow.
+func (b *LBBalancer) serveFollowingRedirects(w http.ResponseWriter, req *http.Request, follow *followRedirects) {
+	original := req
+	for hop := 0; ; hop++ {
+		interceptor := &redirectInterceptor{ResponseWriter: w, header: make(http.Header), codes: follow.codes, host: original.Host, follow: hop < follow.maxHops}
+		b.serveHTTP(interceptor, req)
+		interceptor.finish()
+
+		if interceptor.location == nil {
+			return
+		}
+
+		location := req.URL.ResolveReference(interceptor.location)
+		log.Ctx(req.Context()).Debug().Msgf("Following the redirect %d to %s", interceptor.redirect, location.RequestURI())
+
+		req = redirected(original, req.Method, location, interceptor.redirect)
+	}
+}
+
+// redirected returns the original request rewritten with the path and query of location,
+// following a redirect with the given status code of a request with the given method.
+func redirected(original *http.Request, method string, location *url.URL, code int) *http.Request {
+	next := original.Clone(original.Context())
+	next.Method = method
+	next.URL.Path = location.Path
+	next.URL.RawPath = location.RawPath
+	next.URL.RawQuery = location.RawQuery
+	next.RequestURI = next.URL.RequestURI()
+
+	if code == http.StatusSeeOther && next.Method != http.MethodHead {
+		next.Method = http.MethodGet
+	}
+
+	return next
+}
+
+// sameHost reports whether location is on the given host, i.e. has no host, or that one.
+func sameHost(location *url.URL, host string) bool {
+	return location.Host == "" || strings.EqualFold(location.Host, host)
+}
+
+// redirectInterceptor is a http.ResponseWriter intercepting the redirects to follow,
+// and passing through the other responses.
+// Its headers are its own until the response is passed through,
+// so that the headers of an intercepted redirect are not sent to the client.
+type redirectInterceptor struct {
+	http.ResponseWriter
+
+	header http.Header
+	codes  []int
+	// host is the host of the request, the only one the redirects are followed to.
+	host string
+	// follow is whether a redirect can still be followed.
+	follow bool
+
+	// redirect is the status code of the intercepted redirect, and location its location.
+	redirect int
+	location *url.URL
+	// passed is whether the response is passed through.
+	passed bool
+}
+
+func (r *redirectInterceptor) Header() http.Header {
+	if r.passed {
+		return r.ResponseWriter.Header()
+	}
+
+	return r.header
+}
+
+func (r *redirectInterceptor) WriteHeader(statusCode int) {
+	if r.location != nil {
+		return
+	}
+
+	if !r.passed && r.follow && slices.Contains(r.codes, statusCode) {
+		// A redirect without a valid location, or to another host, cannot be followed, and is passed through.
+		value := r.header.Get("Location")
+		if location, err := url.Parse(value); err == nil && value != "" && sameHost(location, r.host) {
+			r.redirect, r.location = statusCode, location
+			return
+		}
+	}
+
+	// Informational responses are not final, and the headers can still be changed after them.
+	if statusCode >= http.StatusOK {
+		r.passThrough()
+	} else {
+		copyHeader(r.ResponseWriter.Header(), r.header)
+	}
+
+	r.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (r *redirectInterceptor) Write(b []byte) (int, error) {
+	if r.location != nil {
+		// The body of an intercepted redirect is discarded.
+		return len(b), nil
+	}
+
+	r.passThrough()
+
+	return r.ResponseWriter.Write(b)
+}
+
+// Flush implements http.Flusher.
+func (r *redirectInterceptor) Flush() {
+	if r.location != nil {
+		return
+	}
+
+	r.passThrough()
+
+	if flusher, ok := r.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap returns the underlying http.ResponseWriter, for http.ResponseController.
+func (r *redirectInterceptor) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// passThrough passes the response through to the client, with its headers.
+func (r *redirectInterceptor) passThrough() {
+	if r.passed {
+		return
+	}
+
+	r.passed = true
+	copyHeader(r.ResponseWriter.Header(), r.header)
+}
+
+// finish passes the headers through to the client when the handler did not write any response,
+// and the response is not an intercepted redirect.
+func (r *redirectInterceptor) finish() {
+	if r.location == nil {
+		r.passThrough()
+	}
+}
+
+// copyHeader copies the values of src into dst.
+func copyHeader(dst, src http.Header) {
+	for name, values := range src {
+		dst[name] = slices.Clone(values)
+	}
+}
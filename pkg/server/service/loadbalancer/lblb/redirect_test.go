@@ -0,0 +1,115 @@
+package lblb
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLBBalancerFollowRedirects(t *testing.T) {
+	testCases := []struct {
+		desc         string
+		maxHops      int
+		method       string
+		path         string
+		expectedCode int
+		expectedBody string
+		expectedHits int
+	}{
+		{
+			desc:         "disabled",
+			method:       http.MethodGet,
+			path:         "/hop/2",
+			expectedCode: http.StatusTemporaryRedirect,
+			expectedHits: 1,
+		},
+		{
+			desc:         "followed",
+			maxHops:      3,
+			method:       http.MethodGet,
+			path:         "/hop/2",
+			expectedCode: http.StatusOK,
+			expectedBody: "/hop/0?from=1",
+			expectedHits: 3,
+		},
+		{
+			desc:         "hop limit",
+			maxHops:      1,
+			method:       http.MethodGet,
+			path:         "/hop/2",
+			expectedCode: http.StatusTemporaryRedirect,
+			expectedHits: 2,
+		},
+		{
+			desc:         "not an idempotent method",
+			maxHops:      3,
+			method:       http.MethodPost,
+			path:         "/hop/2",
+			expectedCode: http.StatusTemporaryRedirect,
+			expectedHits: 1,
+		},
+		{
+			desc:         "another host",
+			maxHops:      3,
+			method:       http.MethodGet,
+			path:         "/elsewhere",
+			expectedCode: http.StatusTemporaryRedirect,
+			expectedHits: 1,
+		},
+		{
+			desc:         "not a followed code",
+			maxHops:      3,
+			method:       http.MethodGet,
+			path:         "/found",
+			expectedCode: http.StatusFound,
+			expectedHits: 1,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			balancer := New(nil, false)
+			balancer.clock = newFakeClock()
+			balancer.SetFollowRedirects(test.maxHops)
+
+			var hits int
+			balancer.Add("first", http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+				hits++
+				rw.Header().Set("server", "first")
+
+				switch req.URL.Path {
+				case "/elsewhere":
+					http.Redirect(rw, req, "http://other.example.com/hop/0", http.StatusTemporaryRedirect)
+				case "/found":
+					http.Redirect(rw, req, "/hop/0", http.StatusFound)
+				case "/hop/2":
+					http.Redirect(rw, req, "/hop/1", http.StatusTemporaryRedirect)
+				case "/hop/1":
+					http.Redirect(rw, req, "http://example.com/hop/0?from=1", http.StatusTemporaryRedirect)
+				default:
+					_, _ = rw.Write([]byte(req.URL.RequestURI()))
+				}
+			}), Int(10), Int(1), Int(1000), Int(1))
+
+			recorder := httptest.NewRecorder()
+			balancer.ServeHTTP(recorder, httptest.NewRequest(test.method, test.path, nil))
+
+			assert.Equal(t, test.expectedCode, recorder.Code)
+			assert.Equal(t, test.expectedHits, hits)
+			assert.Equal(t, "first", recorder.Header().Get("server"))
+			if test.expectedBody != "" {
+				assert.Equal(t, test.expectedBody, recorder.Body.String())
+				assert.Empty(t, recorder.Header().Get("Location"))
+			} else {
+				assert.NotEmpty(t, recorder.Header().Get("Location"))
+			}
+
+			// Every hop is dispatched through the selection, consuming a token.
+			assert.Equal(t, uint64(test.expectedHits), balancer.Stats().Servers["first"].Served)
+		})
+	}
+}
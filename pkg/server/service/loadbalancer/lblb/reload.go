@@ -0,0 +1,149 @@
+package lblb
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/traefik/traefik/v3/pkg/config/dynamic"
+)
+
+// Server is a server of the balancer, as set by SetServers.
+type Server struct {
+	Name    string
+	Handler http.Handler
+	Config  dynamic.Server
+}
+
+// SetServers replaces all the servers of the balancer with the given ones, atomically:
+// the new servers are built aside, and swapped in at once, so that no request observes a partial or empty balancer.
+// The servers are rebuilt with full buckets, unless seeded with SetSeedBucketsOnReload,
+// and the per-server settings made with the other methods (e.g. maximum response size, priority boosts) are reset.
+// The status, draining state, and cooldown of the servers kept by name are preserved.
+// A server with a non-positive average is ignored, or registered as disabled, as with Add.
+// It returns an error, leaving the balancer untouched, if a server has no handler, if names are duplicated,
+// or if none of the given servers is valid.
+// SetServersDiff also returns the change it made.
+func (b *LBBalancer) SetServers(ctx context.Context, servers []Server) error {
+	return b.setServers(ctx, servers, nil)
+}
+
+// setServers implements SetServers, and sets diff to the change it made, if diff is not nil.
+func (b *LBBalancer) setServers(ctx context.Context, servers []Server, diff *ConfigDiff) error {
+	type newServer struct {
+		Server
+		params serverParams
+	}
+
+	// The servers are validated before taking the mutex, which is only held for the swap.
+	valid := make([]newServer, 0, len(servers))
+	names := make(map[string]struct{}, len(servers))
+	for _, server := range servers {
+		if server.Handler == nil {
+			return fmt.Errorf("server %s has no handler", server.Name)
+		}
+		if _, ok := names[server.Name]; ok {
+			return fmt.Errorf("duplicated server %s", server.Name)
+		}
+		names[server.Name] = struct{}{}
+
+		params, ok := b.resolveServerParams(server.Config.Burst, server.Config.Average, server.Config.Period, server.Config.Priority)
+		if !ok {
+			log.Ctx(ctx).Debug().Msgf("Ignoring server %s with a non-positive average", server.Name)
+			continue
+		}
+
+		valid = append(valid, newServer{Server: server, params: b.withLastResort(params, server.Config.Priority)})
+	}
+	if len(servers) > 0 && len(valid) == 0 {
+		return errors.New("no valid server")
+	}
+
+	// The removed children are detached after the mutex is released, see detachChildren.
+	detached := make(map[string]*LBBalancer)
+	defer func() { b.detachChildren(detached) }()
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	upBefore := len(b.status) > 0
+	now := b.clock.Now()
+
+	handlers := make([]*namedHandler, 0, len(valid))
+	byName := make(map[string]*namedHandler, len(valid))
+	status := make(map[string]struct{}, len(valid))
+	draining := make(map[string]struct{})
+	availability := make(map[string]time.Time)
+	for _, server := range valid {
+		h := b.newHandler(server.Name, server.Handler, server.params)
+		h.setHeaders(server.Config.Headers)
+		if previous, ok := b.servers[server.Name]; ok && b.seedBucketsOnReload {
+			h.bucket = seededLimiter(h.bucket.Limit(), h.bucket.Burst(), previous.bucket.TokensAt(now), now)
+		}
+		handlers = append(handlers, h)
+		byName[server.Name] = h
+
+		// A server kept by name after being disabled was neither up nor down, and starts up as a new one.
+		previous, known := b.servers[server.Name]
+		if _, up := b.status[server.Name]; !h.disabled && (up || !known || previous.disabled) {
+			status[server.Name] = struct{}{}
+		}
+		if _, ok := b.draining[server.Name]; ok || server.Config.Fenced {
+			draining[server.Name] = struct{}{}
+		}
+		if until, ok := b.serverAvailability[server.Name]; ok {
+			availability[server.Name] = until
+		}
+	}
+
+	// The diff is computed before the boosts and schedules are stopped, for the priorities of their servers.
+	if diff != nil {
+		*diff = b.configDiff(handlers)
+	}
+
+	for name, bst := range b.boosts {
+		bst.stop()
+		delete(b.boosts, name)
+	}
+	b.stopSchedules()
+	for name, child := range b.children {
+		if _, ok := byName[name]; !ok {
+			detached[name] = child
+			delete(b.children, name)
+		}
+	}
+
+	b.handlers = handlers
+	heap.Init(b)
+	b.servers = byName
+	b.status = status
+	b.draining = draining
+	b.serverAvailability = availability
+	b.updateAggregateBurst()
+
+	if b.sticky != nil {
+		for _, h := range handlers {
+			b.sticky.AddHandler(h.name, h.Handler)
+		}
+	}
+
+	log.Ctx(ctx).Debug().Msgf("Set %d servers", len(handlers))
+
+	list := make([]string, 0, len(handlers))
+	for _, h := range handlers {
+		list = append(list, h.name)
+	}
+	b.audit(AuditSetServers, map[string]any{"servers": list})
+
+	b.propagateStatus(ctx, upBefore)
+
+	if len(handlers) > 0 {
+		b.initializedOnce.Do(func() { close(b.initialized) })
+	}
+
+	return nil
+}
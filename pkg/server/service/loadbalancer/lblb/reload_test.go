@@ -0,0 +1,166 @@
+package lblb
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/traefik/traefik/v3/pkg/config/dynamic"
+)
+
+func TestLBBalancerSetServers(t *testing.T) {
+	balancer := New(nil, true)
+
+	handler := func(name string) http.Handler {
+		return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			rw.Header().Set("server", name)
+			rw.WriteHeader(http.StatusOK)
+		})
+	}
+	config := func(priority int) dynamic.Server {
+		return dynamic.Server{Burst: Int(10), Average: Int(10), Period: Int(1000), Priority: Int(priority)}
+	}
+
+	var updates []bool
+	require.NoError(t, balancer.RegisterStatusUpdater(func(up bool) {
+		updates = append(updates, up)
+	}))
+
+	require.NoError(t, balancer.SetServers(context.Background(), []Server{
+		{Name: "first", Handler: handler("first"), Config: config(1)},
+		{Name: "second", Handler: handler("second"), Config: config(2)},
+	}))
+	assert.Equal(t, []string{"first", "second"}, serverNames(balancer))
+	assert.Equal(t, "first", serveOne(balancer))
+
+	balancer.SetStatus(context.Background(), "first", false)
+
+	// first is kept and stays down, second is removed, and third is added.
+	require.NoError(t, balancer.SetServers(context.Background(), []Server{
+		{Name: "first", Handler: handler("first"), Config: config(1)},
+		{Name: "third", Handler: handler("third"), Config: config(3)},
+		{Name: "ignored", Handler: handler("ignored"), Config: dynamic.Server{Average: Int(0)}},
+	}))
+	assert.Equal(t, []string{"first", "third"}, serverNames(balancer))
+	assert.Equal(t, "third", serveOne(balancer))
+
+	// Invalid sets leave the balancer untouched.
+	assert.Error(t, balancer.SetServers(context.Background(), []Server{{Name: "nil", Config: config(1)}}))
+	assert.Error(t, balancer.SetServers(context.Background(), []Server{
+		{Name: "dup", Handler: handler("dup"), Config: config(1)},
+		{Name: "dup", Handler: handler("dup"), Config: config(1)},
+	}))
+	assert.Error(t, balancer.SetServers(context.Background(), []Server{
+		{Name: "ignored", Handler: handler("ignored"), Config: dynamic.Server{Average: Int(0)}},
+	}))
+	assert.Equal(t, []string{"first", "third"}, serverNames(balancer))
+
+	require.NoError(t, balancer.SetServers(context.Background(), nil))
+	assert.Empty(t, serverNames(balancer))
+	assert.Equal(t, []bool{true, false}, updates)
+}
+
+func TestLBBalancerSetServersConcurrent(t *testing.T) {
+	balancer := New(nil, false)
+
+	handler := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusOK)
+	})
+
+	servers := func(i int) []Server {
+		return []Server{
+			{Name: fmt.Sprintf("srv-%d", i%3), Handler: handler, Config: dynamic.Server{Burst: Int(1000000), Average: Int(1000000), Period: Int(1), Priority: Int(1)}},
+			{Name: fmt.Sprintf("srv-%d", i%3+3), Handler: handler, Config: dynamic.Server{Burst: Int(1000000), Average: Int(1000000), Period: Int(1), Priority: Int(2)}},
+		}
+	}
+	require.NoError(t, balancer.SetServers(context.Background(), servers(0)))
+
+	var unavailable atomic.Int64
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+
+				recorder := httptest.NewRecorder()
+				balancer.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+				if recorder.Code != http.StatusOK {
+					unavailable.Add(1)
+				}
+			}
+		}()
+	}
+
+	for i := range 500 {
+		require.NoError(t, balancer.SetServers(context.Background(), servers(i+1)))
+	}
+	close(stop)
+	wg.Wait()
+
+	assert.Zero(t, unavailable.Load())
+}
+
+func TestLBBalancerSetServersDiff(t *testing.T) {
+	clock := newFakeClock()
+	clock.now = time.Date(2026, time.January, 1, 12, 0, 0, 0, time.UTC)
+	balancer := New(nil, false)
+	balancer.clock = clock
+
+	handler := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusOK)
+	})
+	config := func(average, priority int) dynamic.Server {
+		return dynamic.Server{Burst: Int(10), Average: Int(average), Period: Int(1000), Priority: Int(priority)}
+	}
+	params := func(average, priority int64) ServerParams {
+		return ServerParams{Burst: 10, Average: average, Period: time.Second, Priority: priority}
+	}
+
+	diff, err := balancer.SetServersDiff(context.Background(), []Server{
+		{Name: "first", Handler: handler, Config: config(10, 1)},
+		{Name: "second", Handler: handler, Config: config(10, 2)},
+		{Name: "third", Handler: handler, Config: config(10, 3)},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, ConfigDiff{Added: map[string]ServerParams{
+		"first":  params(10, 1),
+		"second": params(10, 2),
+		"third":  params(10, 3),
+	}}, diff)
+
+	// The boosted and scheduled priorities are not changes of the configuration.
+	require.NoError(t, balancer.BoostPriority("first", 5, time.Hour))
+	require.NoError(t, balancer.SetPrioritySchedule("second", []PriorityWindow{{Start: 11 * time.Hour, End: 13 * time.Hour, Priority: 6}}, nil))
+
+	diff, err = balancer.SetServersDiff(context.Background(), []Server{
+		{Name: "first", Handler: handler, Config: config(10, 1)},
+		{Name: "second", Handler: handler, Config: config(20, 2)},
+		{Name: "fourth", Handler: handler, Config: config(10, 4)},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, ConfigDiff{
+		Added:   map[string]ServerParams{"fourth": params(10, 4)},
+		Removed: map[string]ServerParams{"third": params(10, 3)},
+		Updated: map[string]ServerUpdate{"second": {Old: params(10, 2), New: params(20, 2)}},
+	}, diff)
+
+	diff, err = balancer.SetServersDiff(context.Background(), []Server{{Name: "nil", Config: config(10, 1)}})
+	require.Error(t, err)
+	assert.True(t, diff.Empty())
+	assert.ElementsMatch(t, []string{"first", "second", "fourth"}, serverNames(balancer))
+}
@@ -0,0 +1,158 @@
+package lblb
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/traefik/traefik/v3/pkg/config/dynamic"
+)
+
+func TestReplay(t *testing.T) {
+	servers := map[string]dynamic.Server{
+		"first":  {Burst: Int(5), Average: Int(10), Period: Int(1000), Priority: Int(1)},
+		"second": {Burst: Int(5), Average: Int(10), Period: Int(1000), Priority: Int(2)},
+	}
+
+	// A burst of 20 requests, a steady request every 100ms for a second, and another burst at 2s.
+	var stream []ReplayRequest
+	for range 20 {
+		stream = append(stream, ReplayRequest{Offset: 2 * time.Second})
+	}
+	for i := range 10 {
+		stream = append(stream, ReplayRequest{Offset: time.Duration(i+1) * 100 * time.Millisecond})
+	}
+	for range 20 {
+		stream = append(stream, ReplayRequest{})
+	}
+
+	result := Replay(servers, stream, nil)
+
+	expected := ReplayResult{
+		Served:           map[string]uint64{"first": 20, "second": 10},
+		Rejected:         20,
+		RejectionReasons: map[string]uint64{RejectAllRateLimited: 20},
+		Timeline: []ReplaySample{
+			{Offset: 0, Admitted: 19, Rejected: 10},
+			{Offset: time.Second, Admitted: 1},
+			{Offset: 2 * time.Second, Admitted: 10, Rejected: 10},
+		},
+	}
+	assert.Equal(t, expected, result)
+
+	// The replay is deterministic.
+	assert.Equal(t, result, Replay(servers, stream, nil))
+
+	// The configuration of the balancer applies.
+	result = Replay(servers, stream, func(b *LBBalancer) { b.SetStrategySelector(func(*http.Request) Strategy { return StrategyMostTokens }) })
+	assert.Equal(t, map[string]uint64{"first": 16, "second": 14}, result.Served)
+	assert.Equal(t, uint64(20), result.Rejected)
+}
+
+// ReplayRequest is a request of a captured stream, see Replay.
+type ReplayRequest struct {
+	// Offset is the time of the request from the start of the stream.
+	Offset time.Duration `json:"offset"`
+	Method string        `json:"method,omitempty"`
+	Path   string        `json:"path,omitempty"`
+	Header http.Header   `json:"header,omitempty"`
+}
+
+// ReplaySample are the numbers of requests of a second of a replayed stream, by outcome.
+type ReplaySample struct {
+	// Offset is the start of the second from the start of the stream.
+	Offset   time.Duration `json:"offset"`
+	Admitted uint64        `json:"admitted"`
+	Rejected uint64        `json:"rejected"`
+}
+
+// ReplayResult is the outcome of a replayed stream.
+type ReplayResult struct {
+	// Served are the numbers of requests served by each server, the servers without any being omitted.
+	Served map[string]uint64 `json:"served"`
+	// Rejected is the number of requests which were not served by any server.
+	Rejected uint64 `json:"rejected"`
+	// RejectionReasons are the numbers of rejected requests by reason, as in Stats.RejectionReasons.
+	RejectionReasons map[string]uint64 `json:"rejectionReasons,omitempty"`
+	// Timeline are the numbers of requests of each second of the stream, up to its last request.
+	Timeline []ReplaySample `json:"timeline"`
+}
+
+// Replay replays a captured stream of requests against a balancer of the given servers, by name,
+// to validate a configuration against a real traffic shape offline:
+// the requests are dispatched at their offsets on a fake clock, the stream taking no actual time,
+// to servers replying immediately with a 200, and the result is deterministic for a given stream.
+// The balancer is configured with configure, if not nil, before the servers are added.
+// The requests are replayed in the order of their offsets, the ones with the same offset in their order in the stream.
+func Replay(servers map[string]dynamic.Server, stream []ReplayRequest, configure func(*LBBalancer)) ReplayResult {
+	clock := newFakeClock()
+	clock.now = time.Unix(0, 0)
+	start := clock.Now()
+
+	balancer := New(nil, false)
+	balancer.clock = clock
+	balancer.created = start
+	if configure != nil {
+		configure(balancer)
+	}
+
+	var served string
+	names := make([]string, 0, len(servers))
+	for name := range servers {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		balancer.AddServer(name, http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			served = name
+			rw.WriteHeader(http.StatusOK)
+		}), servers[name])
+	}
+
+	requests := slices.Clone(stream)
+	sort.SliceStable(requests, func(i, j int) bool { return requests[i].Offset < requests[j].Offset })
+
+	result := ReplayResult{Served: make(map[string]uint64)}
+	for _, r := range requests {
+		clock.Advance(max(start.Add(r.Offset).Sub(clock.Now()), 0))
+
+		method := r.Method
+		if method == "" {
+			method = http.MethodGet
+		}
+		path := r.Path
+		if path == "" {
+			path = "/"
+		}
+
+		req := httptest.NewRequest(method, path, nil)
+		for name, values := range r.Header {
+			req.Header[name] = slices.Clone(values)
+		}
+
+		served = ""
+		balancer.ServeHTTP(httptest.NewRecorder(), req)
+
+		second := int(max(r.Offset, 0) / time.Second)
+		for len(result.Timeline) <= second {
+			result.Timeline = append(result.Timeline, ReplaySample{Offset: time.Duration(len(result.Timeline)) * time.Second})
+		}
+
+		if served == "" {
+			result.Rejected++
+			result.Timeline[second].Rejected++
+			continue
+		}
+
+		result.Served[served]++
+		result.Timeline[second].Admitted++
+	}
+
+	result.RejectionReasons = balancer.Stats().RejectionReasons
+
+	return result
+}
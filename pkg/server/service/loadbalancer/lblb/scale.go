@@ -0,0 +1,99 @@
+package lblb
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"golang.org/x/time/rate"
+)
+
+// rateScale is a temporary scaling of the rates of all the servers.
+type rateScale struct {
+	factor float64
+	stop   func() bool
+}
+
+// ScaleAllRates multiplies the rates of all the servers by factor for the given duration,
+// after which their configured rates are restored, e.g. to relax or tighten all the limits during a load test.
+// The tokens available in the buckets are kept.
+// A zero factor stops refilling the buckets, so that the admission stops once their tokens are used.
+// The servers added or updated in the meantime are scaled as well,
+// and scaling while a scaling is in progress replaces it, the factors not being compounded.
+func (b *LBBalancer) ScaleAllRates(factor float64, duration time.Duration) error {
+	if factor < 0 {
+		return fmt.Errorf("invalid rate factor %v", factor)
+	}
+	if duration <= 0 {
+		return fmt.Errorf("invalid rate scaling duration %s", duration)
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.rateScale != nil {
+		b.rateScale.stop()
+	}
+
+	scale := &rateScale{factor: factor}
+	scale.stop = b.clock.AfterFunc(duration, func() { b.endRateScale(scale) })
+	b.rateScale = scale
+	b.applyRates()
+
+	log.Debug().Msgf("Scaling the rates of all servers by %v for %s", factor, duration)
+
+	return nil
+}
+
+// endRateScale restores the configured rates of the servers, if scale is still the current scaling.
+func (b *LBBalancer) endRateScale(scale *rateScale) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.rateScale != scale {
+		return
+	}
+
+	b.rateScale = nil
+	b.applyRates()
+
+	log.Debug().Msg("Rate scaling ended, restoring the rates of all servers")
+}
+
+// applyRates sets the rates of the buckets of all the servers to their configured rates, scaled if need be.
+// It must be called with the mutex held.
+func (b *LBBalancer) applyRates() {
+	now := b.clock.Now()
+	for _, h := range b.handlers {
+		b.applyRate(h, now)
+	}
+}
+
+// applyRate sets the rates of the buckets of h to its configured rate, scaled and reduced by its backpressure if need be.
+// It must be called with the mutex held.
+func (b *LBBalancer) applyRate(h *namedHandler, now time.Time) {
+	limit := b.scaledLimit(h.limit()) * rate.Limit(h.backpressure.rateFactor())
+	h.bucket.SetLimitAt(now, limit)
+	if h.stickyBucket != nil {
+		h.stickyBucket.SetLimitAt(now, stickyLimit(limit, b.nonStickyReservation))
+	}
+}
+
+// scaledLimit returns limit, scaled by the rate scaling in progress if any.
+// It must be called with the mutex held.
+func (b *LBBalancer) scaledLimit(limit rate.Limit) rate.Limit {
+	if b.rateScale == nil {
+		return limit
+	}
+
+	return limit * rate.Limit(b.rateScale.factor)
+}
+
+// limit returns the configured rate of the handler, i.e. average tokens per period, zero for a disabled handler.
+func (h *namedHandler) limit() rate.Limit {
+	if h.average == 0 {
+		return 0
+	}
+
+	return rate.Every(h.period / time.Duration(h.average))
+}
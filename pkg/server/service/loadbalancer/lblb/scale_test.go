@@ -0,0 +1,97 @@
+package lblb
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/traefik/traefik/v3/pkg/config/dynamic"
+	"golang.org/x/time/rate"
+)
+
+func TestLBBalancerScaleAllRates(t *testing.T) {
+	clock := newFakeClock()
+	balancer := New(nil, false)
+	balancer.clock = clock
+	defer balancer.Close()
+
+	handler := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {})
+	for i, name := range []string{"first", "second"} {
+		balancer.Add(name, handler, Int(1000), Int(10*(i+1)), Int(1000), Int(i+1))
+	}
+
+	bucket := func(name string) *rate.Limiter {
+		balancer.mutex.RLock()
+		defer balancer.mutex.RUnlock()
+
+		return balancer.servers[name].bucket
+	}
+
+	// The buckets are emptied, so that their refills are observed.
+	for _, name := range []string{"first", "second"} {
+		require.True(t, bucket(name).AllowN(clock.Now(), 1000))
+	}
+
+	require.NoError(t, balancer.ScaleAllRates(2, 5*time.Second))
+	assert.Equal(t, rate.Limit(20), bucket("first").Limit())
+	assert.Equal(t, rate.Limit(40), bucket("second").Limit())
+
+	clock.Advance(time.Second)
+	assert.InDelta(t, 20, bucket("first").TokensAt(clock.Now()), 0.01)
+	assert.InDelta(t, 40, bucket("second").TokensAt(clock.Now()), 0.01)
+
+	// The rates are restored after the duration, and the tokens are kept.
+	clock.Advance(4 * time.Second)
+	assert.Equal(t, rate.Limit(10), bucket("first").Limit())
+	assert.Equal(t, rate.Limit(20), bucket("second").Limit())
+	assert.InDelta(t, 100, bucket("first").TokensAt(clock.Now()), 0.01)
+
+	clock.Advance(time.Second)
+	assert.InDelta(t, 110, bucket("first").TokensAt(clock.Now()), 0.01)
+
+	// A zero factor stops the refills.
+	require.NoError(t, balancer.ScaleAllRates(0, time.Minute))
+	clock.Advance(30 * time.Second)
+	assert.InDelta(t, 110, bucket("first").TokensAt(clock.Now()), 0.01)
+
+	// A server updated in the meantime is scaled as well, and a new scaling replaces the previous one.
+	require.NoError(t, balancer.UpdateServer("first", dynamic.Server{Burst: Int(1000), Average: Int(5), Period: Int(1000), Priority: Int(1)}))
+	assert.Equal(t, rate.Limit(0), bucket("first").Limit())
+
+	require.NoError(t, balancer.ScaleAllRates(0.5, time.Minute))
+	assert.Equal(t, rate.Limit(2.5), bucket("first").Limit())
+	assert.Equal(t, rate.Limit(10), bucket("second").Limit())
+
+	// The previous scaling does not end the new one.
+	clock.Advance(30 * time.Second)
+	assert.Equal(t, rate.Limit(2.5), bucket("first").Limit())
+
+	clock.Advance(30 * time.Second)
+	assert.Equal(t, rate.Limit(5), bucket("first").Limit())
+	assert.Equal(t, rate.Limit(20), bucket("second").Limit())
+
+	assert.Error(t, balancer.ScaleAllRates(-1, time.Minute))
+	assert.Error(t, balancer.ScaleAllRates(2, 0))
+}
+
+func TestLBBalancerScaleAllRatesClose(t *testing.T) {
+	clock := newFakeClock()
+	balancer := New(nil, false)
+	balancer.clock = clock
+
+	balancer.Add("first", http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {}), Int(10), Int(10), Int(1000), Int(1))
+
+	require.NoError(t, balancer.ScaleAllRates(2, time.Minute))
+	balancer.Close()
+
+	// The scaling is reverted by Close, and its timer is stopped.
+	clock.Advance(time.Hour)
+
+	balancer.mutex.RLock()
+	defer balancer.mutex.RUnlock()
+
+	assert.Equal(t, rate.Limit(10), balancer.servers["first"].bucket.Limit())
+	assert.Nil(t, balancer.rateScale)
+}
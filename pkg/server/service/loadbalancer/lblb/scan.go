@@ -0,0 +1,15 @@
+package lblb
+
+// SetScanLimit caps the number of candidates considered by a selection with the priority strategy,
+// bounding its worst case on large pools where overflow goes through many rate-limited servers.
+// The selection fails once limit candidates were considered without finding an admissible one:
+// as they were all denied by their bucket, or not eligible, there is no best one to fall back to.
+// The servers beyond the limit then only get the traffic when a higher priority one is admissible,
+// so the limit should be higher than the number of servers expected to be rate-limited at the same time.
+// A non-positive limit disables it, which is the default.
+func (b *LBBalancer) SetScanLimit(limit int) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.scanLimit = max(limit, 0)
+}
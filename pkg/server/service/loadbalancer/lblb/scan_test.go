@@ -0,0 +1,49 @@
+package lblb
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLBBalancerScanLimit(t *testing.T) {
+	clock := newFakeClock()
+	balancer := New(nil, false)
+	balancer.clock = clock
+	balancer.SetScanLimit(3)
+
+	for i := range 10 {
+		name := fmt.Sprintf("srv-%d", i)
+		balancer.Add(name, http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {}), Int(1), Int(1), Int(1000), Int(i+1))
+	}
+
+	var decisions []Decision
+	balancer.SetDecisionTracer(func(d Decision) {
+		decisions = append(decisions, d)
+	})
+
+	// An admissible server within the limit is selected.
+	for i := range 3 {
+		handler, err := balancer.nextServer()
+		require.NoError(t, err)
+		assert.Equal(t, fmt.Sprintf("srv-%d", i), handler.name)
+	}
+
+	// The admissible servers beyond the limit are not considered.
+	_, err := balancer.nextServer()
+	assert.ErrorIs(t, err, errNoAvailableServer)
+
+	require.Len(t, decisions, 4)
+	assert.Len(t, decisions[2].Candidates, 3)
+	assert.Len(t, decisions[3].Candidates, 3)
+
+	// All the servers are still there, in order.
+	balancer.SetScanLimit(0)
+	handler, err := balancer.nextServer()
+	require.NoError(t, err)
+	assert.Equal(t, "srv-3", handler.name)
+	assert.Len(t, serverNames(balancer), 10)
+}
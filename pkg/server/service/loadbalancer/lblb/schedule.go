@@ -0,0 +1,186 @@
+package lblb
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// PriorityWindow is a daily time window during which a server has another priority, see SetPrioritySchedule.
+type PriorityWindow struct {
+	// Start and End are the times of day the window starts and ends at, as durations since midnight.
+	// They are wall clock times, e.g. a window starting at 9 hours starts at 9:00 even on a daylight saving time change.
+	// A window ending before it starts spans midnight.
+	Start    time.Duration `json:"start"`
+	End      time.Duration `json:"end"`
+	Priority int           `json:"priority"`
+}
+
+// contains reports whether the window contains the time of day offset.
+func (w PriorityWindow) contains(offset time.Duration) bool {
+	if w.Start < w.End {
+		return offset >= w.Start && offset < w.End
+	}
+
+	return offset >= w.Start || offset < w.End
+}
+
+// prioritySchedule is the schedule of the priority of a server.
+type prioritySchedule struct {
+	windows  []PriorityWindow
+	location *time.Location
+	// base is the priority of the server outside of the windows.
+	base int64
+	stop func() bool
+}
+
+// priorityAt returns the priority of the server at now.
+func (s *prioritySchedule) priorityAt(now time.Time) int64 {
+	offset := timeOfDay(now.In(s.location))
+
+	for _, w := range s.windows {
+		if w.contains(offset) {
+			return int64(max(w.Priority, 1))
+		}
+	}
+
+	return s.base
+}
+
+// nextChange returns the next time after now a window starts or ends at.
+func (s *prioritySchedule) nextChange(now time.Time) time.Time {
+	now = now.In(s.location)
+	tomorrow := now.AddDate(0, 0, 1)
+
+	var next time.Time
+	for _, day := range []time.Time{now, tomorrow} {
+		for _, w := range s.windows {
+			for _, boundary := range []time.Time{atTimeOfDay(day, w.Start), atTimeOfDay(day, w.End)} {
+				if boundary.After(now) && (next.IsZero() || boundary.Before(next)) {
+					next = boundary
+				}
+			}
+		}
+	}
+
+	return next
+}
+
+// timeOfDay returns the wall clock time of day of t, in its location, as a duration since midnight.
+func timeOfDay(t time.Time) time.Duration {
+	hour, minute, second := t.Clock()
+	return time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute + time.Duration(second)*time.Second + time.Duration(t.Nanosecond())
+}
+
+// atTimeOfDay returns the time at the wall clock time of day offset of the day of t, in its location.
+func atTimeOfDay(t time.Time, offset time.Duration) time.Time {
+	year, month, day := t.Date()
+	hour, minute := int(offset/time.Hour), int(offset%time.Hour/time.Minute)
+	return time.Date(year, month, day, hour, minute, 0, int(offset%time.Minute), t.Location())
+}
+
+// SetPrioritySchedule sets the daily windows during which the named server has another priority,
+// e.g. for a batch-capable server to be preferred off-peak, the times of day being in location, UTC if nil.
+// The priority of the server is changed as the windows start and end,
+// and is its configured one, e.g. by UpdateServer, outside of them.
+// When windows overlap, the first one in the list applies.
+// A priority boost in progress takes precedence, the scheduled priority applying when it ends.
+// Like the priority boosts, the schedule is removed by SetServers, and stopped by Close.
+// Setting empty windows removes the schedule, restoring the configured priority.
+// It returns an error if no such server exists, or for a window which is empty or does not fit in a day.
+func (b *LBBalancer) SetPrioritySchedule(name string, windows []PriorityWindow, location *time.Location) error {
+	for _, w := range windows {
+		if w.Start < 0 || w.Start >= 24*time.Hour || w.End < 0 || w.End >= 24*time.Hour || w.Start == w.End {
+			return fmt.Errorf("invalid priority window from %s to %s", w.Start, w.End)
+		}
+	}
+
+	if location == nil {
+		location = time.UTC
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	h, ok := b.servers[name]
+	if !ok {
+		return fmt.Errorf("unknown server %s", name)
+	}
+
+	base := b.configuredPriority(h)
+	if previous, ok := b.schedules[name]; ok {
+		previous.stop()
+		delete(b.schedules, name)
+	}
+
+	if len(windows) == 0 {
+		b.setPriority(h, base)
+		return nil
+	}
+
+	s := &prioritySchedule{windows: append([]PriorityWindow(nil), windows...), location: location, base: base}
+	b.schedules[name] = s
+	b.applySchedule(h, s)
+
+	return nil
+}
+
+// configuredPriority returns the priority of h without its schedule and boost, if any,
+// i.e. the one to give back to UpdateServer to keep its priority.
+// It must be called with the mutex held.
+func (b *LBBalancer) configuredPriority(h *namedHandler) int64 {
+	if s, ok := b.schedules[h.name]; ok {
+		return s.base
+	}
+
+	if bst, ok := b.boosts[h.name]; ok {
+		return bst.original
+	}
+
+	return h.priority
+}
+
+// setPriority sets the priority of h, or the one restored at the end of its boost, if any.
+// It must be called with the mutex held.
+func (b *LBBalancer) setPriority(h *namedHandler, priority int64) {
+	if bst, ok := b.boosts[h.name]; ok {
+		bst.original = priority
+		return
+	}
+
+	if h.priority != priority {
+		h.priority = priority
+		b.fix(h)
+	}
+}
+
+// applySchedule sets the priority of h per its schedule s, and schedules the next change.
+// It must be called with the mutex held.
+func (b *LBBalancer) applySchedule(h *namedHandler, s *prioritySchedule) {
+	now := b.clock.Now()
+
+	priority := s.priorityAt(now)
+	log.Debug().Msgf("Applying scheduled priority %d to %s", priority, h.name)
+	b.setPriority(h, priority)
+
+	s.stop = b.clock.AfterFunc(s.nextChange(now).Sub(now), func() {
+		b.mutex.Lock()
+		defer b.mutex.Unlock()
+
+		if b.schedules[h.name] != s || b.servers[h.name] != h {
+			return
+		}
+
+		b.applySchedule(h, s)
+	})
+}
+
+// stopSchedules stops the priority schedules of the servers.
+// It must be called with the mutex held.
+func (b *LBBalancer) stopSchedules() {
+	for name, s := range b.schedules {
+		s.stop()
+		delete(b.schedules, name)
+	}
+}
@@ -0,0 +1,168 @@
+package lblb
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/traefik/traefik/v3/pkg/config/dynamic"
+)
+
+func TestLBBalancerPrioritySchedule(t *testing.T) {
+	clock := newFakeClock()
+	clock.now = time.Date(2026, time.January, 1, 12, 0, 0, 0, time.UTC)
+	balancer := newBoostTestBalancer(clock)
+
+	priority := func() int64 {
+		balancer.mutex.RLock()
+		defer balancer.mutex.RUnlock()
+
+		return balancer.servers["second"].priority
+	}
+
+	require.NoError(t, balancer.SetPrioritySchedule("second", []PriorityWindow{
+		{Start: 22 * time.Hour, End: 6 * time.Hour, Priority: 1},
+		{Start: 13 * time.Hour, End: 14 * time.Hour, Priority: 1},
+		// Overlaps the previous window, which applies first.
+		{Start: 13*time.Hour + 30*time.Minute, End: 15 * time.Hour, Priority: 5},
+	}, nil))
+
+	assert.Equal(t, int64(3), priority())
+	assert.Equal(t, "first", serveOne(balancer))
+
+	clock.Advance(time.Hour - time.Nanosecond)
+	assert.Equal(t, int64(3), priority())
+
+	// 13:00.
+	clock.Advance(time.Nanosecond)
+	assert.Equal(t, int64(1), priority())
+	assert.Equal(t, "second", serveOne(balancer))
+
+	// 14:00, the overlapping window applies.
+	clock.Advance(time.Hour)
+	assert.Equal(t, int64(5), priority())
+	assert.Equal(t, "first", serveOne(balancer))
+
+	// 15:00, back to the configured priority, which an update changes.
+	clock.Advance(time.Hour)
+	assert.Equal(t, int64(3), priority())
+	require.NoError(t, balancer.UpdateServer("second", dynamic.Server{Burst: Int(100), Average: Int(100), Period: Int(1), Priority: Int(4)}))
+	assert.Equal(t, int64(4), priority())
+
+	// 22:00 to 6:00, across midnight.
+	clock.Advance(7 * time.Hour)
+	assert.Equal(t, int64(1), priority())
+	require.NoError(t, balancer.UpdateServer("second", dynamic.Server{Burst: Int(100), Average: Int(100), Period: Int(1), Priority: Int(3)}))
+	assert.Equal(t, int64(1), priority())
+
+	clock.Advance(8 * time.Hour)
+	assert.Equal(t, int64(3), priority())
+
+	// Closing the balancer stops the schedule.
+	balancer.Close()
+	clock.Advance(7 * time.Hour)
+	assert.Equal(t, int64(3), priority())
+
+	assert.Error(t, balancer.SetPrioritySchedule("unknown", nil, nil))
+	assert.Error(t, balancer.SetPrioritySchedule("second", []PriorityWindow{{Start: time.Hour, End: time.Hour}}, nil))
+	assert.Error(t, balancer.SetPrioritySchedule("second", []PriorityWindow{{Start: time.Hour, End: 24 * time.Hour}}, nil))
+}
+
+func TestLBBalancerPriorityScheduleRemoved(t *testing.T) {
+	clock := newFakeClock()
+	clock.now = time.Date(2026, time.January, 1, 12, 0, 0, 0, time.UTC)
+	balancer := newBoostTestBalancer(clock)
+
+	location := time.FixedZone("UTC+2", 2*60*60)
+	require.NoError(t, balancer.SetPrioritySchedule("second", []PriorityWindow{{Start: 14 * time.Hour, End: 15 * time.Hour, Priority: 1}}, location))
+	assert.Equal(t, "second", serveOne(balancer))
+
+	// Removing the schedule restores the configured priority, and a boost takes precedence over a schedule.
+	require.NoError(t, balancer.SetPrioritySchedule("second", nil, nil))
+	assert.Equal(t, "first", serveOne(balancer))
+
+	require.NoError(t, balancer.BoostPriority("first", 1, time.Minute))
+	require.NoError(t, balancer.SetPrioritySchedule("first", []PriorityWindow{{Start: 12 * time.Hour, End: 13 * time.Hour, Priority: 5}}, nil))
+	assert.Equal(t, "first", serveOne(balancer))
+
+	clock.Advance(time.Minute)
+	assert.Equal(t, "second", serveOne(balancer))
+}
+
+func TestLBBalancerPriorityScheduleDaylightSavingTime(t *testing.T) {
+	location, err := time.LoadLocation("Europe/Paris")
+	require.NoError(t, err)
+
+	clock := newFakeClock()
+	// The clocks go forward from 2:00 to 3:00 on March 29, 2026 in Paris.
+	clock.now = time.Date(2026, time.March, 28, 12, 0, 0, 0, location)
+	balancer := newBoostTestBalancer(clock)
+
+	priority := func() int64 {
+		balancer.mutex.RLock()
+		defer balancer.mutex.RUnlock()
+
+		return balancer.servers["second"].priority
+	}
+
+	require.NoError(t, balancer.SetPrioritySchedule("second", []PriorityWindow{{Start: 9 * time.Hour, End: 10 * time.Hour, Priority: 1}}, location))
+	assert.Equal(t, int64(3), priority())
+
+	// The window starts at 9:00 on the day of the change, 20 hours later instead of 21.
+	clock.Advance(20*time.Hour - time.Nanosecond)
+	assert.Equal(t, int64(3), priority())
+	clock.Advance(time.Nanosecond)
+	assert.Equal(t, time.Date(2026, time.March, 29, 9, 0, 0, 0, location), clock.Now())
+	assert.Equal(t, int64(1), priority())
+
+	clock.Advance(time.Hour)
+	assert.Equal(t, int64(3), priority())
+}
+
+func TestLBBalancerPriorityScheduleAutoWeighting(t *testing.T) {
+	clock := newFakeClock()
+	clock.now = time.Date(2026, time.January, 1, 12, 0, 0, 0, time.UTC)
+	balancer := New(nil, false)
+	balancer.clock = clock
+	defer balancer.Close()
+
+	latencies := map[string]time.Duration{"fast": time.Millisecond, "slow": 10 * time.Millisecond}
+	for i, name := range []string{"fast", "slow"} {
+		latency := latencies[name]
+		balancer.Add(name, http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			clock.Advance(latency)
+			rw.WriteHeader(http.StatusOK)
+		}), Int(10), Int(10), Int(1000), Int(i+1))
+	}
+
+	server := func(name string) (int64, int64) {
+		balancer.mutex.RLock()
+		defer balancer.mutex.RUnlock()
+
+		return balancer.servers[name].priority, balancer.servers[name].average
+	}
+
+	// During the window, both servers have the same priority, and both are adjusted.
+	require.NoError(t, balancer.SetPrioritySchedule("slow", []PriorityWindow{{Start: 12 * time.Hour, End: 13 * time.Hour, Priority: 1}}, nil))
+	balancer.SetAutoWeighting(context.Background(), &AutoWeighting{Interval: time.Minute, LearningRate: 0.5, MinAverage: 1})
+
+	start := clock.Now()
+	for range 20 {
+		balancer.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+		clock.Advance(time.Second)
+	}
+	clock.Advance(time.Minute - clock.Now().Sub(start))
+
+	priority, average := server("slow")
+	assert.Equal(t, int64(1), priority)
+	assert.Less(t, average, int64(10))
+
+	// Once the window ends, the configured priority is restored, despite the adjustment during the window.
+	clock.Advance(time.Hour)
+	priority, _ = server("slow")
+	assert.Equal(t, int64(2), priority)
+}
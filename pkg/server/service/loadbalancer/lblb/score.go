@@ -0,0 +1,148 @@
+package lblb
+
+import (
+	"container/heap"
+	"fmt"
+	"time"
+)
+
+// latencySmoothing is the weight of a new response time in the moving average latency of a server.
+const latencySmoothing = 0.3
+
+// ScoreWeights are the weights of the score ordering the servers, the lowest score being preferred:
+// score = Priority * priority + Latency * latency / LatencyUnit - External * external,
+// where latency is the moving average response time of the server,
+// and external the score set with SetServerScore, the highest being preferred.
+type ScoreWeights struct {
+	Priority float64 `json:"priority"`
+	Latency  float64 `json:"latency"`
+	// LatencyUnit is the latency normalizing the latency to the scale of the priorities.
+	LatencyUnit time.Duration `json:"latencyUnit"`
+	External    float64       `json:"external"`
+	// ExternalTTL is how long an external score is fully applied after being set,
+	// it then decays linearly to neutral, i.e. 0, over another ExternalTTL.
+	ExternalTTL time.Duration `json:"externalTTL"`
+}
+
+// SetDefaults sets the default values.
+func (w *ScoreWeights) SetDefaults() {
+	w.Priority = 1
+	w.Latency = 1
+	w.LatencyUnit = 100 * time.Millisecond
+	w.External = 1
+	w.ExternalTTL = time.Minute
+}
+
+// SetScoreWeights orders the servers by a score combining their priority with their measured latency,
+// instead of their priority alone, so that the balance between the static preference and the performance is tuned.
+// A server without measured latency yet is scored on its priority alone.
+// A nil weights restores the ordering by priority, which is the default.
+func (b *LBBalancer) SetScoreWeights(weights *ScoreWeights) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if weights != nil {
+		w := *weights
+		if w.LatencyUnit <= 0 {
+			w.LatencyUnit = 100 * time.Millisecond
+		}
+		if w.ExternalTTL <= 0 {
+			w.ExternalTTL = time.Minute
+		}
+		weights = &w
+	}
+
+	b.scoreWeights = weights
+	b.decayExternalScores(b.clock.Now())
+	heap.Init(b)
+}
+
+// score returns the score of h, the lowest being preferred.
+// It must be called with the mutex held.
+func (b *LBBalancer) score(h *namedHandler) float64 {
+	w := b.scoreWeights
+	return w.Priority*float64(h.priority) + w.Latency*float64(h.latency)/float64(w.LatencyUnit) - w.External*h.external.effective
+}
+
+// externalScore is the score of a server set by the caller, guarded by the balancer mutex.
+type externalScore struct {
+	value float64
+	// at is when the score was set, zero once it decayed to neutral.
+	at time.Time
+	// effective is the decayed score, as of the latest selection.
+	effective float64
+}
+
+// SetServerScore sets the score of the named server, e.g. from an external scoring system,
+// which is combined with its priority when the servers are ordered by score, see ScoreWeights,
+// the highest score being preferred.
+// Scores are meant to be updated regularly: a score not updated within ScoreWeights.ExternalTTL decays to neutral.
+// It returns an error if no such server exists.
+func (b *LBBalancer) SetServerScore(name string, score float64) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	h, ok := b.servers[name]
+	if !ok {
+		return fmt.Errorf("unknown server %s", name)
+	}
+
+	h.external = externalScore{value: score, at: b.clock.Now(), effective: score}
+	if b.scoreWeights != nil {
+		b.fix(h)
+	}
+
+	return nil
+}
+
+// decayExternalScores updates the effective external scores of the servers at now,
+// and re-orders the servers if any of them changed.
+// It must be called with the mutex held.
+func (b *LBBalancer) decayExternalScores(now time.Time) {
+	if b.scoreWeights == nil || b.scoreWeights.External == 0 {
+		return
+	}
+
+	ttl := b.scoreWeights.ExternalTTL
+
+	var changed bool
+	for _, h := range b.handlers {
+		if h.external.at.IsZero() {
+			continue
+		}
+
+		effective := h.external.value
+		if age := now.Sub(h.external.at); age >= 2*ttl {
+			effective = 0
+			h.external.at = time.Time{}
+		} else if age > ttl {
+			effective *= 1 - float64(age-ttl)/float64(ttl)
+		}
+
+		if effective != h.external.effective {
+			h.external.effective = effective
+			changed = true
+		}
+	}
+
+	if changed {
+		heap.Init(b)
+	}
+}
+
+// recordLatency updates the moving average latency of h with a response time of d,
+// and re-orders the servers accordingly when they are ordered by score.
+func (b *LBBalancer) recordLatency(h *namedHandler, d time.Duration) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if h.latency == 0 {
+		h.latency = d
+	} else {
+		h.latency = time.Duration(latencySmoothing*float64(d) + (1-latencySmoothing)*float64(h.latency))
+	}
+
+	if b.scoreWeights != nil {
+		b.fix(h)
+	}
+}
@@ -0,0 +1,107 @@
+package lblb
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLBBalancerScoreWeights(t *testing.T) {
+	clock := newFakeClock()
+	balancer := New(nil, false)
+	balancer.clock = clock
+	balancer.SetScoreWeights(&ScoreWeights{Priority: 1, Latency: 1, LatencyUnit: 100 * time.Millisecond})
+
+	// first is preferred, but slow.
+	latencies := map[string]time.Duration{"first": 200 * time.Millisecond, "second": 10 * time.Millisecond}
+	for i, name := range []string{"first", "second"} {
+		balancer.Add(name, http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			clock.Advance(latencies[name])
+			rw.Header().Set("server", name)
+			rw.WriteHeader(http.StatusOK)
+		}), Int(1000), Int(1000), Int(1), Int(i+1))
+	}
+
+	// Without measured latency, the priorities win.
+	assert.Equal(t, "first", serveOne(balancer))
+
+	// Measures the latency of second.
+	balancer.SetStatus(context.Background(), "first", false)
+	assert.Equal(t, "second", serveOne(balancer))
+	balancer.SetStatus(context.Background(), "first", true)
+
+	// Latency sensitive: 1 + 2 for first, against 2 + 0.1 for second.
+	assert.Equal(t, "second", serveOne(balancer))
+
+	// Priority sensitive: 10 + 2 for first, against 20 + 0.1 for second.
+	balancer.SetScoreWeights(&ScoreWeights{Priority: 10, Latency: 1})
+	assert.Equal(t, "first", serveOne(balancer))
+
+	// Latency only.
+	balancer.SetScoreWeights(&ScoreWeights{Latency: 1})
+	assert.Equal(t, "second", serveOne(balancer))
+
+	// Back to the priorities.
+	balancer.SetScoreWeights(nil)
+	assert.Equal(t, "first", serveOne(balancer))
+}
+
+func TestLBBalancerScoreLatencyAverage(t *testing.T) {
+	clock := newFakeClock()
+	balancer := New(nil, false)
+	balancer.clock = clock
+	balancer.SetScoreWeights(&ScoreWeights{Latency: 1})
+
+	latency := 100 * time.Millisecond
+	balancer.Add("first", http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		clock.Advance(latency)
+	}), Int(1000), Int(1000), Int(1), Int(1))
+
+	serveOne(balancer)
+	assert.Equal(t, 100*time.Millisecond, balancer.servers["first"].latency)
+
+	latency = 200 * time.Millisecond
+	serveOne(balancer)
+	assert.Equal(t, 130*time.Millisecond, balancer.servers["first"].latency)
+}
+
+func TestLBBalancerServerScore(t *testing.T) {
+	clock := newFakeClock()
+	balancer := New(nil, false)
+	balancer.clock = clock
+	balancer.SetScoreWeights(&ScoreWeights{Priority: 1, External: 1, ExternalTTL: time.Second})
+
+	for i, name := range []string{"first", "second"} {
+		balancer.Add(name, http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			rw.Header().Set("server", name)
+			rw.WriteHeader(http.StatusOK)
+		}), Int(1000), Int(1000), Int(1), Int(i+1))
+	}
+
+	assert.Equal(t, "first", serveOne(balancer))
+
+	// 1 for first, against 2 - 5 for second.
+	assert.NoError(t, balancer.SetServerScore("second", 5))
+	assert.Equal(t, "second", serveOne(balancer))
+
+	// Once stale, the score decays: 2 - 2.5 for second.
+	clock.Advance(1500 * time.Millisecond)
+	assert.Equal(t, "second", serveOne(balancer))
+
+	// 2 - 0.5 for second.
+	clock.Advance(400 * time.Millisecond)
+	assert.Equal(t, "first", serveOne(balancer))
+
+	// Fully decayed, until updated again.
+	clock.Advance(time.Second)
+	assert.Equal(t, "first", serveOne(balancer))
+	assert.Zero(t, balancer.servers["second"].external.effective)
+
+	assert.NoError(t, balancer.SetServerScore("second", 5))
+	assert.Equal(t, "second", serveOne(balancer))
+
+	assert.Error(t, balancer.SetServerScore("unknown", 1))
+}
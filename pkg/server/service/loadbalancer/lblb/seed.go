@@ -0,0 +1,61 @@
+package lblb
+
+import (
+	"math"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// SetSeedBucketsOnReload sets whether SetServers seeds the buckets of the new servers
+// with the tokens left in the buckets of the previous servers of the same name, instead of full buckets,
+// so that a reload does not let a spike of requests through.
+// The servers without a previous server of the same name still start with full buckets.
+func (b *LBBalancer) SetSeedBucketsOnReload(enabled bool) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.seedBucketsOnReload = enabled
+}
+
+// ExportBucketState returns the tokens currently available in the buckets of the servers, by server name,
+// to be restored with ImportBucketState, e.g. by another balancer instance.
+func (b *LBBalancer) ExportBucketState() map[string]float64 {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	now := b.clock.Now()
+
+	state := make(map[string]float64, len(b.handlers))
+	for _, h := range b.handlers {
+		state[h.name] = h.bucket.TokensAt(now)
+	}
+
+	return state
+}
+
+// ImportBucketState sets the tokens available in the buckets of the servers, by server name, up to their burst.
+// The servers unknown to the balancer are ignored.
+func (b *LBBalancer) ImportBucketState(state map[string]float64) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	now := b.clock.Now()
+
+	for name, tokens := range state {
+		if h, ok := b.servers[name]; ok {
+			h.bucket = seededLimiter(h.bucket.Limit(), h.bucket.Burst(), tokens, now)
+		}
+	}
+}
+
+// seededLimiter returns a limiter holding the given tokens at now, rounded down to a whole number.
+func seededLimiter(limit rate.Limit, burst int, tokens float64, now time.Time) *rate.Limiter {
+	limiter := rate.NewLimiter(limit, burst)
+
+	if missing := math.Ceil(float64(burst) - max(tokens, 0)); missing > 0 {
+		limiter.ReserveN(now, int(missing))
+	}
+
+	return limiter
+}
@@ -0,0 +1,101 @@
+package lblb
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/traefik/traefik/v3/pkg/config/dynamic"
+)
+
+func TestLBBalancerSeedBucketsOnReload(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		seed     bool
+		expected int
+	}{
+		{
+			desc:     "full buckets",
+			expected: 20,
+		},
+		{
+			desc:     "seeded buckets",
+			seed:     true,
+			expected: 0,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			clock := newFakeClock()
+			balancer := New(nil, false)
+			balancer.clock = clock
+			balancer.SetSeedBucketsOnReload(test.seed)
+
+			handler := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+				rw.WriteHeader(http.StatusOK)
+			})
+			servers := []Server{
+				{Name: "first", Handler: handler, Config: dynamic.Server{Burst: Int(10), Average: Int(1), Period: Int(3600000), Priority: Int(1)}},
+				{Name: "second", Handler: handler, Config: dynamic.Server{Burst: Int(10), Average: Int(1), Period: Int(3600000), Priority: Int(2)}},
+			}
+			require.NoError(t, balancer.SetServers(context.Background(), servers))
+
+			admitted := func(n int) int {
+				var count int
+				for range n {
+					recorder := httptest.NewRecorder()
+					balancer.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+					if recorder.Code == http.StatusOK {
+						count++
+					}
+				}
+				return count
+			}
+
+			// The load empties the buckets.
+			assert.Equal(t, 20, admitted(30))
+
+			require.NoError(t, balancer.SetServers(context.Background(), servers))
+			assert.Equal(t, test.expected, admitted(30))
+		})
+	}
+}
+
+func TestLBBalancerBucketState(t *testing.T) {
+	clock := newFakeClock()
+	balancer := New(nil, false)
+	balancer.clock = clock
+
+	for i, name := range []string{"first", "second"} {
+		balancer.Add(name, http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {}), Int(10), Int(1), Int(3600000), Int(i+1))
+	}
+
+	for range 3 {
+		_, err := balancer.nextServer()
+		require.NoError(t, err)
+	}
+
+	state := balancer.ExportBucketState()
+	assert.InDelta(t, 7, state["first"], 1e-6)
+	assert.InDelta(t, 10, state["second"], 1e-6)
+
+	restored := New(nil, false)
+	restored.clock = clock
+	for i, name := range []string{"first", "second", "third"} {
+		restored.Add(name, http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {}), Int(10), Int(1), Int(3600000), Int(i+1))
+	}
+
+	restored.ImportBucketState(map[string]float64{"first": 7, "second": 2.5, "unknown": 1})
+
+	state = restored.ExportBucketState()
+	assert.InDelta(t, 7, state["first"], 1e-6)
+	// The tokens are rounded down.
+	assert.InDelta(t, 2, state["second"], 1e-6)
+	assert.InDelta(t, 10, state["third"], 1e-6)
+}
@@ -0,0 +1,40 @@
+package lblb
+
+// singleUp returns the only up server, or nil if there are several, or none.
+// It must be called with the mutex held.
+func (b *LBBalancer) singleUp() *namedHandler {
+	if len(b.status) != 1 {
+		return nil
+	}
+
+	for name := range b.status {
+		return b.servers[name]
+	}
+
+	return nil
+}
+
+// pickSingle selects h, the only up server, which is the only possible selection whatever the strategy,
+// without walking the heap: it is selected if it would be by the general path,
+// i.e. if it is not excluded, draining, nor in cooldown, and if it is allowed by its bucket unless bypassed.
+// Only h is recorded as a candidate in decision if it is not nil, the down servers not being considered.
+// It must be called with the mutex held.
+func (b *LBBalancer) pickSingle(decision *Decision, sel selection, h *namedHandler) (*namedHandler, error) {
+	now := b.clock.Now()
+
+	if reason := b.skipReason(h, sel, now); reason != "" {
+		decision.add(h, reason)
+		return nil, errNoAvailableServer
+	}
+
+	if !sel.bypass {
+		if !b.admitCandidate(h, sel, now) {
+			decision.add(h, skipRateLimited)
+			return nil, errNoAvailableServer
+		}
+	}
+
+	decision.add(h, "")
+
+	return h, nil
+}
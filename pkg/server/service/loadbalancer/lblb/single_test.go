@@ -0,0 +1,100 @@
+package lblb
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLBBalancerSingleUpServer(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		setup    func(b *LBBalancer)
+		sel      selection
+		expected string
+	}{
+		{desc: "allowed", expected: "only"},
+		{
+			desc:  "throttled",
+			setup: func(b *LBBalancer) { b.servers["only"].bucket.AllowN(b.clock.Now(), 1) },
+		},
+		{
+			desc:     "throttled but bypassed",
+			setup:    func(b *LBBalancer) { b.servers["only"].bucket.AllowN(b.clock.Now(), 1) },
+			sel:      selection{bypass: true},
+			expected: "only",
+		},
+		{
+			desc:  "draining",
+			setup: func(b *LBBalancer) { b.SetDraining("only", true) },
+		},
+		{
+			desc:  "in cooldown",
+			setup: func(b *LBBalancer) { b.serverAvailability["only"] = b.clock.Now().Add(time.Second) },
+		},
+		{
+			desc: "excluded",
+			sel:  selection{excluded: []string{"only"}},
+		},
+		{
+			desc:     "other strategy",
+			sel:      selection{strategy: StrategyMostTokens},
+			expected: "only",
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			clock := newFakeClock()
+			newBalancer := func() *LBBalancer {
+				balancer := New(nil, false)
+				balancer.clock = clock
+				for i, name := range []string{"down", "only", "other"} {
+					balancer.Add(name, http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {}), Int(1), Int(1), Int(1000), Int(i+1))
+				}
+				balancer.SetStatus(context.Background(), "down", false)
+				balancer.SetStatus(context.Background(), "other", false)
+				if test.setup != nil {
+					test.setup(balancer)
+				}
+				return balancer
+			}
+
+			// The general path, walking the heap.
+			general := newBalancer()
+			general.mutex.Lock()
+			expected, expectedErr := general.pickServer(nil, test.sel)
+			general.mutex.Unlock()
+
+			var decisions []Decision
+			single := newBalancer()
+			single.SetDecisionTracer(func(d Decision) { decisions = append(decisions, d) })
+			handler, err := single.selectServer(test.sel)
+
+			if test.expected == "" {
+				require.ErrorIs(t, expectedErr, errNoAvailableServer)
+				require.ErrorIs(t, err, errNoAvailableServer)
+			} else {
+				require.NoError(t, expectedErr)
+				require.NoError(t, err)
+				assert.Equal(t, test.expected, expected.name)
+				assert.Equal(t, test.expected, handler.name)
+			}
+
+			now := clock.Now()
+			assert.InDelta(t, general.servers["only"].bucket.TokensAt(now), single.servers["only"].bucket.TokensAt(now), 0)
+			assert.Equal(t, general.servers["only"].throttled.Load(), single.servers["only"].throttled.Load())
+
+			// Only the up server is considered.
+			require.Len(t, decisions, 1)
+			require.Len(t, decisions[0].Candidates, 1)
+			assert.Equal(t, "only", decisions[0].Candidates[0].Name)
+		})
+	}
+}
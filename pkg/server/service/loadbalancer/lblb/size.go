@@ -0,0 +1,90 @@
+package lblb
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+var errResponseTooLarge = errors.New("response too large")
+
+// SetMaxResponseSize sets the maximum size, in bytes, of the response bodies of the named server,
+// protecting the clients from a misbehaving server streaming unbounded data.
+// A response exceeding it is replaced with a 502 if nothing was sent yet,
+// and otherwise aborted by closing the connection, as its headers are already sent.
+// Every violation is logged and counted in the stats.
+// A non-positive size disables the limit, which is the default.
+// It returns an error if no such server exists.
+func (b *LBBalancer) SetMaxResponseSize(name string, size int64) error {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	h, ok := b.servers[name]
+	if !ok {
+		return fmt.Errorf("unknown server %s", name)
+	}
+
+	h.maxResponseSize.Store(max(size, 0))
+
+	return nil
+}
+
+// sizeLimiter is a http.ResponseWriter failing the writes beyond a maximum body size.
+type sizeLimiter struct {
+	http.ResponseWriter
+
+	limit       int64
+	written     int64
+	wroteHeader bool
+	exceeded    bool
+	// truncated is whether the limit was exceeded after the response was sent, which then needs to be aborted.
+	truncated bool
+}
+
+func (l *sizeLimiter) WriteHeader(statusCode int) {
+	// Informational responses are not final, and the headers can still be changed after them.
+	if statusCode >= http.StatusOK {
+		l.wroteHeader = true
+	}
+
+	l.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (l *sizeLimiter) Write(b []byte) (int, error) {
+	if l.exceeded {
+		return 0, errResponseTooLarge
+	}
+
+	if l.written+int64(len(b)) > l.limit {
+		l.exceeded = true
+
+		if l.wroteHeader {
+			l.truncated = true
+		} else {
+			l.wroteHeader = true
+			http.Error(l.ResponseWriter, http.StatusText(http.StatusBadGateway), http.StatusBadGateway)
+		}
+
+		return 0, errResponseTooLarge
+	}
+
+	l.wroteHeader = true
+	n, err := l.ResponseWriter.Write(b)
+	l.written += int64(n)
+
+	return n, err
+}
+
+// Flush implements http.Flusher.
+func (l *sizeLimiter) Flush() {
+	l.wroteHeader = true
+
+	if flusher, ok := l.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap returns the underlying http.ResponseWriter, for http.ResponseController.
+func (l *sizeLimiter) Unwrap() http.ResponseWriter {
+	return l.ResponseWriter
+}
@@ -0,0 +1,62 @@
+package lblb
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLBBalancerMaxResponseSize(t *testing.T) {
+	balancer := New(nil, false)
+
+	balancer.Add("first", http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		size := len(req.URL.Query().Get("body"))
+		if req.URL.Query().Get("header") != "" {
+			rw.WriteHeader(http.StatusOK)
+		}
+
+		// The body is written in two chunks.
+		body := req.URL.Query().Get("body")
+		_, err := rw.Write([]byte(body[:size/2]))
+		if err != nil {
+			return
+		}
+		_, _ = rw.Write([]byte(body[size/2:]))
+	}), Int(100), Int(100), Int(1000), Int(1))
+
+	require.NoError(t, balancer.SetMaxResponseSize("first", 10))
+	assert.Error(t, balancer.SetMaxResponseSize("unknown", 10))
+
+	// Under the limit, the response passes through.
+	recorder := httptest.NewRecorder()
+	balancer.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/?body=0123456789", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "0123456789", recorder.Body.String())
+
+	// Over the limit with nothing sent yet, the response is replaced.
+	recorder = httptest.NewRecorder()
+	balancer.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/?body="+strings.Repeat("a", 30), nil))
+	assert.Equal(t, http.StatusBadGateway, recorder.Code)
+	assert.Equal(t, "Bad Gateway\n", recorder.Body.String())
+
+	// Over the limit once the response is sent, it is aborted.
+	recorder = httptest.NewRecorder()
+	assert.PanicsWithValue(t, http.ErrAbortHandler, func() {
+		balancer.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/?header=1&body="+strings.Repeat("b", 16), nil))
+	})
+	assert.Equal(t, strings.Repeat("b", 8), recorder.Body.String())
+
+	stats := balancer.Stats().Servers["first"]
+	assert.Equal(t, uint64(2), stats.Oversized)
+	assert.Equal(t, map[string]uint64{"2xx": 2, "5xx": 1}, stats.Responses)
+
+	// Once disabled, large responses pass through.
+	require.NoError(t, balancer.SetMaxResponseSize("first", 0))
+	recorder = httptest.NewRecorder()
+	balancer.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/?body="+strings.Repeat("c", 30), nil))
+	assert.Equal(t, strings.Repeat("c", 30), recorder.Body.String())
+}
@@ -0,0 +1,58 @@
+package lblb
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/rs/zerolog/log"
+)
+
+// decisionStreamBuffer is the number of decisions buffered per stream subscriber.
+// Decisions are dropped for a subscriber which does not keep up.
+const decisionStreamBuffer = 64
+
+// DecisionStreamHandler returns a http.Handler streaming the selection decisions as Server-Sent Events,
+// as they happen, until the client disconnects.
+func (b *LBBalancer) DecisionStreamHandler() http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		flusher, ok := rw.(http.Flusher)
+		if !ok {
+			http.Error(rw, "streaming unsupported", http.StatusInternalServerError)
+			return
+		}
+
+		decisions := make(chan Decision, decisionStreamBuffer)
+		remove := b.AddSelectionObserver(func(d Decision) {
+			select {
+			case decisions <- d:
+			default:
+			}
+		})
+		defer remove()
+
+		rw.Header().Set("Content-Type", "text/event-stream")
+		rw.Header().Set("Cache-Control", "no-cache")
+		rw.Header().Set("Connection", "keep-alive")
+		rw.WriteHeader(http.StatusOK)
+		flusher.Flush()
+
+		for {
+			select {
+			case <-req.Context().Done():
+				return
+			case d := <-decisions:
+				data, err := json.Marshal(d)
+				if err != nil {
+					log.Ctx(req.Context()).Error().Err(err).Msg("Error while marshaling leaky bucket decision")
+					continue
+				}
+
+				if _, err := fmt.Fprintf(rw, "event: decision\ndata: %s\n\n", data); err != nil {
+					return
+				}
+				flusher.Flush()
+			}
+		}
+	})
+}
@@ -0,0 +1,109 @@
+package lblb
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLBBalancerDecisionStream(t *testing.T) {
+	balancer := New(nil, false)
+
+	balancer.Add("first", http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusOK)
+	}), Int(2), Int(1), Int(1000), Int(1))
+
+	balancer.Add("second", http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusOK)
+	}), Int(1), Int(1), Int(1000), Int(2))
+
+	server := httptest.NewServer(balancer.DecisionStreamHandler())
+	t.Cleanup(server.Close)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// Two concurrent subscribers receive the same events.
+	var readers []*bufio.Reader
+	for range 2 {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+		require.NoError(t, err)
+
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = resp.Body.Close() })
+
+		assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+		readers = append(readers, bufio.NewReader(resp.Body))
+	}
+
+	for range 4 {
+		balancer.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	}
+
+	for _, reader := range readers {
+		var selected []string
+		for range 4 {
+			d := readDecisionEvent(t, reader)
+			selected = append(selected, d.Selected)
+		}
+
+		assert.Equal(t, []string{"first", "first", "second", ""}, selected)
+	}
+}
+
+func TestLBBalancerDecisionStreamUnsubscribe(t *testing.T) {
+	balancer := New(nil, false)
+
+	server := httptest.NewServer(balancer.DecisionStreamHandler())
+	t.Cleanup(server.Close)
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	require.NoError(t, err)
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = resp.Body.Close() })
+
+	balancer.mutex.RLock()
+	assert.Len(t, balancer.observers, 1)
+	balancer.mutex.RUnlock()
+
+	cancel()
+
+	assert.Eventually(t, func() bool {
+		balancer.mutex.RLock()
+		defer balancer.mutex.RUnlock()
+
+		return len(balancer.observers) == 0
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func readDecisionEvent(t *testing.T, reader *bufio.Reader) Decision {
+	t.Helper()
+
+	for {
+		line, err := reader.ReadString('\n')
+		require.NoError(t, err)
+
+		data, ok := strings.CutPrefix(line, "data: ")
+		if !ok {
+			continue
+		}
+
+		var d Decision
+		require.NoError(t, json.Unmarshal([]byte(data), &d))
+
+		return d
+	}
+}
@@ -0,0 +1,98 @@
+package lblb
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+var errStartupRamp = errors.New("startup admission ramp exceeded")
+
+// startupRamp is the ramp of the admission of the balancer as a whole during its startup phase.
+type startupRamp struct {
+	start    time.Time
+	duration time.Duration
+	initial  float64
+
+	mu sync.Mutex
+	// credit is the fraction of a request admitted so far, a request being admitted by each whole credit.
+	credit float64
+}
+
+// SetStartupRamp ramps the admission of the balancer as a whole during the given duration from its creation with New,
+// smoothing the cold start of the servers added at startup, which all admit their full burst at once:
+// the fraction of the requests admitted grows linearly from initial, between 0 and 1, to all of them,
+// on top of the buckets of the servers and of their own warm-up settings.
+// A request denied by the ramp is rejected as by a cap of the balancer, see SetCapRejection,
+// and counted with the RejectStartupRamp reason, while a request which could not be dispatched to any server
+// does not count toward the ramp. The bypassing requests are not ramped.
+// A non-positive duration disables the ramp, which is the default, and so does the end of the startup phase.
+func (b *LBBalancer) SetStartupRamp(duration time.Duration, initial float64) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if duration <= 0 {
+		b.startupRamp = nil
+		return
+	}
+
+	b.startupRamp = &startupRamp{start: b.created, duration: duration, initial: min(max(initial, 0), 1)}
+}
+
+// fraction returns the fraction of the requests admitted at now, 1 after the startup phase.
+func (r *startupRamp) fraction(now time.Time) float64 {
+	elapsed := now.Sub(r.start)
+	if elapsed >= r.duration {
+		return 1
+	}
+
+	return r.initial + (1-r.initial)*float64(max(elapsed, 0))/float64(r.duration)
+}
+
+// allowStartup reports whether the startup ramp admits a request,
+// and whether it took a credit of the ramp to do so, which is to be given back with refundStartup.
+func (b *LBBalancer) allowStartup() (allowed, taken bool) {
+	b.mutex.RLock()
+	ramp := b.startupRamp
+	now := b.clock.Now()
+	b.mutex.RUnlock()
+
+	if ramp == nil {
+		return true, false
+	}
+
+	fraction := ramp.fraction(now)
+	if fraction >= 1 {
+		return true, false
+	}
+
+	ramp.mu.Lock()
+	defer ramp.mu.Unlock()
+
+	// A request is denied only without a whole credit, so that the credit never exceeds 2,
+	// and the requests denied earlier do not let a burst through later.
+	ramp.credit += fraction
+	if ramp.credit < 1 {
+		return false, false
+	}
+
+	ramp.credit--
+
+	return true, true
+}
+
+// refundStartup gives back the credit taken by a request which could not be dispatched to any server.
+func (b *LBBalancer) refundStartup() {
+	b.mutex.RLock()
+	ramp := b.startupRamp
+	b.mutex.RUnlock()
+
+	if ramp == nil {
+		return
+	}
+
+	ramp.mu.Lock()
+	defer ramp.mu.Unlock()
+
+	ramp.credit++
+}
@@ -0,0 +1,87 @@
+package lblb
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLBBalancerStartupRamp(t *testing.T) {
+	clock := newFakeClock()
+	balancer := New(nil, false)
+	balancer.clock = clock
+	balancer.created = clock.Now()
+	balancer.SetStartupRamp(10*time.Second, 0.1)
+
+	for _, name := range []string{"first", "second"} {
+		balancer.Add(name, http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			rw.WriteHeader(http.StatusOK)
+		}), Int(10000), Int(10000), Int(1000), Int(1))
+	}
+
+	admitted := func() int {
+		var count int
+		for range 100 {
+			recorder := httptest.NewRecorder()
+			balancer.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+			if recorder.Code == http.StatusOK {
+				count++
+			}
+		}
+		return count
+	}
+
+	// The admitted fraction grows linearly from 10% to 100% of the requests.
+	assert.InDelta(t, 10, admitted(), 1)
+
+	clock.Advance(5 * time.Second)
+	assert.InDelta(t, 55, admitted(), 1)
+
+	clock.Advance(4 * time.Second)
+	assert.InDelta(t, 91, admitted(), 1)
+
+	// After the startup phase, all the requests are admitted.
+	clock.Advance(time.Second)
+	assert.Equal(t, 100, admitted())
+
+	stats := balancer.Stats()
+	assert.Equal(t, stats.Rejected, stats.RejectionReasons[RejectStartupRamp])
+	assert.InDelta(t, 144, stats.RejectionReasons[RejectStartupRamp], 3)
+}
+
+func TestLBBalancerStartupRampRefund(t *testing.T) {
+	clock := newFakeClock()
+	balancer := New(nil, false)
+	balancer.clock = clock
+	balancer.created = clock.Now()
+	balancer.SetStartupRamp(10*time.Second, 0.5)
+
+	// Tokens are not refilled during the test.
+	balancer.Add("first", http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusOK)
+	}), Int(1), Int(1), Int(3600000), Int(1))
+
+	ramp := balancer.startupRamp
+	credit := func() float64 {
+		ramp.mu.Lock()
+		defer ramp.mu.Unlock()
+
+		return ramp.credit
+	}
+
+	// Every other request is admitted by the ramp, the bucket of first being used up by the first admitted one.
+	for range 5 {
+		balancer.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	}
+	assert.InDelta(t, 1.5, credit(), 0.01)
+
+	// After the startup phase, the requests which could not be dispatched took no credit, and are not given any back.
+	clock.Advance(10 * time.Second)
+	for range 3 {
+		balancer.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	}
+	assert.InDelta(t, 1.5, credit(), 0.01)
+}
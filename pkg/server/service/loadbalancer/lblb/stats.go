@@ -0,0 +1,228 @@
+package lblb
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Stats is a snapshot of the counters of the balancer.
+type Stats struct {
+	// Rejected is the number of requests which could not be dispatched to any server.
+	Rejected uint64 `json:"rejected"`
+	// Capped is the number of rejected requests which were rejected by a cap of the balancer as a whole,
+	// e.g. its concurrency limit, rather than because no server could admit them.
+	Capped uint64 `json:"capped"`
+	// RejectionReasons are the numbers of rejected requests by reason, see the Reject values.
+	// A reason without any rejected request is omitted.
+	RejectionReasons map[string]uint64 `json:"rejectionReasons,omitempty"`
+	// Bypassed is the number of requests which bypassed the rate limiting with the bypass token.
+	Bypassed uint64                 `json:"bypassed"`
+	Servers  map[string]ServerStats `json:"servers"`
+	// StickySessions are the stats of the sticky sessions, when tracked, see SetStickySessionTracking.
+	StickySessions *StickySessionStats `json:"stickySessions,omitempty"`
+	// Cache are the stats of the response cache, when enabled, see SetResponseCache.
+	Cache *CacheStats `json:"cache,omitempty"`
+	// Children are the stats of the child balancers added with AddChild, by name.
+	Children map[string]Stats `json:"children,omitempty"`
+}
+
+// CacheStats is a snapshot of the counters of the response cache.
+type CacheStats struct {
+	// Entries is the number of cached responses, including the expired ones not evicted yet.
+	Entries int `json:"entries"`
+	// Hits is the number of requests replied from the cache.
+	Hits uint64 `json:"hits"`
+}
+
+// ServerStats is a snapshot of the counters of a server.
+type ServerStats struct {
+	Up bool `json:"up"`
+	// Disabled is whether the server is registered as disabled, see SetZeroAverageDisabled, in which case it is not up.
+	Disabled bool `json:"disabled,omitempty"`
+	// Served is the number of requests dispatched to the server.
+	Served uint64 `json:"served"`
+	// Throttled is the number of times the bucket of the server denied a request, before any dispatch.
+	// A throttled request may still have been dispatched to another server.
+	Throttled uint64 `json:"throttled"`
+	// Oversized is the number of responses of the server which exceeded its maximum response size.
+	Oversized uint64 `json:"oversized"`
+	// ClientCanceled is the number of requests canceled by their client before the end of the response of the server,
+	// which are not counted in the responses, nor by the outlier detection.
+	ClientCanceled uint64 `json:"clientCanceled"`
+	// Responses are the number of responses of the server by status class, e.g. "2xx".
+	// A class without any response is omitted.
+	Responses map[string]uint64 `json:"responses,omitempty"`
+	// Ejections is the number of times the server was ejected by the outlier detection.
+	Ejections uint64 `json:"ejections"`
+	// Readmissions is the number of times the server was re-admitted after an ejection.
+	Readmissions uint64 `json:"readmissions"`
+	// EjectedUntil is the end of the current ejection, zero if the server is not ejected.
+	EjectedUntil time.Time `json:"ejectedUntil,omitempty"`
+	// HalfOpen is whether the server is being probed after its ejection, see OutlierDetection.HalfOpen.
+	HalfOpen bool `json:"halfOpen,omitempty"`
+}
+
+// Stats returns a snapshot of the counters of the balancer.
+func (b *LBBalancer) Stats() Stats {
+	b.mutex.RLock()
+	stats := b.serverStats()
+	children := b.childrenSnapshot()
+	b.mutex.RUnlock()
+
+	// The children are queried without the mutex held, see HealthSnapshot.
+	for name, child := range children {
+		if stats.Children == nil {
+			stats.Children = make(map[string]Stats, len(children))
+		}
+		stats.Children[name] = child.Stats()
+	}
+
+	return stats
+}
+
+// serverStats returns a snapshot of the counters of the balancer, without its children.
+// It must be called with the mutex held.
+func (b *LBBalancer) serverStats() Stats {
+	stats := Stats{
+		Rejected: b.rejected.Load(),
+		Capped:   b.capped.Load(),
+		Bypassed: b.bypassed.Load(),
+		Servers:  make(map[string]ServerStats, len(b.handlers)),
+
+		RejectionReasons: b.rejections.snapshot(),
+	}
+	for _, h := range b.handlers {
+		stats.Servers[h.name] = b.handlerStats(h)
+	}
+	if b.stickySessions != nil {
+		stats.StickySessions = b.stickySessions.snapshot(b.clock.Now())
+	}
+	if b.responseCache != nil {
+		entries, hits := b.responseCache.stats()
+		stats.Cache = &CacheStats{Entries: entries, Hits: hits}
+	}
+
+	return stats
+}
+
+// handlerStats returns a snapshot of the counters of h.
+// It must be called with the mutex held.
+func (b *LBBalancer) handlerStats(h *namedHandler) ServerStats {
+	_, up := b.status[h.name]
+
+	server := ServerStats{
+		Up:        up,
+		Disabled:  h.disabled,
+		Served:    h.served.Load(),
+		Throttled: h.throttled.Load(),
+		Oversized: h.oversized.Load(),
+		Ejections: h.outlier.ejections,
+
+		ClientCanceled: h.clientCanceled.Load(),
+		Readmissions:   h.outlier.readmissions,
+		HalfOpen:       h.outlier.halfOpen,
+	}
+	if h.outlier.ejected {
+		server.EjectedUntil = b.serverAvailability[h.name]
+	}
+
+	for i := range h.responses {
+		if count := h.responses[i].Load(); count > 0 {
+			if server.Responses == nil {
+				server.Responses = make(map[string]uint64)
+			}
+			server.Responses[strconv.Itoa(i+1)+"xx"] = count
+		}
+	}
+
+	return server
+}
+
+// persistedStats are the counters saved by MarshalStats.
+type persistedStats struct {
+	Rejected uint64                          `json:"rejected"`
+	Capped   uint64                          `json:"capped"`
+	Bypassed uint64                          `json:"bypassed"`
+	Servers  map[string]persistedServerStats `json:"servers"`
+
+	RejectionReasons map[string]uint64 `json:"rejectionReasons,omitempty"`
+}
+
+type persistedServerStats struct {
+	Served       uint64            `json:"served"`
+	Throttled    uint64            `json:"throttled"`
+	Oversized    uint64            `json:"oversized"`
+	Responses    map[string]uint64 `json:"responses,omitempty"`
+	Ejections    uint64            `json:"ejections"`
+	Readmissions uint64            `json:"readmissions"`
+
+	ClientCanceled uint64 `json:"clientCanceled"`
+}
+
+// MarshalStats serializes the counters of the balancer, to be restored with LoadStats, e.g. after a restart.
+// The state of the buckets is not part of it.
+func (b *LBBalancer) MarshalStats() ([]byte, error) {
+	stats := b.Stats()
+
+	persisted := persistedStats{
+		Rejected: stats.Rejected,
+		Capped:   stats.Capped,
+		Bypassed: stats.Bypassed,
+		Servers:  make(map[string]persistedServerStats, len(stats.Servers)),
+
+		RejectionReasons: stats.RejectionReasons,
+	}
+	for name, server := range stats.Servers {
+		persisted.Servers[name] = persistedServerStats{
+			Served:       server.Served,
+			Throttled:    server.Throttled,
+			Oversized:    server.Oversized,
+			Responses:    server.Responses,
+			Ejections:    server.Ejections,
+			Readmissions: server.Readmissions,
+
+			ClientCanceled: server.ClientCanceled,
+		}
+	}
+
+	return json.Marshal(persisted)
+}
+
+// LoadStats restores counters serialized by MarshalStats.
+// The restored counters are added to the current ones,
+// and the counters of servers unknown to the balancer are ignored.
+func (b *LBBalancer) LoadStats(data []byte) error {
+	var persisted persistedStats
+	if err := json.Unmarshal(data, &persisted); err != nil {
+		return fmt.Errorf("unmarshaling stats: %w", err)
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.rejected.Add(persisted.Rejected)
+	b.capped.Add(persisted.Capped)
+	b.rejections.add(persisted.RejectionReasons)
+	b.bypassed.Add(persisted.Bypassed)
+
+	for _, h := range b.handlers {
+		server, ok := persisted.Servers[h.name]
+		if !ok {
+			continue
+		}
+
+		h.served.Add(server.Served)
+		h.throttled.Add(server.Throttled)
+		h.oversized.Add(server.Oversized)
+		h.clientCanceled.Add(server.ClientCanceled)
+		for i := range h.responses {
+			h.responses[i].Add(server.Responses[strconv.Itoa(i+1)+"xx"])
+		}
+		h.outlier.ejections += server.Ejections
+		h.outlier.readmissions += server.Readmissions
+	}
+
+	return nil
+}
@@ -0,0 +1,121 @@
+package lblb
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLBBalancerMarshalStats(t *testing.T) {
+	newBalancer := func() *LBBalancer {
+		balancer := New(nil, false)
+
+		balancer.Add("first", http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			rw.WriteHeader(http.StatusOK)
+		}), Int(2), Int(1), Int(1000), Int(1))
+
+		balancer.Add("second", http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			rw.WriteHeader(http.StatusOK)
+		}), Int(1), Int(1), Int(1000), Int(2))
+
+		return balancer
+	}
+
+	balancer := newBalancer()
+	for range 5 {
+		balancer.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	}
+
+	stats := balancer.Stats()
+	assert.Equal(t, uint64(2), stats.Servers["first"].Served)
+	assert.Equal(t, uint64(1), stats.Servers["second"].Served)
+	assert.Equal(t, uint64(2), stats.Rejected)
+
+	data, err := balancer.MarshalStats()
+	require.NoError(t, err)
+
+	// A new balancer, e.g. after a restart, continues from the saved counters.
+	restarted := newBalancer()
+	restarted.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	require.NoError(t, restarted.LoadStats(data))
+
+	stats = restarted.Stats()
+	assert.Equal(t, uint64(3), stats.Servers["first"].Served)
+	assert.Equal(t, uint64(1), stats.Servers["second"].Served)
+	assert.Equal(t, uint64(2), stats.Rejected)
+
+	// Round-tripping again keeps the counters.
+	data, err = restarted.MarshalStats()
+	require.NoError(t, err)
+
+	restarted = newBalancer()
+	require.NoError(t, restarted.LoadStats(data))
+	assert.Equal(t, stats, restarted.Stats())
+
+	assert.Error(t, restarted.LoadStats([]byte("not json")))
+}
+
+func TestLBBalancerStatusClassStats(t *testing.T) {
+	balancer := New(nil, false)
+
+	balancer.Add("first", http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case "/redirect":
+			http.Redirect(rw, req, "/", http.StatusFound)
+		case "/missing":
+			http.NotFound(rw, req)
+		case "/error":
+			rw.WriteHeader(http.StatusBadGateway)
+		case "/hinted":
+			// The informational responses are not counted, only the final ones.
+			rw.WriteHeader(http.StatusEarlyHints)
+			rw.WriteHeader(http.StatusBadGateway)
+		case "/implicit":
+			_, _ = rw.Write([]byte("ok"))
+		default:
+			rw.WriteHeader(http.StatusNoContent)
+		}
+	}), Int(100), Int(100), Int(1), Int(1))
+
+	balancer.Add("second", http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusOK)
+	}), Int(100), Int(100), Int(1), Int(2))
+
+	for _, path := range []string{"/", "/implicit", "/redirect", "/missing", "/missing", "/error", "/error", "/hinted", "/noop"} {
+		balancer.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	stats := balancer.Stats()
+	assert.Equal(t, map[string]uint64{"2xx": 3, "3xx": 1, "4xx": 2, "5xx": 3}, stats.Servers["first"].Responses)
+	assert.Nil(t, stats.Servers["second"].Responses)
+}
+
+func TestLBBalancerThrottledStats(t *testing.T) {
+	clock := newFakeClock()
+	balancer := New(nil, false)
+	balancer.clock = clock
+
+	balancer.Add("first", http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusOK)
+	}), Int(2), Int(1), Int(1000), Int(1))
+
+	balancer.Add("second", http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusOK)
+	}), Int(1), Int(1), Int(1000), Int(2))
+
+	for range 5 {
+		balancer.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	}
+
+	// The third request overflows to second, and the last two are throttled by both servers.
+	stats := balancer.Stats()
+	assert.Equal(t, uint64(2), stats.Servers["first"].Served)
+	assert.Equal(t, uint64(3), stats.Servers["first"].Throttled)
+	assert.Equal(t, uint64(1), stats.Servers["second"].Served)
+	assert.Equal(t, uint64(2), stats.Servers["second"].Throttled)
+	assert.Equal(t, uint64(2), stats.Rejected)
+}
@@ -0,0 +1,198 @@
+package lblb
+
+import (
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// stickyLifetimeBounds are the upper bounds of the buckets of the distribution of the sticky session lifetimes.
+var stickyLifetimeBounds = []time.Duration{time.Minute, 10 * time.Minute, time.Hour, 6 * time.Hour, 24 * time.Hour}
+
+// StickySessionStats is a snapshot of the tracking of the sticky sessions, see SetStickySessionTracking.
+type StickySessionStats struct {
+	// Active is the number of sessions currently tracked.
+	Active int `json:"active"`
+	// Ended is the number of sessions which ended, i.e. which were idle for longer than the idle timeout.
+	Ended uint64 `json:"ended"`
+	// Lifetimes is the distribution of the lifetimes of the ended sessions, from their first to their last request,
+	// by upper bound, e.g. "10m0s" for the sessions which lasted between 1 and 10 minutes, or "+Inf".
+	// A bucket without any session is omitted.
+	Lifetimes map[string]uint64 `json:"lifetimes,omitempty"`
+	// Untracked is the number of sessions which were not tracked because the maximum number of sessions was reached.
+	Untracked uint64 `json:"untracked,omitempty"`
+	// Hits is the number of requests served by the server their session was sticking to,
+	// and Migrated the number of requests whose session had to move to another server.
+	Hits     uint64 `json:"hits"`
+	Migrated uint64 `json:"migrated"`
+	// ChurnRate is the fraction of the sticky requests whose session had to move to another server.
+	ChurnRate float64 `json:"churnRate"`
+}
+
+// stickySessions tracks the creation and last request times of the sticky sessions, with its own mutex.
+type stickySessions struct {
+	mu          sync.Mutex
+	key         func(*http.Request) string
+	idle        time.Duration
+	maxSessions int
+
+	// sessions are the first and last request times of the tracked sessions, by key.
+	sessions  map[string]*stickySession
+	ended     uint64
+	lifetimes []uint64
+	untracked uint64
+	hits      uint64
+	migrated  uint64
+}
+
+type stickySession struct {
+	created  time.Time
+	lastSeen time.Time
+}
+
+// SetStickySessionTracking enables the tracking of the lifetimes of the sticky sessions, e.g. for tuning the cookie TTL,
+// reported in Stats along with the rate at which the sessions churn, i.e. move to another server.
+// As the sticky cookie only identifies the server, a session is identified by key, which defaults to the client IP.
+// A session ends once idle for longer than idle, and at most maxSessions sessions are tracked at once,
+// the new sessions being left untracked beyond: the lifetimes are bounded in memory,
+// being reported as a distribution in a fixed number of buckets.
+// A non-positive idle or maxSessions disables the tracking, which is the default.
+func (b *LBBalancer) SetStickySessionTracking(idle time.Duration, maxSessions int, key func(*http.Request) string) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if idle <= 0 || maxSessions <= 0 {
+		b.stickySessions = nil
+		return
+	}
+
+	if key == nil {
+		key = clientIP
+	}
+
+	b.stickySessions = &stickySessions{
+		key:         key,
+		idle:        idle,
+		maxSessions: maxSessions,
+		sessions:    make(map[string]*stickySession),
+		lifetimes:   make([]uint64, len(stickyLifetimeBounds)+1),
+	}
+}
+
+// clientIP returns the IP of the client of req.
+func clientIP(req *http.Request) string {
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		return req.RemoteAddr
+	}
+
+	return host
+}
+
+// trackStickySession records a sticky request of req, which was served by the server its session was sticking to if hit,
+// or which had to move to another one if migrated, and was otherwise the first request of its session.
+func (b *LBBalancer) trackStickySession(req *http.Request, hit, migrated bool) {
+	b.mutex.RLock()
+	s := b.stickySessions
+	b.mutex.RUnlock()
+
+	if s == nil {
+		return
+	}
+
+	key := s.key(req)
+	now := b.clock.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch {
+	case hit:
+		s.hits++
+	case migrated:
+		s.migrated++
+	}
+
+	if session, ok := s.sessions[key]; ok {
+		if now.Sub(session.lastSeen) <= s.idle {
+			session.lastSeen = now
+			return
+		}
+
+		s.end(key, session)
+	}
+
+	if len(s.sessions) >= s.maxSessions {
+		s.sweep(now)
+	}
+	if len(s.sessions) >= s.maxSessions {
+		s.untracked++
+		return
+	}
+
+	s.sessions[key] = &stickySession{created: now, lastSeen: now}
+}
+
+// end records the lifetime of the session, and stops tracking it.
+// It must be called with s.mu held.
+func (s *stickySessions) end(key string, session *stickySession) {
+	delete(s.sessions, key)
+	s.ended++
+
+	lifetime := session.lastSeen.Sub(session.created)
+	for i, bound := range stickyLifetimeBounds {
+		if lifetime <= bound {
+			s.lifetimes[i]++
+			return
+		}
+	}
+	s.lifetimes[len(stickyLifetimeBounds)]++
+}
+
+// sweep ends the sessions idle at now.
+// It must be called with s.mu held.
+func (s *stickySessions) sweep(now time.Time) {
+	for key, session := range s.sessions {
+		if now.Sub(session.lastSeen) > s.idle {
+			s.end(key, session)
+		}
+	}
+}
+
+// snapshot returns the stats of the sessions at now, ending the idle ones.
+func (s *stickySessions) snapshot(now time.Time) *StickySessionStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sweep(now)
+
+	stats := &StickySessionStats{
+		Active:    len(s.sessions),
+		Ended:     s.ended,
+		Untracked: s.untracked,
+		Hits:      s.hits,
+		Migrated:  s.migrated,
+	}
+	if total := s.hits + s.migrated; total > 0 {
+		stats.ChurnRate = float64(s.migrated) / float64(total)
+	}
+
+	for i, count := range s.lifetimes {
+		if count == 0 {
+			continue
+		}
+
+		bound := "+Inf"
+		if i < len(stickyLifetimeBounds) {
+			bound = stickyLifetimeBounds[i].String()
+		}
+
+		if stats.Lifetimes == nil {
+			stats.Lifetimes = make(map[string]uint64)
+		}
+		stats.Lifetimes[bound] = count
+	}
+
+	return stats
+}
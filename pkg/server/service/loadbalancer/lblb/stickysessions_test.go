@@ -0,0 +1,80 @@
+package lblb
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/traefik/traefik/v3/pkg/config/dynamic"
+)
+
+func TestLBBalancerStickySessionTracking(t *testing.T) {
+	clock := newFakeClock()
+
+	balancer := New(&dynamic.Sticky{Cookie: &dynamic.Cookie{Name: "lblb"}}, false)
+	balancer.clock = clock
+	balancer.SetStickySessionTracking(5*time.Minute, 3, func(req *http.Request) string {
+		return req.Header.Get("X-Client")
+	})
+
+	for i, name := range []string{"first", "second"} {
+		balancer.Add(name, http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			rw.WriteHeader(http.StatusOK)
+		}), Int(100), Int(100), Int(1), Int(i+1))
+	}
+
+	cookies := map[string]*http.Cookie{}
+	serve := func(client string) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("X-Client", client)
+		if cookie, ok := cookies[client]; ok {
+			req.AddCookie(cookie)
+		}
+
+		recorder := httptest.NewRecorder()
+		balancer.ServeHTTP(recorder, req)
+		if c := recorder.Result().Cookies(); len(c) > 0 {
+			cookies[client] = c[0]
+		}
+	}
+
+	// short lasts 40 seconds, long 20 minutes, and once lasts a single request.
+	serve("short")
+	serve("long")
+	serve("once")
+	clock.Advance(40 * time.Second)
+	serve("short")
+
+	// The sessions of first move to second.
+	balancer.SetStatus(context.Background(), "first", false)
+	for range 5 {
+		clock.Advance(4 * time.Minute)
+		serve("long")
+	}
+
+	// The idle sessions end to make room for new ones, until the maximum number of tracked sessions is reached.
+	serve("new")
+	serve("other")
+	serve("untracked")
+
+	stats := balancer.Stats().StickySessions
+	require.NotNil(t, stats)
+	assert.Equal(t, 3, stats.Active)
+	assert.Equal(t, uint64(2), stats.Ended)
+	assert.Equal(t, map[string]uint64{"1m0s": 2}, stats.Lifetimes)
+	assert.Equal(t, uint64(1), stats.Untracked)
+	assert.Equal(t, uint64(5), stats.Hits)
+	assert.Equal(t, uint64(1), stats.Migrated)
+	assert.InDelta(t, 1.0/6, stats.ChurnRate, 0.001)
+
+	clock.Advance(6 * time.Minute)
+
+	stats = balancer.Stats().StickySessions
+	assert.Equal(t, 0, stats.Active)
+	assert.Equal(t, uint64(5), stats.Ended)
+	assert.Equal(t, map[string]uint64{"1m0s": 4, "1h0m0s": 1}, stats.Lifetimes)
+}
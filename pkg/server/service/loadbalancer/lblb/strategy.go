@@ -0,0 +1,100 @@
+package lblb
+
+import (
+	"net/http"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Strategy is how a server is selected among the eligible ones.
+type Strategy string
+
+const (
+	// StrategyPriority selects the highest priority server allowed by its bucket, overflowing to the next ones.
+	// It is the default strategy.
+	StrategyPriority Strategy = "priority"
+	// StrategyMostTokens selects the least loaded server, i.e. the one with the most tokens available in its bucket,
+	// regardless of the priorities, which only break ties.
+	StrategyMostTokens Strategy = "most-tokens"
+	// StrategyPredictedCompletion selects the server predicted to complete the request the soonest,
+	// given the number of requests in flight on each server and its moving average latency,
+	// overflowing to the next soonest ones. The priorities only break ties.
+	StrategyPredictedCompletion Strategy = "predicted-completion"
+	// StrategyProportionalTiers interleaves the priority tiers in proportion to their shares, see SetTierShares,
+	// rather than only overflowing to the lower priority tiers,
+	// overflowing to the other tiers when the servers of the selected tier cannot admit the request.
+	StrategyProportionalTiers Strategy = "proportional-tiers"
+)
+
+// SetStrategySelector sets the function choosing the selection strategy of each request,
+// so that different classes of traffic are balanced differently over the same servers, e.g. by path.
+// An empty or unknown strategy falls back to StrategyPriority,
+// and sticky requests keep going to their server whatever the strategy.
+// The selector must not block, and a nil selector applies StrategyPriority to all requests, which is the default.
+func (b *LBBalancer) SetStrategySelector(fn func(*http.Request) Strategy) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.strategySelector = fn
+}
+
+// strategy returns the selection strategy of req.
+func (b *LBBalancer) strategy(req *http.Request) Strategy {
+	b.mutex.RLock()
+	selector := b.strategySelector
+	b.mutex.RUnlock()
+
+	if selector == nil {
+		return StrategyPriority
+	}
+
+	switch strategy := selector(req); strategy {
+	case StrategyPriority, StrategyMostTokens, StrategyPredictedCompletion, StrategyProportionalTiers:
+		return strategy
+	case "":
+		return StrategyPriority
+	default:
+		log.Ctx(req.Context()).Debug().Msgf("Unknown selection strategy %q, falling back to %s", strategy, StrategyPriority)
+		return StrategyPriority
+	}
+}
+
+// pickMostTokens selects the eligible server with the most tokens available, if it is allowed by its bucket.
+// The considered candidates are recorded in decision if it is not nil.
+// It must be called with the mutex held.
+func (b *LBBalancer) pickMostTokens(decision *Decision, sel selection) (*namedHandler, error) {
+	if len(b.handlers) == 0 || len(b.status) == 0 {
+		return nil, errNoAvailableServer
+	}
+
+	now := b.clock.Now()
+
+	var best *namedHandler
+	var bestTokens float64
+	for _, h := range b.handlers {
+		if reason := b.skipReason(h, sel, now); reason != "" {
+			decision.add(h, reason)
+			continue
+		}
+
+		tokens := h.bucket.TokensAt(now)
+		if best == nil || tokens > bestTokens || (tokens == bestTokens && higherPriority(h, best)) {
+			best, bestTokens = h, tokens
+		}
+	}
+
+	if best == nil {
+		return nil, errNoAvailableServer
+	}
+
+	if !sel.bypass {
+		if !b.admitCandidate(best, sel, now) {
+			decision.add(best, skipRateLimited)
+			return nil, errNoAvailableServer
+		}
+	}
+
+	decision.add(best, "")
+
+	return best, nil
+}
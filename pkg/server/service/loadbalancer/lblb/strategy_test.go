@@ -0,0 +1,81 @@
+package lblb
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLBBalancerStrategySelector(t *testing.T) {
+	clock := newFakeClock()
+	balancer := New(nil, false)
+	balancer.clock = clock
+
+	for i, name := range []string{"first", "second"} {
+		balancer.Add(name, http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			rw.Header().Set("server", name)
+			rw.WriteHeader(http.StatusOK)
+		}), Int(4), Int(1), Int(1000), Int(i+1))
+	}
+
+	balancer.SetStrategySelector(func(req *http.Request) Strategy {
+		switch {
+		case strings.HasPrefix(req.URL.Path, "/api"):
+			return StrategyMostTokens
+		case strings.HasPrefix(req.URL.Path, "/unknown"):
+			return "unknown"
+		default:
+			return ""
+		}
+	})
+
+	serve := func(path string) string {
+		recorder := httptest.NewRecorder()
+		balancer.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+		return recorder.Header().Get("server")
+	}
+
+	// The session traffic goes to the highest priority server.
+	assert.Equal(t, "first", serve("/"))
+	assert.Equal(t, "first", serve("/unknown"))
+
+	// The API traffic goes to the least loaded server, the priority breaking ties.
+	assert.Equal(t, "second", serve("/api"))
+	assert.Equal(t, "second", serve("/api"))
+	assert.Equal(t, "first", serve("/api"))
+	assert.Equal(t, "second", serve("/api"))
+
+	// Both strategies share the same buckets.
+	assert.Equal(t, "first", serve("/"))
+	assert.Equal(t, "second", serve("/"))
+	assert.Empty(t, serve("/"))
+	assert.Empty(t, serve("/api"))
+}
+
+func TestLBBalancerStrategyMostTokensRateLimited(t *testing.T) {
+	clock := newFakeClock()
+	balancer := New(nil, false)
+	balancer.clock = clock
+	balancer.SetStrategySelector(func(*http.Request) Strategy { return StrategyMostTokens })
+
+	balancer.Add("first", http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusOK)
+	}), Int(1), Int(1), Int(1000), Int(1))
+
+	var decisions []Decision
+	balancer.SetDecisionTracer(func(d Decision) {
+		decisions = append(decisions, d)
+	})
+
+	for range 2 {
+		balancer.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	}
+
+	assert.Len(t, decisions, 2)
+	assert.Equal(t, "first", decisions[0].Selected)
+	assert.Equal(t, []Candidate{{Name: "first", Priority: 1, Skipped: skipRateLimited}}, decisions[1].Candidates)
+	assert.Equal(t, uint64(1), balancer.Stats().Rejected)
+}
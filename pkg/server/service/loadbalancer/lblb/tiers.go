@@ -0,0 +1,131 @@
+package lblb
+
+import (
+	"cmp"
+	"maps"
+	"slices"
+)
+
+// tierShares are the shares of the traffic of the priority tiers of the proportional tiers strategy,
+// along with the credits of the smooth weighted round-robin between them.
+type tierShares struct {
+	shares map[int64]int
+	// credits are the deficits of the tiers, by priority.
+	credits map[int64]int
+}
+
+// SetTierShares sets the shares of the traffic of the priority tiers, i.e. of the servers of each priority,
+// used by the StrategyProportionalTiers strategy, e.g. {1: 70, 2: 30} for the servers of priority 1
+// to receive 70% of the traffic, and the servers of priority 2 the remaining 30%,
+// regardless of the capacity of the servers of priority 1.
+// A tier without a positive share only receives the traffic the other tiers cannot admit.
+// Setting the shares resets the deficits of the tiers, and a nil or empty shares map removes them,
+// the strategy then behaving as StrategyPriority, which is the default.
+func (b *LBBalancer) SetTierShares(shares map[int]int) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	tiers := &tierShares{shares: make(map[int64]int, len(shares)), credits: make(map[int64]int)}
+	for priority, share := range shares {
+		if share > 0 {
+			tiers.shares[int64(priority)] = share
+		}
+	}
+
+	b.tierShares = tiers
+}
+
+// pickProportionalTier selects a server of the tier having the largest deficit of traffic compared to its share,
+// overflowing to the tiers having the next largest deficits, and within a tier,
+// the eligible server with the most tokens available, overflowing to the next ones.
+// The considered candidates are recorded in decision if it is not nil.
+// It must be called with the mutex held.
+func (b *LBBalancer) pickProportionalTier(decision *Decision, sel selection) (*namedHandler, error) {
+	if len(b.handlers) == 0 || len(b.status) == 0 {
+		return nil, errNoAvailableServer
+	}
+
+	now := b.clock.Now()
+
+	tiers := make(map[int64][]*namedHandler)
+	for _, h := range b.handlers {
+		if reason := b.skipReason(h, sel, now); reason != "" {
+			decision.add(h, reason)
+			continue
+		}
+
+		tiers[h.priority] = append(tiers[h.priority], h)
+	}
+
+	var shares tierShares
+	if b.tierShares != nil {
+		shares = *b.tierShares
+	}
+	// A dry run works on a copy of the credits, which it does not change.
+	if sel.dryRun {
+		shares.credits = maps.Clone(shares.credits)
+	}
+
+	// The credits of the tiers having eligible servers grow by their share, and the selected tier pays for the total,
+	// so that the tiers are interleaved in proportion to their shares. The credits are bounded,
+	// so that a tier which could not be selected for a while does not get all the traffic once it can.
+	var total int
+	for priority := range tiers {
+		total += shares.shares[priority]
+	}
+	if total > 0 {
+		for priority := range tiers {
+			shares.credits[priority] = min(shares.credits[priority]+shares.shares[priority], total)
+		}
+	}
+
+	order := slices.SortedFunc(maps.Keys(tiers), func(a, c int64) int {
+		// The tiers without a share only get the traffic the others cannot admit.
+		if shared := cmp.Compare(min(shares.shares[c], 1), min(shares.shares[a], 1)); shared != 0 {
+			return shared
+		}
+		if credit := cmp.Compare(shares.credits[c], shares.credits[a]); credit != 0 {
+			return credit
+		}
+
+		switch {
+		case higherPriority(tiers[a][0], tiers[c][0]):
+			return -1
+		case higherPriority(tiers[c][0], tiers[a][0]):
+			return 1
+		default:
+			return 0
+		}
+	})
+
+	var throttled int
+	for _, priority := range order {
+		servers := tiers[priority]
+		slices.SortStableFunc(servers, func(a, c *namedHandler) int {
+			return cmp.Compare(c.bucket.TokensAt(now), a.bucket.TokensAt(now))
+		})
+
+		for _, h := range servers {
+			if !sel.bypass {
+				if !b.admitCandidate(h, sel, now) {
+					decision.add(h, skipRateLimited)
+
+					throttled++
+					if b.overflowLimitReached(throttled) {
+						return nil, errOverflowLimit
+					}
+					continue
+				}
+			}
+
+			if total > 0 {
+				shares.credits[priority] = max(shares.credits[priority]-total, -total)
+			}
+			decision.add(h, "")
+
+			return h, nil
+		}
+	}
+
+	return nil, errNoAvailableServer
+}
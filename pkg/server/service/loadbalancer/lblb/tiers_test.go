@@ -0,0 +1,79 @@
+package lblb
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLBBalancerProportionalTiers(t *testing.T) {
+	testCases := []struct {
+		desc     string
+		burst    int
+		shares   map[int]int
+		expected map[string]uint64
+	}{
+		{
+			desc:     "interleaved by share",
+			burst:    1000,
+			shares:   map[int]int{1: 70, 2: 30},
+			expected: map[string]uint64{"first": 700, "second": 300},
+		},
+		{
+			desc:     "lower tier favored",
+			burst:    1000,
+			shares:   map[int]int{1: 1, 2: 3},
+			expected: map[string]uint64{"first": 250, "second": 750},
+		},
+		{
+			desc:     "no share",
+			burst:    1000,
+			shares:   map[int]int{2: 1},
+			expected: map[string]uint64{"second": 1000},
+		},
+		{
+			desc:     "no shares",
+			burst:    1000,
+			expected: map[string]uint64{"first": 1000},
+		},
+		{
+			desc:     "overflow of a throttled tier",
+			burst:    100,
+			shares:   map[int]int{1: 70, 2: 30},
+			expected: map[string]uint64{"first": 100, "second": 900},
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.desc, func(t *testing.T) {
+			t.Parallel()
+
+			balancer := New(nil, false)
+			balancer.clock = newFakeClock()
+			balancer.SetStrategySelector(func(*http.Request) Strategy { return StrategyProportionalTiers })
+			balancer.SetTierShares(test.shares)
+
+			handler := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+				rw.WriteHeader(http.StatusOK)
+			})
+			balancer.Add("first", handler, Int(test.burst), Int(1), Int(1000), Int(1))
+			balancer.Add("second", handler, Int(1000), Int(1), Int(1000), Int(2))
+
+			for range 1000 {
+				recorder := httptest.NewRecorder()
+				balancer.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+				assert.Equal(t, http.StatusOK, recorder.Code)
+			}
+
+			served := make(map[string]uint64)
+			for name, server := range balancer.Stats().Servers {
+				if server.Served > 0 {
+					served[name] = server.Served
+				}
+			}
+			assert.Equal(t, test.expected, served)
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package lblb
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Skip reasons reported in a Candidate when it was not selected.
+const (
+	skipDown        = "down"
+	skipRateLimited = "rate-limited"
+	skipEjected     = "ejected"
+	skipDraining    = "draining"
+	skipExcluded    = "excluded"
+	skipDisabled    = "disabled"
+)
+
+// Candidate is a server considered during a selection.
+type Candidate struct {
+	Name     string `json:"name"`
+	Priority int64  `json:"priority"`
+	// Skipped is the reason why the candidate was not selected, empty if it was.
+	Skipped string `json:"skipped,omitempty"`
+}
+
+// Decision is a record of a single selection made by the balancer.
+type Decision struct {
+	Time time.Time `json:"time"`
+	// Candidates are the servers considered, in the order they were evaluated.
+	Candidates []Candidate   `json:"candidates"`
+	Selected   string        `json:"selected,omitempty"`
+	Error      string        `json:"error,omitempty"`
+	Duration   time.Duration `json:"duration"`
+}
+
+// SetDecisionTracer sets the hook called with a Decision after every selection.
+// Tracing is disabled when fn is nil, which is the default.
+func (b *LBBalancer) SetDecisionTracer(fn func(Decision)) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.tracer = fn
+}
+
+type selectionObserver struct {
+	id uint64
+	fn func(Decision)
+}
+
+// AddSelectionObserver adds fn to the hooks called with the record of every selection,
+// and returns a function removing it.
+// fn is called on the request path, so it must not block.
+func (b *LBBalancer) AddSelectionObserver(fn func(Decision)) func() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.nextObserverID++
+	id := b.nextObserverID
+
+	observers := make([]selectionObserver, 0, len(b.observers)+1)
+	observers = append(observers, b.observers...)
+	b.observers = append(observers, selectionObserver{id: id, fn: fn})
+
+	return func() {
+		b.mutex.Lock()
+		defer b.mutex.Unlock()
+
+		observers := make([]selectionObserver, 0, len(b.observers))
+		for _, o := range b.observers {
+			if o.id != id {
+				observers = append(observers, o)
+			}
+		}
+		b.observers = observers
+	}
+}
+
+// NewDecisionWriter returns a decision tracer writing each Decision as a line of JSON to w.
+func NewDecisionWriter(w io.Writer) func(Decision) {
+	var mu sync.Mutex
+	encoder := json.NewEncoder(w)
+
+	return func(d Decision) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if err := encoder.Encode(d); err != nil {
+			log.Error().Err(err).Msg("Error while writing leaky bucket decision")
+		}
+	}
+}
+
+func (d *Decision) add(h *namedHandler, skipped string) {
+	if d == nil {
+		return
+	}
+
+	d.Candidates = append(d.Candidates, Candidate{Name: h.name, Priority: h.priority, Skipped: skipped})
+}
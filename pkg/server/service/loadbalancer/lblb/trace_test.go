@@ -0,0 +1,94 @@
+package lblb
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLBBalancerDecisionTrace(t *testing.T) {
+	balancer := New(nil, false)
+
+	balancer.Add("first", http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Header().Set("server", "first")
+		rw.WriteHeader(http.StatusOK)
+	}), Int(1), Int(1), Int(1000), Int(1))
+
+	balancer.Add("second", http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Header().Set("server", "second")
+		rw.WriteHeader(http.StatusOK)
+	}), Int(1), Int(1), Int(1000), Int(2))
+
+	balancer.Add("third", http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.Header().Set("server", "third")
+		rw.WriteHeader(http.StatusOK)
+	}), Int(1), Int(1), Int(1000), Int(3))
+
+	balancer.SetStatus(context.Background(), "first", false)
+
+	buf := &bytes.Buffer{}
+	balancer.SetDecisionTracer(NewDecisionWriter(buf))
+
+	recorder := &responseRecorder{ResponseRecorder: httptest.NewRecorder(), save: map[string]int{}}
+	for range 2 {
+		balancer.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+	}
+
+	assert.Equal(t, []string{"second", "third"}, recorder.sequence)
+
+	var decisions []Decision
+	decoder := json.NewDecoder(buf)
+	for decoder.More() {
+		var d Decision
+		require.NoError(t, decoder.Decode(&d))
+		decisions = append(decisions, d)
+	}
+	require.Len(t, decisions, 2)
+
+	assert.Equal(t, "second", decisions[0].Selected)
+	assert.Equal(t, []Candidate{
+		{Name: "first", Priority: 1, Skipped: skipDown},
+		{Name: "second", Priority: 2},
+	}, decisions[0].Candidates)
+
+	assert.Equal(t, "third", decisions[1].Selected)
+	assert.Equal(t, []Candidate{
+		{Name: "first", Priority: 1, Skipped: skipDown},
+		{Name: "second", Priority: 2, Skipped: skipRateLimited},
+		{Name: "third", Priority: 3},
+	}, decisions[1].Candidates)
+
+	// Once disabled, no more decisions are traced.
+	balancer.SetDecisionTracer(nil)
+	balancer.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.False(t, decoder.More())
+}
+
+func TestLBBalancerDecisionTraceNoServer(t *testing.T) {
+	balancer := New(nil, false)
+
+	balancer.Add("first", http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusOK)
+	}), Int(1), Int(1), Int(1000), Int(1))
+
+	var decisions []Decision
+	balancer.SetDecisionTracer(func(d Decision) {
+		decisions = append(decisions, d)
+	})
+
+	for range 2 {
+		balancer.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	}
+
+	require.Len(t, decisions, 2)
+	assert.Equal(t, "first", decisions[0].Selected)
+	assert.Empty(t, decisions[1].Selected)
+	assert.Equal(t, errNoAvailableServer.Error(), decisions[1].Error)
+	assert.Equal(t, []Candidate{{Name: "first", Priority: 1, Skipped: skipRateLimited}}, decisions[1].Candidates)
+}
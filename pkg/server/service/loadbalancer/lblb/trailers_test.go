@@ -0,0 +1,67 @@
+package lblb
+
+import (
+	"bufio"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLBBalancerTrailersAndStreaming(t *testing.T) {
+	balancer := New(nil, false)
+	balancer.SetResponseCache(&ResponseCache{TTL: time.Minute})
+	balancer.SetFollowRedirects(2)
+	balancer.SetBackpressure(&Backpressure{Header: "X-Queue-Depth", MaxDepth: 10})
+
+	flushed := make(chan struct{})
+	var hits int
+	balancer.Add("first", http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		hits++
+
+		rw.Header().Set("Trailer", "X-Announced")
+		rw.WriteHeader(http.StatusOK)
+
+		_, _ = rw.Write([]byte("first chunk\n"))
+		rw.(http.Flusher).Flush()
+
+		// The second chunk is only sent once the client received the first one.
+		<-flushed
+		_, _ = rw.Write([]byte("second chunk\n"))
+
+		rw.Header().Set("X-Announced", "announced")
+		rw.Header().Set(http.TrailerPrefix+"X-Late", "late")
+	}), Int(10), Int(10), Int(1000), Int(1))
+	require.NoError(t, balancer.SetMaxResponseSize("first", 1024))
+	require.NoError(t, balancer.SetServerHeaders("first", map[string]string{"X-Injected": "true"}))
+
+	server := httptest.NewServer(balancer)
+	t.Cleanup(server.Close)
+
+	for range 2 {
+		resp, err := http.Get(server.URL)
+		require.NoError(t, err)
+
+		assert.Equal(t, []string{"chunked"}, resp.TransferEncoding)
+
+		reader := bufio.NewReader(resp.Body)
+		line, err := reader.ReadString('\n')
+		require.NoError(t, err)
+		assert.Equal(t, "first chunk\n", line)
+		flushed <- struct{}{}
+
+		rest, err := io.ReadAll(reader)
+		require.NoError(t, err)
+		assert.Equal(t, "second chunk\n", string(rest))
+		require.NoError(t, resp.Body.Close())
+
+		assert.Equal(t, http.Header{"X-Announced": {"announced"}, "X-Late": {"late"}}, resp.Trailer)
+	}
+
+	// The responses with trailers are not cached.
+	assert.Equal(t, 2, hits)
+}
@@ -0,0 +1,129 @@
+package lblb
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/traefik/traefik/v3/pkg/config/dynamic"
+)
+
+// HealthSnapshot is a snapshot of the status of a balancer and of its servers.
+type HealthSnapshot struct {
+	// Up is whether the balancer has at least one up server.
+	Up bool `json:"up"`
+	// Servers are the status of the servers, by name.
+	Servers map[string]bool `json:"servers"`
+	// Children are the snapshots of the child balancers added with AddChild, by name.
+	Children map[string]HealthSnapshot `json:"children,omitempty"`
+}
+
+// AddChild adds the child balancer as a server named name, and wires its status to the balancer:
+// the server is marked as down when the child has no up server anymore, and up again when it recovers.
+// The child must be created with the health check enabled.
+// The stats and health snapshots of the child are included in the ones of the balancer.
+func (b *LBBalancer) AddChild(name string, child *LBBalancer, server dynamic.Server) error {
+	if child == nil {
+		return fmt.Errorf("nil child %s", name)
+	}
+	if child == b {
+		return fmt.Errorf("balancer cannot be its own child %s", name)
+	}
+
+	// Adding the child again replaces its updater.
+	key := b.childUpdaterKey(name)
+	child.UnregisterStatusUpdater(key)
+
+	err := child.RegisterKeyedStatusUpdater(key, func(up bool) {
+		// The child may have been removed or replaced since.
+		if b.child(name) != child {
+			return
+		}
+		b.SetStatus(context.Background(), name, up)
+	})
+	if err != nil {
+		return fmt.Errorf("registering status updater of child %s: %w", name, err)
+	}
+
+	b.mutex.Lock()
+	previous := b.children[name]
+	b.children[name] = child
+	b.mutex.Unlock()
+
+	if previous != nil && previous != child {
+		b.detachChildren(map[string]*LBBalancer{name: previous})
+	}
+
+	b.AddServer(name, child, server)
+
+	child.mutex.RLock()
+	up := len(child.status) > 0
+	child.mutex.RUnlock()
+
+	if !up {
+		b.SetStatus(context.Background(), name, false)
+	}
+
+	return nil
+}
+
+// childUpdaterKey returns the key of the status updater registered by the balancer on its child named name.
+func (b *LBBalancer) childUpdaterKey(name string) string {
+	return fmt.Sprintf("parent-%p/%s", b, name)
+}
+
+// detachChildren unregisters the status updaters of the balancer from the given children, by name.
+// It must be called without the mutex held, as the children run their updaters with their own mutex held,
+// and the updaters take the mutex of the balancer.
+func (b *LBBalancer) detachChildren(children map[string]*LBBalancer) {
+	for name, child := range children {
+		child.UnregisterStatusUpdater(b.childUpdaterKey(name))
+	}
+}
+
+func (b *LBBalancer) child(name string) *LBBalancer {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	return b.children[name]
+}
+
+// HealthSnapshot returns a snapshot of the status of the balancer, of its servers, and of its children.
+func (b *LBBalancer) HealthSnapshot() HealthSnapshot {
+	b.mutex.RLock()
+	snapshot := HealthSnapshot{
+		Up:      len(b.status) > 0,
+		Servers: make(map[string]bool, len(b.handlers)),
+	}
+	for _, h := range b.handlers {
+		_, up := b.status[h.name]
+		snapshot.Servers[h.name] = up
+	}
+	children := b.childrenSnapshot()
+	b.mutex.RUnlock()
+
+	// The children are queried without the mutex held,
+	// as they take their own mutex before propagating their status to the balancer.
+	for name, child := range children {
+		if snapshot.Children == nil {
+			snapshot.Children = make(map[string]HealthSnapshot, len(children))
+		}
+		snapshot.Children[name] = child.HealthSnapshot()
+	}
+
+	return snapshot
+}
+
+// childrenSnapshot returns a copy of the children of the balancer.
+// It must be called with the mutex held.
+func (b *LBBalancer) childrenSnapshot() map[string]*LBBalancer {
+	if len(b.children) == 0 {
+		return nil
+	}
+
+	children := make(map[string]*LBBalancer, len(b.children))
+	for name, child := range b.children {
+		children[name] = child
+	}
+
+	return children
+}
@@ -0,0 +1,88 @@
+package lblb
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/traefik/traefik/v3/pkg/config/dynamic"
+)
+
+func TestLBBalancerAddChild(t *testing.T) {
+	newChild := func(names ...string) *LBBalancer {
+		child := New(nil, true)
+		for i, name := range names {
+			child.Add(name, http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+				rw.Header().Set("server", name)
+				rw.WriteHeader(http.StatusOK)
+			}), Int(100), Int(100), Int(1), Int(i+1))
+		}
+		return child
+	}
+
+	balancer1 := newChild("first", "second")
+	balancer2 := newChild("third", "fourth")
+
+	topBalancer := New(nil, true)
+	require.NoError(t, topBalancer.AddChild("balancer1", balancer1, dynamic.Server{Burst: Int(100), Average: Int(100), Period: Int(1), Priority: Int(2)}))
+	require.NoError(t, topBalancer.AddChild("balancer2", balancer2, dynamic.Server{Burst: Int(100), Average: Int(100), Period: Int(1), Priority: Int(1)}))
+
+	var updates []bool
+	require.NoError(t, topBalancer.RegisterStatusUpdater(func(up bool) {
+		updates = append(updates, up)
+	}))
+
+	assert.Equal(t, "third", serveOne(topBalancer))
+
+	// fourth gets downed, but balancer2 still up since third is still up.
+	balancer2.SetStatus(context.Background(), "fourth", false)
+	assert.Equal(t, "third", serveOne(topBalancer))
+
+	// third gets downed, and the propagation marks balancer2 as down for topBalancer.
+	balancer2.SetStatus(context.Background(), "third", false)
+	assert.Equal(t, "first", serveOne(topBalancer))
+
+	assert.Equal(t, HealthSnapshot{
+		Up:      true,
+		Servers: map[string]bool{"balancer1": true, "balancer2": false},
+		Children: map[string]HealthSnapshot{
+			"balancer1": {Up: true, Servers: map[string]bool{"first": true, "second": true}},
+			"balancer2": {Up: false, Servers: map[string]bool{"third": false, "fourth": false}},
+		},
+	}, topBalancer.HealthSnapshot())
+
+	stats := topBalancer.Stats()
+	assert.Equal(t, uint64(2), stats.Servers["balancer2"].Served)
+	assert.Equal(t, uint64(2), stats.Children["balancer2"].Servers["third"].Served)
+	assert.Equal(t, uint64(1), stats.Children["balancer1"].Servers["first"].Served)
+
+	// Once balancer1 is down too, the top balancer propagates its own status.
+	balancer1.SetStatus(context.Background(), "first", false)
+	balancer1.SetStatus(context.Background(), "second", false)
+	assert.Equal(t, []bool{false}, updates)
+
+	balancer2.SetStatus(context.Background(), "third", true)
+	assert.Equal(t, []bool{false, true}, updates)
+	assert.Equal(t, "third", serveOne(topBalancer))
+}
+
+func TestLBBalancerAddChildDown(t *testing.T) {
+	child := New(nil, true)
+	child.Add("first", http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {}), Int(1), Int(1), Int(1), Int(1))
+	child.SetStatus(context.Background(), "first", false)
+
+	balancer := New(nil, false)
+	require.NoError(t, balancer.AddChild("child", child, dynamic.Server{Burst: Int(1), Average: Int(1), Period: Int(1), Priority: Int(1)}))
+	assert.False(t, balancer.HealthSnapshot().Servers["child"])
+
+	// Once removed, the child status is not propagated anymore.
+	assert.True(t, balancer.RemoveServer(context.Background(), "child"))
+	child.SetStatus(context.Background(), "first", true)
+	assert.Empty(t, balancer.HealthSnapshot().Servers)
+	assert.False(t, balancer.HealthSnapshot().Up)
+
+	assert.Error(t, balancer.AddChild("self", balancer, dynamic.Server{}))
+	assert.Error(t, balancer.AddChild("nohealthcheck", New(nil, false), dynamic.Server{}))
+}
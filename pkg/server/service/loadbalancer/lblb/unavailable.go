@@ -0,0 +1,73 @@
+package lblb
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/rs/zerolog/log"
+)
+
+// unavailableResponse is the response written when no server is available.
+type unavailableResponse struct {
+	status      int
+	contentType string
+	body        []byte
+}
+
+// SetUnavailableResponse sets the response written when no server is available, e.g. a maintenance page.
+// A non-positive status resets to the default plain text response with a 503 status.
+func (b *LBBalancer) SetUnavailableResponse(status int, contentType string, body []byte) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if status <= 0 {
+		b.unavailable = nil
+		return
+	}
+
+	b.unavailable = &unavailableResponse{
+		status:      status,
+		contentType: contentType,
+		body:        append([]byte(nil), body...),
+	}
+}
+
+// writeUnavailable writes the response for a request which could not be dispatched because of err.
+func (b *LBBalancer) writeUnavailable(rw http.ResponseWriter, req *http.Request, err error) {
+	b.rejected.Add(1)
+
+	log.Ctx(req.Context()).Debug().Err(err).Msg("No server available for the request")
+
+	b.mutex.RLock()
+	resp := b.unavailable
+	expectContinueStatus := b.expectContinueStatus
+	b.mutex.RUnlock()
+
+	// Nothing reads the body of a rejected request, so the 100-continue is never sent,
+	// and the connection cannot be reused as the client may send the body anyway.
+	if expectsContinue(req) {
+		rw.Header().Set("Connection", "close")
+
+		if expectContinueStatus > 0 {
+			http.Error(rw, err.Error(), expectContinueStatus)
+			return
+		}
+	}
+
+	// The configured response is only about the servers being unavailable.
+	if resp == nil || !errors.Is(err, errNoAvailableServer) {
+		http.Error(rw, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+
+	if resp.contentType != "" {
+		rw.Header().Set("Content-Type", resp.contentType)
+	}
+	rw.Header().Set("Content-Length", strconv.Itoa(len(resp.body)))
+	rw.WriteHeader(resp.status)
+
+	if _, err := rw.Write(resp.body); err != nil {
+		log.Ctx(req.Context()).Debug().Err(err).Msg("Error while writing unavailable response")
+	}
+}
@@ -0,0 +1,66 @@
+package lblb
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLBBalancerUnavailableResponse(t *testing.T) {
+	balancer := New(nil, false)
+
+	balancer.Add("first", http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusOK)
+	}), Int(1), Int(1), Int(1), Int(1))
+	balancer.SetStatus(context.Background(), "first", false)
+
+	page := []byte("<html><body>Down for maintenance</body></html>")
+	balancer.SetUnavailableResponse(http.StatusServiceUnavailable, "text/html; charset=utf-8", page)
+
+	recorder := httptest.NewRecorder()
+	balancer.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+	assert.Equal(t, "text/html; charset=utf-8", recorder.Header().Get("Content-Type"))
+	assert.Equal(t, page, recorder.Body.Bytes())
+
+	// The page is also served by an empty balancer.
+	balancer = New(nil, false)
+	balancer.SetUnavailableResponse(http.StatusTeapot, "text/plain", []byte("nope"))
+
+	recorder = httptest.NewRecorder()
+	balancer.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	assert.Equal(t, http.StatusTeapot, recorder.Code)
+	assert.Equal(t, "nope", recorder.Body.String())
+}
+
+func TestLBBalancerUnavailableResponseDefault(t *testing.T) {
+	balancer := New(nil, false)
+
+	balancer.Add("first", http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusOK)
+	}), Int(1), Int(1), Int(1000), Int(1))
+
+	// Consumes the only token.
+	balancer.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	recorder := httptest.NewRecorder()
+	balancer.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+	assert.Equal(t, "text/plain; charset=utf-8", recorder.Header().Get("Content-Type"))
+	assert.Equal(t, errNoAvailableServer.Error()+"\n", recorder.Body.String())
+
+	// Resetting the response restores the default.
+	balancer.SetUnavailableResponse(http.StatusServiceUnavailable, "text/html", []byte("maintenance"))
+	balancer.SetUnavailableResponse(0, "", nil)
+
+	recorder = httptest.NewRecorder()
+	balancer.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	assert.Equal(t, errNoAvailableServer.Error()+"\n", recorder.Body.String())
+}
@@ -0,0 +1,54 @@
+package lblb
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/traefik/traefik/v3/pkg/config/dynamic"
+)
+
+func TestLBBalancerUpdateServer(t *testing.T) {
+	clock := newFakeClock()
+
+	balancer := New(nil, false)
+	balancer.clock = clock
+
+	for i, name := range []string{"first", "second"} {
+		balancer.Add(name, http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			rw.Header().Set("server", name)
+			rw.WriteHeader(http.StatusOK)
+		}), Int(4), Int(1), Int(1000), Int(i+2))
+	}
+
+	recorder := &responseRecorder{ResponseRecorder: httptest.NewRecorder(), save: map[string]int{}}
+	balancer.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, []string{"first"}, recorder.sequence)
+
+	// Promoting the second server shifts the traffic to it.
+	require.NoError(t, balancer.UpdateServer("second", dynamic.Server{Burst: Int(2), Average: Int(2), Period: Int(1000), Priority: Int(1)}))
+
+	second := balancer.servers["second"]
+	assert.Equal(t, int64(1), second.priority)
+	assert.Equal(t, int64(2), second.burst)
+	assert.Equal(t, int64(2), second.average)
+	assert.Equal(t, time.Second, second.period)
+
+	recorder = &responseRecorder{ResponseRecorder: httptest.NewRecorder(), save: map[string]int{}}
+	for range 3 {
+		balancer.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+	}
+
+	// The tokens of the second server were capped to its new burst.
+	assert.Equal(t, []string{"second", "second", "first"}, recorder.sequence)
+
+	// The new rate is applied: 2 tokens per second.
+	clock.Advance(500 * time.Millisecond)
+	assert.InDelta(t, 1, second.bucket.TokensAt(clock.Now()), 0.001)
+
+	assert.Error(t, balancer.UpdateServer("unknown", dynamic.Server{}))
+	assert.Error(t, balancer.UpdateServer("first", dynamic.Server{Average: Int(0)}))
+}
@@ -0,0 +1,112 @@
+package lblb
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// statusUpdater is a hook run when the status of the balancer changes.
+type statusUpdater struct {
+	// key identifies the updater, empty for an anonymous one.
+	key string
+	fn  func(up bool)
+}
+
+// SetHealthCheckEnabled sets whether the status of the balancer is propagated to its status updaters,
+// which is decided by New initially, e.g. for a balancer to start propagating once its parent enables health checks.
+// While disabled, RegisterStatusUpdater fails, and the status changes do not run the updaters, which are kept.
+// Enabling it runs the updaters with the current status, which may have changed in the meantime,
+// once the mutex is released, so that they can call back into the balancer.
+func (b *LBBalancer) SetHealthCheckEnabled(enabled bool) {
+	b.mutex.Lock()
+
+	changed := b.wantsHealthCheck != enabled
+	b.wantsHealthCheck = enabled
+	if !changed || !enabled {
+		b.mutex.Unlock()
+		return
+	}
+
+	// The slice is replaced, never modified, so that it can be run without the mutex.
+	up := len(b.status) > 0
+	updaters := b.updaters
+	b.mutex.Unlock()
+
+	for _, u := range updaters {
+		u.fn(up)
+	}
+}
+
+// SetMaxStatusUpdaters sets the maximum number of status updaters of the balancer,
+// beyond which RegisterStatusUpdater and RegisterKeyedStatusUpdater fail,
+// so that a bug registering updaters over and over in a tree of balancers fails loudly
+// instead of causing propagation storms.
+// The updaters registered already are kept, even beyond the maximum.
+// A non-positive maximum disables the limit, which is the default.
+func (b *LBBalancer) SetMaxStatusUpdaters(maxUpdaters int) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.maxUpdaters = max(maxUpdaters, 0)
+}
+
+// RegisterKeyedStatusUpdater adds fn to the list of hooks that are run when the status of the balancer changes,
+// identified by key, so that registering the same updater twice is detected.
+// It returns an error if an updater with the same key is registered already,
+// or if the maximum number of updaters is reached, see SetMaxStatusUpdaters.
+// An empty key registers an anonymous updater, as RegisterStatusUpdater.
+// The updaters are run with the mutex of the balancer held on its status changes, e.g. by SetStatus,
+// and must not call back into the balancer, which would deadlock.
+func (b *LBBalancer) RegisterKeyedStatusUpdater(key string, fn func(up bool)) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if !b.wantsHealthCheck {
+		return errors.New("healthCheck not enabled in config for this leaky bucket service")
+	}
+
+	if key != "" {
+		for _, u := range b.updaters {
+			if u.key == key {
+				return fmt.Errorf("status updater %s already registered", key)
+			}
+		}
+	}
+
+	if b.maxUpdaters > 0 && len(b.updaters) >= b.maxUpdaters {
+		return fmt.Errorf("maximum of %d status updaters reached", b.maxUpdaters)
+	}
+
+	// The slice is replaced, never modified, so that a propagation in progress is not affected.
+	updaters := make([]statusUpdater, 0, len(b.updaters)+1)
+	updaters = append(updaters, b.updaters...)
+	b.updaters = append(updaters, statusUpdater{key: key, fn: fn})
+
+	return nil
+}
+
+// UnregisterStatusUpdater removes the status updater registered with the given key,
+// e.g. when the balancer is detached from a parent, so that the parent is not updated anymore.
+// A propagation in progress may still run the removed updater.
+// It returns false if no such updater exists.
+func (b *LBBalancer) UnregisterStatusUpdater(key string) bool {
+	if key == "" {
+		return false
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	for i, u := range b.updaters {
+		if u.key != key {
+			continue
+		}
+
+		// The slice is replaced, never modified, so that a propagation in progress is not affected.
+		b.updaters = slices.Concat(b.updaters[:i], b.updaters[i+1:])
+		return true
+	}
+
+	return false
+}
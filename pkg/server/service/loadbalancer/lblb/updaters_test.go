@@ -0,0 +1,179 @@
+package lblb
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/traefik/traefik/v3/pkg/config/dynamic"
+)
+
+func TestLBBalancerKeyedStatusUpdaters(t *testing.T) {
+	balancer := New(nil, true)
+	balancer.Add("first", http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {}), Int(1), Int(1), Int(1000), Int(1))
+
+	var updates []string
+	register := func(key string) error {
+		return balancer.RegisterKeyedStatusUpdater(key, func(up bool) {
+			updates = append(updates, key)
+		})
+	}
+
+	require.NoError(t, register("parent"))
+	assert.Error(t, register("parent"))
+
+	// The anonymous updaters are never duplicates of each other.
+	require.NoError(t, register(""))
+	require.NoError(t, register(""))
+
+	balancer.SetStatus(context.Background(), "first", false)
+	assert.Equal(t, []string{"parent", "", ""}, updates)
+}
+
+func TestLBBalancerMaxStatusUpdaters(t *testing.T) {
+	balancer := New(nil, true)
+	balancer.SetMaxStatusUpdaters(2)
+
+	fn := func(up bool) {}
+	require.NoError(t, balancer.RegisterStatusUpdater(fn))
+	require.NoError(t, balancer.RegisterKeyedStatusUpdater("parent", fn))
+	assert.Error(t, balancer.RegisterStatusUpdater(fn))
+	assert.Error(t, balancer.RegisterKeyedStatusUpdater("other", fn))
+
+	// The registered updaters are kept when lowering the maximum, and the limit can be lifted.
+	balancer.SetMaxStatusUpdaters(1)
+	assert.Len(t, balancer.updaters, 2)
+
+	balancer.SetMaxStatusUpdaters(0)
+	require.NoError(t, balancer.RegisterKeyedStatusUpdater("other", fn))
+	assert.Len(t, balancer.updaters, 3)
+
+	// Without health check, nothing can be registered.
+	assert.Error(t, New(nil, false).RegisterKeyedStatusUpdater("parent", fn))
+}
+
+func TestLBBalancerUnregisterStatusUpdater(t *testing.T) {
+	balancer := New(nil, true)
+	balancer.Add("first", http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {}), Int(1), Int(1), Int(1000), Int(1))
+
+	var updates []string
+	for _, key := range []string{"kept", "removed"} {
+		require.NoError(t, balancer.RegisterKeyedStatusUpdater(key, func(up bool) {
+			updates = append(updates, key)
+		}))
+	}
+
+	balancer.SetStatus(context.Background(), "first", false)
+	assert.Equal(t, []string{"kept", "removed"}, updates)
+
+	assert.True(t, balancer.UnregisterStatusUpdater("removed"))
+	assert.False(t, balancer.UnregisterStatusUpdater("removed"))
+	assert.False(t, balancer.UnregisterStatusUpdater(""))
+
+	balancer.SetStatus(context.Background(), "first", true)
+	assert.Equal(t, []string{"kept", "removed", "kept"}, updates)
+
+	// The key can be registered again.
+	require.NoError(t, balancer.RegisterKeyedStatusUpdater("removed", func(up bool) {}))
+}
+
+func TestLBBalancerUnregisterStatusUpdaterConcurrently(t *testing.T) {
+	balancer := New(nil, true)
+	balancer.Add("first", http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {}), Int(1), Int(1), Int(1000), Int(1))
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+
+		for i := range 100 {
+			balancer.SetStatus(context.Background(), "first", i%2 == 0)
+		}
+	}()
+
+	for range 100 {
+		require.NoError(t, balancer.RegisterKeyedStatusUpdater("child", func(up bool) {}))
+		assert.True(t, balancer.UnregisterStatusUpdater("child"))
+	}
+
+	<-done
+}
+
+func TestLBBalancerDetachedChildUpdaters(t *testing.T) {
+	parent := New(nil, true)
+	handler := http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {})
+	config := dynamic.Server{Burst: Int(1), Average: Int(1), Period: Int(1000), Priority: Int(1)}
+
+	newChild := func() *LBBalancer {
+		child := New(nil, true)
+		child.Add("server", handler, Int(1), Int(1), Int(1000), Int(1))
+		return child
+	}
+
+	removed, pruned, replaced, kept := newChild(), newChild(), newChild(), newChild()
+	require.NoError(t, parent.AddChild("removed", removed, config))
+	require.NoError(t, parent.AddChild("pruned", pruned, config))
+	require.NoError(t, parent.AddChild("replaced", replaced, config))
+	require.NoError(t, parent.AddChild("replaced", kept, config))
+
+	// Adding the same child again does not register its updater twice.
+	require.NoError(t, parent.AddChild("replaced", kept, config))
+	assert.Len(t, kept.updaters, 1)
+	assert.Empty(t, replaced.updaters)
+
+	assert.True(t, parent.RemoveServer(context.Background(), "removed"))
+	assert.Empty(t, removed.updaters)
+
+	require.NoError(t, parent.SetServers(context.Background(), []Server{{Name: "replaced", Handler: kept, Config: config}}))
+	assert.Empty(t, pruned.updaters)
+	assert.Len(t, kept.updaters, 1)
+
+	// Only the kept child still updates the parent.
+	pruned.SetStatus(context.Background(), "server", false)
+	kept.SetStatus(context.Background(), "server", false)
+	assert.False(t, parent.HealthSnapshot().Up)
+}
+
+func TestLBBalancerSetHealthCheckEnabled(t *testing.T) {
+	balancer := New(nil, false)
+	balancer.Add("first", http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {}), Int(1), Int(1), Int(1000), Int(1))
+
+	var updates []bool
+	fn := func(up bool) {
+		updates = append(updates, up)
+	}
+
+	assert.Error(t, balancer.RegisterStatusUpdater(fn))
+
+	balancer.SetHealthCheckEnabled(true)
+	require.NoError(t, balancer.RegisterStatusUpdater(fn))
+
+	balancer.SetStatus(context.Background(), "first", false)
+	assert.Equal(t, []bool{false}, updates)
+
+	// Once disabled, the updaters are kept but not run.
+	balancer.SetHealthCheckEnabled(false)
+	balancer.SetStatus(context.Background(), "first", true)
+	assert.Equal(t, []bool{false}, updates)
+	assert.Error(t, balancer.RegisterStatusUpdater(fn))
+	assert.Len(t, balancer.updaters, 1)
+
+	// Enabling it again catches the updaters up with the current status.
+	balancer.SetHealthCheckEnabled(true)
+	assert.Equal(t, []bool{false, true}, updates)
+
+	balancer.SetHealthCheckEnabled(true)
+	balancer.SetStatus(context.Background(), "first", false)
+	assert.Equal(t, []bool{false, true, false}, updates)
+
+	// The updaters run on enabling can call back into the balancer.
+	require.NoError(t, balancer.RegisterKeyedStatusUpdater("once", func(up bool) {
+		updates = append(updates, up)
+		balancer.UnregisterStatusUpdater("once")
+	}))
+	balancer.SetHealthCheckEnabled(false)
+	balancer.SetHealthCheckEnabled(true)
+	assert.Equal(t, []bool{false, true, false, false, false}, updates)
+	assert.Len(t, balancer.updaters, 1)
+}
@@ -0,0 +1,151 @@
+package lblb
+
+import (
+	"time"
+)
+
+// warmPool is a ring of servers selected ahead of the requests.
+type warmPool struct {
+	interval time.Duration
+	stop     func() bool
+	// servers are the pre-selected servers, in the order they were selected.
+	servers chan *namedHandler
+	// pending are the numbers of pre-selections of each server in servers, guarded by the balancer mutex.
+	pending map[*namedHandler]int
+}
+
+// SetWarmPool enables the pre-selection of up to size servers, refilled every interval,
+// so that the requests take a pre-selected server instead of running a selection,
+// which lowers the selection latency under bursts.
+// Only the selection is made ahead of time: the token of a pre-selected server is taken from its bucket
+// when a request takes it, so that the pool never lets a server admit more than its bucket does.
+// A server is pre-selected at most as many times as its bucket has tokens.
+// A pre-selected server which cannot be used anymore, e.g. because it went down or its bucket is empty, is discarded.
+// The requests fall back to a live selection when the pool is empty,
+// and so do the requests having a selection of their own, such as the bypassing ones,
+// or the ones with another strategy than the priority one.
+// As a server is selected ahead of time, a request may be sent to a server which is not the best one anymore,
+// e.g. right after a change of priority. Such changes are only reflected in the pool after at most size requests.
+// The selections of the pool are not recorded by the decision tracer.
+// A non-positive size or interval disables the pool, which is the default.
+func (b *LBBalancer) SetWarmPool(size int, interval time.Duration) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.stopWarmPool()
+
+	if size <= 0 || interval <= 0 {
+		return
+	}
+
+	pool := &warmPool{interval: interval, servers: make(chan *namedHandler, size), pending: make(map[*namedHandler]int)}
+	b.warmPool = pool
+	b.refillWarmPool(pool)
+	pool.stop = b.clock.AfterFunc(interval, func() { b.scheduledRefill(pool) })
+}
+
+// stopWarmPool disables the warm pool, discarding the pre-selected servers.
+// It must be called with the mutex held.
+func (b *LBBalancer) stopWarmPool() {
+	pool := b.warmPool
+	if pool == nil {
+		return
+	}
+
+	pool.stop()
+	b.warmPool = nil
+
+	for {
+		select {
+		case <-pool.servers:
+		default:
+			return
+		}
+	}
+}
+
+// scheduledRefill refills pool, and schedules its next refill.
+func (b *LBBalancer) scheduledRefill(pool *warmPool) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.warmPool != pool {
+		return
+	}
+
+	b.refillWarmPool(pool)
+	pool.stop = b.clock.AfterFunc(pool.interval, func() { b.scheduledRefill(pool) })
+}
+
+// refillWarmPool pre-selects servers until pool is full, or no server is available.
+// The selections are dry runs, which neither take any token, nor are recorded as admissions,
+// a server being excluded once it is pre-selected as many times as its bucket has tokens.
+// It must be called with the mutex held.
+func (b *LBBalancer) refillWarmPool(pool *warmPool) {
+	now := b.clock.Now()
+
+	var excluded []string
+	for h, pending := range pool.pending {
+		if h.bucket.TokensAt(now) < float64(pending+1) {
+			excluded = append(excluded, h.name)
+		}
+	}
+
+	for len(pool.servers) < cap(pool.servers) {
+		h, err := b.pickServer(nil, selection{excluded: excluded, dryRun: true})
+		if err != nil {
+			return
+		}
+
+		pool.servers <- h
+		pool.pending[h]++
+		if h.bucket.TokensAt(now) < float64(pool.pending[h]+1) {
+			excluded = append(excluded, h.name)
+		}
+	}
+}
+
+// warmServer takes a pre-selected server from the warm pool for a selection sel,
+// or returns nil if it is empty or disabled, or if sel is not the plain selection the servers were pre-selected with.
+// The pre-selected servers which cannot be used anymore are discarded.
+func (b *LBBalancer) warmServer(sel selection) *namedHandler {
+	if sel.bypass || len(sel.excluded) > 0 || sel.strategy != StrategyPriority {
+		return nil
+	}
+
+	b.mutex.RLock()
+	pool := b.warmPool
+	b.mutex.RUnlock()
+
+	if pool == nil {
+		return nil
+	}
+
+	for {
+		var h *namedHandler
+		select {
+		case h = <-pool.servers:
+		default:
+			return nil
+		}
+
+		if b.admitWarm(pool, h) {
+			return h
+		}
+	}
+}
+
+// admitWarm reports whether the pre-selected server h of pool can still be used,
+// taking a token of its bucket if so.
+func (b *LBBalancer) admitWarm(pool *warmPool, h *namedHandler) bool {
+	// The write lock is required, as the check may re-admit the server, see available.
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if pool.pending[h]--; pool.pending[h] <= 0 {
+		delete(pool.pending, h)
+	}
+
+	now := b.clock.Now()
+	return b.servers[h.name] == h && b.skipReason(h, selection{}, now) == "" && h.allow(now)
+}
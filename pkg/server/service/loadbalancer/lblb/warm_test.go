@@ -0,0 +1,98 @@
+package lblb
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLBBalancerWarmPool(t *testing.T) {
+	clock := newFakeClock()
+	balancer := New(nil, false)
+	balancer.clock = clock
+	defer balancer.Close()
+
+	for i, name := range []string{"first", "second"} {
+		balancer.Add(name, http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			rw.Header().Set("server", name)
+			rw.WriteHeader(http.StatusOK)
+		}), Int(10), Int(1), Int(60000), Int(i+1))
+	}
+
+	tokens := func(name string) float64 {
+		balancer.mutex.RLock()
+		defer balancer.mutex.RUnlock()
+
+		return balancer.servers[name].bucket.TokensAt(clock.Now())
+	}
+
+	// The pool is filled right away, without taking any token.
+	balancer.SetWarmPool(4, time.Second)
+	assert.InDelta(t, 10, tokens("first"), 0.01)
+	assert.InDelta(t, 10, tokens("second"), 0.01)
+
+	// The requests take the tokens of the pre-selected servers.
+	for range 2 {
+		recorder := httptest.NewRecorder()
+		balancer.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+		assert.Equal(t, "first", recorder.Header().Get("server"))
+	}
+	assert.InDelta(t, 8, tokens("first"), 0.01)
+
+	// The refills do not take any token either.
+	clock.Advance(time.Second)
+	assert.InDelta(t, 8, tokens("first"), 0.05)
+
+	// A server which went down is discarded, and the request is served by a live selection.
+	balancer.SetStatus(context.Background(), "first", false)
+	recorder := httptest.NewRecorder()
+	balancer.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, "second", recorder.Header().Get("server"))
+	assert.InDelta(t, 8, tokens("first"), 0.05)
+	assert.InDelta(t, 9, tokens("second"), 0.01)
+
+	// Disabling the pool does not change any bucket.
+	clock.Advance(time.Second)
+	balancer.SetWarmPool(0, 0)
+	assert.InDelta(t, 9, tokens("second"), 0.05)
+}
+
+func TestLBBalancerWarmPoolBurst(t *testing.T) {
+	clock := newFakeClock()
+	balancer := New(nil, false)
+	balancer.clock = clock
+	defer balancer.Close()
+
+	balancer.SetAdmissionHistory(10)
+
+	var served int
+	balancer.Add("first", http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		served++
+		rw.WriteHeader(http.StatusOK)
+	}), Int(2), Int(1), Int(60000), Int(1))
+
+	// The pool is larger than the burst of the only server.
+	balancer.SetWarmPool(8, time.Second)
+	clock.Advance(3 * time.Second)
+
+	// The refills are not recorded as admissions.
+	for _, second := range balancer.AdmissionHistory()["first"] {
+		assert.Zero(t, second.Allowed)
+		assert.Zero(t, second.Denied)
+	}
+
+	codes := map[int]int{}
+	for range 8 {
+		recorder := httptest.NewRecorder()
+		balancer.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+		codes[recorder.Code]++
+	}
+
+	// A warmed server never admits more than its burst.
+	assert.Equal(t, 2, served)
+	assert.Equal(t, map[int]int{http.StatusOK: 2, http.StatusServiceUnavailable: 6}, codes)
+}
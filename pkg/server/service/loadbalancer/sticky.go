@@ -119,8 +119,19 @@ func (s *Sticky) StickyHandler(req *http.Request) (*NamedHandler, bool, error) {
 	return handler, ok, nil
 }
 
+// CookieName returns the name of the sticky cookie.
+func (s *Sticky) CookieName() string {
+	return s.cookie.name
+}
+
 // WriteStickyCookie writes a sticky cookie to the response to stick the client to the given handler name.
 func (s *Sticky) WriteStickyCookie(rw http.ResponseWriter, name string) error {
+	return s.WriteStickyCookieMaxAge(rw, name, s.cookie.maxAge)
+}
+
+// WriteStickyCookieMaxAge writes a sticky cookie to the response to stick the client to the given handler name,
+// overriding the configured MaxAge of the cookie with maxAge.
+func (s *Sticky) WriteStickyCookieMaxAge(rw http.ResponseWriter, name string, maxAge int) error {
 	s.handlersMu.RLock()
 	hash, ok := s.hashMap[name]
 	s.handlersMu.RUnlock()
@@ -136,7 +147,7 @@ func (s *Sticky) WriteStickyCookie(rw http.ResponseWriter, name string) error {
 		HttpOnly: s.cookie.httpOnly,
 		Secure:   s.cookie.secure,
 		SameSite: s.cookie.sameSite,
-		MaxAge:   s.cookie.maxAge,
+		MaxAge:   maxAge,
 	}
 	http.SetCookie(rw, cookie)
 
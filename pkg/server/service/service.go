@@ -58,7 +58,9 @@ type Manager struct {
 	services       map[string]http.Handler
 	configs        map[string]*runtime.ServiceInfo
 	healthCheckers map[string]*healthcheck.ServiceHealthChecker
-	rand           *rand.Rand // For the initial shuffling of load-balancers.
+	// leakyBuckets are the leaky bucket balancers, closed when the configuration they were built for is replaced.
+	leakyBuckets []*lblb.LBBalancer
+	rand         *rand.Rand // For the initial shuffling of load-balancers.
 }
 
 // NewManager creates a new Manager.
@@ -293,6 +295,7 @@ func (m *Manager) getLBServiceHandler(ctx context.Context, serviceName string, c
 	}
 
 	balancer := lblb.New(config.Sticky, config.HealthCheck != nil)
+	m.leakyBuckets = append(m.leakyBuckets, balancer)
 	for _, service := range shuffle(config.Services, m.rand) {
 		serviceHandler, err := m.BuildHTTP(ctx, service.Name)
 		if err != nil {
@@ -390,7 +393,9 @@ func (m *Manager) getLoadBalancerServiceHandler(ctx context.Context, serviceName
 	// Here we are handling the empty value to comply with providers that are not applying defaults (e.g. REST provider)
 	// TODO: remove this when all providers apply default values.
 	case dynamic.BalancerStrategyLBLB:
-		lb = lblb.New(service.Sticky, service.HealthCheck != nil)
+		balancer := lblb.New(service.Sticky, service.HealthCheck != nil)
+		m.leakyBuckets = append(m.leakyBuckets, balancer)
+		lb = balancer
 	case dynamic.BalancerStrategyWRR, "":
 		lb = wrr.New(service.Sticky, service.HealthCheck != nil)
 	case dynamic.BalancerStrategyP2C:
@@ -487,11 +492,23 @@ func (m *Manager) getLoadBalancerServiceHandler(ctx context.Context, serviceName
 }
 
 // LaunchHealthCheck launches the health checks.
+// The leaky bucket balancers are closed once ctx is done, i.e. when they are dropped on a reload of the configuration,
+// so that their timers do not keep running.
 func (m *Manager) LaunchHealthCheck(ctx context.Context) {
 	for serviceName, hc := range m.healthCheckers {
 		logger := log.Ctx(ctx).With().Str(logs.ServiceName, serviceName).Logger()
 		go hc.Launch(logger.WithContext(ctx))
 	}
+
+	if len(m.leakyBuckets) > 0 {
+		balancers := m.leakyBuckets
+		go func() {
+			<-ctx.Done()
+			for _, balancer := range balancers {
+				balancer.Close()
+			}
+		}()
+	}
 }
 
 func shuffle[T any](values []T, r *rand.Rand) []T {